@@ -3,12 +3,21 @@ package main
 import (
 	"context"
 	"log/slog"
+	"net/http"
 	"opportunities/config"
 	_ "opportunities/docs"
+	"opportunities/internal/app"
+	"opportunities/internal/auth"
+	"opportunities/internal/handler"
 	"opportunities/internal/messaging"
 	"opportunities/internal/repository"
 	"opportunities/internal/router"
+	"opportunities/internal/rules"
 	"opportunities/internal/service"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 // @title Opportunities API
@@ -38,8 +47,153 @@ func main() {
 		ClientID: kafkaConfig.ClientID,
 	})
 
-	csvService := service.NewOpeningCSVService(repo, feedbackProducer, 100)
-	csvService.Start(context.Background())
+	rulesConfig := config.LoadOpeningRulesConfig()
+	openingRules, err := rules.NewStore(rulesConfig.Path)
+	if err != nil {
+		slog.Error("Error loading opening rules", slog.String("error", err.Error()))
+		return
+	}
+
+	csvConfig := config.LoadCSVImportConfig()
+	csvService := service.NewOpeningCSVService(repo, feedbackProducer, csvConfig.QueueSize,
+		service.WithRules(openingRules),
+		service.WithImportJobs(repository.NewImportJobRepository(db)),
+		service.WithSpoolDir(csvConfig.SpoolDir),
+		service.WithBatchSize(csvConfig.BatchSize),
+		service.WithParseWorkers(csvConfig.ParseWorkers),
+		service.WithWorkers(csvConfig.Workers),
+		service.WithJobTimeout(csvConfig.JobTimeout),
+		service.WithMaxActivePerOwner(csvConfig.MaxActivePerUser),
+		service.WithValidateMaxBytes(int64(csvConfig.ValidateMaxBytes)),
+		service.WithUploadMaxBytes(int64(csvConfig.UploadMaxBytes)),
+		service.WithDedupeWindow(csvConfig.DedupeWindow),
+		service.WithProgress(csvConfig.ProgressRows, csvConfig.ProgressInterval),
+		service.WithArchiveLimits(csvConfig.ArchiveMaxEntries, int64(csvConfig.ArchiveMaxBytes)),
+		service.WithErrorReportTTL(csvConfig.ErrorReportTTL))
+	if err := csvService.Restore(); err != nil {
+		slog.Error("Error restoring csv jobs", slog.String("error", err.Error()))
+	}
+
+	exportConfig := config.LoadExportConfig()
+	exportService := service.NewOpeningExportService(repo, repository.NewExportJobRepository(db),
+		exportConfig.Dir, exportConfig.QueueSize,
+		service.WithExportFileTTL(exportConfig.FileTTL))
+	if err := exportService.Restore(); err != nil {
+		slog.Error("Error restoring exports", slog.String("error", err.Error()))
+	}
+
+	throttleConfig := config.LoadLoginThrottleConfig()
+	loginLimiter := auth.NewLoginLimiter(auth.NewMemoryLoginAttemptStore(), auth.LoginLimiterConfig{
+		MaxAccountFailures: throttleConfig.MaxAccountFailures,
+		MaxIPFailures:      throttleConfig.MaxIPFailures,
+		BaseDelay:          throttleConfig.BaseDelay,
+		MaxDelay:           throttleConfig.MaxDelay,
+		LockoutDuration:    throttleConfig.LockoutDuration,
+		ResetAfter:         throttleConfig.ResetAfter,
+	})
+
+	twoFactorConfig := config.LoadTwoFactorConfig()
+	twoFactorService := service.NewTwoFactorService(repository.NewTwoFactorRepository(db), twoFactorConfig.Issuer)
+
+	handlerOpts := []handler.Option{
+		handler.WithLoginLimiter(loginLimiter),
+		handler.WithTwoFactor(twoFactorService),
+		handler.WithExports(exportService),
+		handler.WithRules(openingRules),
+	}
+
+	oidcConfig := config.LoadOIDCConfig()
+	if oidcConfig.Enabled {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		provider, err := auth.NewOIDCProvider(ctx, auth.OIDCConfig{
+			IssuerURL:        oidcConfig.IssuerURL,
+			ClientID:         oidcConfig.ClientID,
+			ClientSecret:     oidcConfig.ClientSecret,
+			RedirectURL:      oidcConfig.RedirectURL,
+			Scopes:           oidcConfig.Scopes,
+			GroupsClaim:      oidcConfig.GroupsClaim,
+			GroupRoles:       oidcConfig.GroupRoles,
+			MaxPendingLogins: oidcConfig.MaxPendingLogins,
+		}, nil)
+		cancel()
+
+		if err != nil {
+			slog.Error("Error initializing oidc provider", slog.String("error", err.Error()))
+		} else {
+			handlerOpts = append(handlerOpts, handler.WithOIDC(provider))
+		}
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		slog.Error("Error getting database handle", slog.String("error", err.Error()))
+		return
+	}
+
+	serverConfig := config.LoadServerConfig()
+	engine, err := router.New(db, csvService, serverConfig.TrustedProxies, handlerOpts...)
+	if err != nil {
+		slog.Error("Error initializing router", slog.String("error", err.Error()))
+		return
+	}
+
+	server := &http.Server{
+		Addr:    serverConfig.Addr,
+		Handler: engine,
+	}
+
+	// The server stops accepting requests first, then the workers finish or
+	// requeue their jobs, then the producer and the database are closed.
+	appOpts := []app.Option{}
 
-	router.Initialize(db, csvService)
+	inboxConfig := config.LoadCSVInboxConfig()
+	if inboxConfig.Dir != "" {
+		inboxOpts := []service.CSVInboxOption{
+			service.WithInboxOwner(inboxConfig.Owner),
+			service.WithInboxInterval(inboxConfig.Interval),
+			service.WithInboxStableFor(inboxConfig.StableFor),
+		}
+		if inboxConfig.RequireMarker {
+			inboxOpts = append(inboxOpts, service.WithInboxReadyMarker())
+		}
+
+		inbox, err := service.NewCSVInboxWatcher(csvService, inboxConfig.Dir, inboxOpts...)
+		if err != nil {
+			slog.Error("Error initializing csv inbox", slog.String("error", err.Error()))
+		} else {
+			// The inbox stops before the csv service, so it enqueues nothing
+			// during the drain.
+			appOpts = append(appOpts, app.WithWorker("csv-inbox", inbox))
+		}
+	}
+
+	application := app.New(server, append(appOpts,
+		app.WithWorker("csv", csvService),
+		app.WithWorker("export", exportService),
+		app.WithCloser("kafka", feedbackProducer),
+		app.WithCloser("sqlite", sqlDB),
+		app.WithShutdownTimeout(serverConfig.ShutdownTimeout),
+		app.WithDrainTimeout(serverConfig.WorkerDrainTimeout))...)
+
+	// SIGHUP reloads the rules file, as does the admin endpoint.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if _, err := openingRules.Reload(); err != nil {
+				slog.Error("Error reloading opening rules", slog.String("error", err.Error()))
+				continue
+			}
+			slog.Info("Opening rules reloaded", slog.String("path", rulesConfig.Path))
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err = application.Run(ctx)
+	stop()
+
+	if err != nil {
+		slog.Error("Error shutting down", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type CSVImportConfig struct {
+	// SpoolDir holds uploads until their job finishes.
+	SpoolDir     string
+	BatchSize    int
+	ParseWorkers int
+	// Workers is the number of jobs processed at the same time and QueueSize
+	// how many more may wait for a worker.
+	Workers    int
+	QueueSize  int
+	JobTimeout time.Duration
+	// MaxActivePerUser caps the jobs of one user running at once. Zero leaves
+	// one worker free for other users.
+	MaxActivePerUser int
+	// ValidateMaxBytes limits the files checked by the dry-run endpoint.
+	ValidateMaxBytes int
+	// UploadMaxBytes limits the body of an upload request.
+	UploadMaxBytes int
+	// DedupeWindow is how long a repeated upload returns the original job.
+	DedupeWindow time.Duration
+	// ProgressRows and ProgressInterval trigger the progress feedback of
+	// running jobs.
+	ProgressRows     int
+	ProgressInterval time.Duration
+	// ArchiveMaxEntries and ArchiveMaxBytes bound the files and the
+	// decompressed size of all the file parts of an upload request.
+	ArchiveMaxEntries int
+	ArchiveMaxBytes   int
+	// ErrorReportTTL is how long the upload of a finished job is kept for
+	// its errors.csv. Zero keeps it forever.
+	ErrorReportTTL time.Duration
+}
+
+func LoadCSVImportConfig() CSVImportConfig {
+	spoolDir := strings.TrimSpace(os.Getenv("CSV_SPOOL_DIR"))
+	if spoolDir == "" {
+		spoolDir = filepath.Join(os.TempDir(), "opportunities-csv")
+	}
+
+	return CSVImportConfig{
+		SpoolDir:         spoolDir,
+		BatchSize:        envInt("CSV_BATCH_SIZE", 500),
+		ParseWorkers:     envInt("CSV_PARSE_WORKERS", runtime.NumCPU()),
+		Workers:          envInt("CSV_WORKERS", 2),
+		QueueSize:        envInt("CSV_QUEUE_SIZE", 100),
+		JobTimeout:       envDuration("CSV_JOB_TIMEOUT", 30*time.Minute),
+		MaxActivePerUser: envInt("CSV_MAX_ACTIVE_PER_USER", 0),
+		ValidateMaxBytes: envInt("CSV_VALIDATE_MAX_BYTES", 5<<20),
+		UploadMaxBytes:   envInt("CSV_UPLOAD_MAX_BYTES", 500<<20),
+		DedupeWindow:     envDuration("CSV_DEDUPE_WINDOW", 24*time.Hour),
+		ProgressRows:     envInt("CSV_PROGRESS_ROWS", 1000),
+		ProgressInterval: envDuration("CSV_PROGRESS_INTERVAL", 5*time.Second),
+
+		ArchiveMaxEntries: envInt("CSV_ARCHIVE_MAX_ENTRIES", 50),
+		ArchiveMaxBytes:   envInt("CSV_ARCHIVE_MAX_BYTES", 500<<20),
+		ErrorReportTTL:    envDuration("CSV_ERROR_REPORT_TTL", 7*24*time.Hour),
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+type CSVInboxConfig struct {
+	// Dir is the watched directory. Empty disables the inbox.
+	Dir   string
+	Owner string
+	// Interval is how often Dir is scanned and StableFor how long the size of
+	// a file must not change before it is imported, unless RequireMarker only
+	// accepts files with a ".ready" marker.
+	Interval      time.Duration
+	StableFor     time.Duration
+	RequireMarker bool
+}
+
+func LoadCSVInboxConfig() CSVInboxConfig {
+	owner := strings.TrimSpace(os.Getenv("CSV_INBOX_OWNER"))
+	if owner == "" {
+		owner = "inbox@system"
+	}
+
+	return CSVInboxConfig{
+		Dir:           strings.TrimSpace(os.Getenv("CSV_INBOX_DIR")),
+		Owner:         owner,
+		Interval:      envDuration("CSV_INBOX_INTERVAL", 5*time.Second),
+		StableFor:     envDuration("CSV_INBOX_STABLE_FOR", 10*time.Second),
+		RequireMarker: strings.EqualFold(strings.TrimSpace(os.Getenv("CSV_INBOX_READY")), "marker"),
+	}
+}
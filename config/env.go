@@ -0,0 +1,39 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func envInt(name string, fallback int) int {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		slog.Warn("invalid integer env var, using default", slog.String("name", name), slog.Int("default", fallback))
+		return fallback
+	}
+
+	return value
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		slog.Warn("invalid duration env var, using default", slog.String("name", name), slog.Duration("default", fallback))
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type ExportConfig struct {
+	// Dir holds the files of async exports.
+	Dir       string
+	QueueSize int
+	// FileTTL is how long the file of a finished export is kept. Zero keeps
+	// it forever.
+	FileTTL time.Duration
+}
+
+func LoadExportConfig() ExportConfig {
+	dir := strings.TrimSpace(os.Getenv("EXPORT_DIR"))
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "opportunities-export")
+	}
+
+	return ExportConfig{
+		Dir:       dir,
+		QueueSize: envInt("EXPORT_QUEUE_SIZE", 10),
+		FileTTL:   envDuration("EXPORT_FILE_TTL", 24*time.Hour),
+	}
+}
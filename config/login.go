@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+type LoginThrottleConfig struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+	LockoutDuration    time.Duration
+	ResetAfter         time.Duration
+}
+
+func LoadLoginThrottleConfig() LoginThrottleConfig {
+	return LoginThrottleConfig{
+		MaxAccountFailures: envInt("LOGIN_MAX_ACCOUNT_FAILURES", 5),
+		MaxIPFailures:      envInt("LOGIN_MAX_IP_FAILURES", 20),
+		BaseDelay:          envDuration("LOGIN_BACKOFF_BASE", time.Second),
+		MaxDelay:           envDuration("LOGIN_BACKOFF_MAX", 5*time.Minute),
+		LockoutDuration:    envDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		ResetAfter:         envDuration("LOGIN_FAILURE_RESET_AFTER", time.Hour),
+	}
+}
+
+type TwoFactorConfig struct {
+	// Issuer is the account label shown by authenticator apps.
+	Issuer string
+}
+
+func LoadTwoFactorConfig() TwoFactorConfig {
+	issuer := strings.TrimSpace(os.Getenv("TOTP_ISSUER"))
+	if issuer == "" {
+		issuer = "Opportunities API"
+	}
+
+	return TwoFactorConfig{Issuer: issuer}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+type OIDCConfig struct {
+	Enabled      bool
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+	GroupRoles   map[string]string
+	// MaxPendingLogins caps the OIDC logins started and not yet completed.
+	MaxPendingLogins int
+}
+
+// LoadOIDCConfig reads the identity provider settings. OIDC login is enabled
+// only when OIDC_ISSUER_URL is set. OIDC_GROUP_ROLES maps groups to local
+// roles, e.g. "recruiters=recruiter,hr-admins=admin".
+func LoadOIDCConfig() OIDCConfig {
+	issuer := strings.TrimSpace(os.Getenv("OIDC_ISSUER_URL"))
+
+	redirectURL := strings.TrimSpace(os.Getenv("OIDC_REDIRECT_URL"))
+	if redirectURL == "" {
+		redirectURL = "http://localhost:8080/api/v1/login/oidc/callback"
+	}
+
+	scopesRaw := strings.TrimSpace(os.Getenv("OIDC_SCOPES"))
+	if scopesRaw == "" {
+		scopesRaw = "openid,email,profile,groups"
+	}
+
+	groupsClaim := strings.TrimSpace(os.Getenv("OIDC_GROUPS_CLAIM"))
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+
+	scopes := strings.Split(scopesRaw, ",")
+	for i := range scopes {
+		scopes[i] = strings.TrimSpace(scopes[i])
+	}
+
+	groupRoles := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv("OIDC_GROUP_ROLES"), ",") {
+		group, role, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+
+		group = strings.TrimSpace(group)
+		role = strings.TrimSpace(role)
+		if group != "" && role != "" {
+			groupRoles[group] = role
+		}
+	}
+
+	return OIDCConfig{
+		Enabled:          issuer != "",
+		IssuerURL:        issuer,
+		ClientID:         strings.TrimSpace(os.Getenv("OIDC_CLIENT_ID")),
+		ClientSecret:     strings.TrimSpace(os.Getenv("OIDC_CLIENT_SECRET")),
+		RedirectURL:      redirectURL,
+		Scopes:           scopes,
+		GroupsClaim:      groupsClaim,
+		GroupRoles:       groupRoles,
+		MaxPendingLogins: envInt("OIDC_MAX_PENDING_LOGINS", 10000),
+	}
+}
@@ -0,0 +1,17 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+type OpeningRulesConfig struct {
+	// Path is the YAML or JSON rules file. Empty means no rules.
+	Path string
+}
+
+func LoadOpeningRulesConfig() OpeningRulesConfig {
+	return OpeningRulesConfig{
+		Path: strings.TrimSpace(os.Getenv("OPENING_RULES_FILE")),
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+type ServerConfig struct {
+	Addr string
+	// ShutdownTimeout bounds the wait for the requests in flight and
+	// WorkerDrainTimeout, which starts after it, the wait for running jobs.
+	ShutdownTimeout    time.Duration
+	WorkerDrainTimeout time.Duration
+	// TrustedProxies lists the proxies, as IPs or CIDRs, whose
+	// X-Forwarded-For header gives the client IP. Empty trusts none, so the
+	// client IP is the peer address.
+	TrustedProxies []string
+}
+
+func LoadServerConfig() ServerConfig {
+	addr := strings.TrimSpace(os.Getenv("HTTP_ADDR"))
+	if addr == "" {
+		addr = ":8080"
+	}
+
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+
+	return ServerConfig{
+		Addr:               addr,
+		ShutdownTimeout:    envDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		WorkerDrainTimeout: envDuration("WORKER_DRAIN_TIMEOUT", 30*time.Second),
+		TrustedProxies:     trustedProxies,
+	}
+}
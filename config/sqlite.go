@@ -40,7 +40,17 @@ func InitializeSQLite() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
 	}
 
-	err = db.AutoMigrate(&schemas.Openings{})
+	err = db.AutoMigrate(
+		&schemas.Openings{},
+		&schemas.Session{},
+		&schemas.TwoFactor{},
+		&schemas.TwoFactorPolicy{},
+		&schemas.ImportJob{},
+		&schemas.ImportRowError{},
+		&schemas.ImportJobFile{},
+		&schemas.CSVMappingPreset{},
+		&schemas.ExportJob{},
+	)
 	if err != nil {
 		logger.Error("sqlite auto-migration failed", slog.Any("error", err))
 		return nil, fmt.Errorf("failed to auto-migrate database: %w", err)
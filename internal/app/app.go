@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultShutdownTimeout = 30 * time.Second
+	defaultDrainTimeout    = 30 * time.Second
+)
+
+// Server is the HTTP server run by App, usually an *http.Server.
+type Server interface {
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
+}
+
+// Worker is a background service started with the server and stopped after
+// it, such as the CSV import and export services.
+type Worker interface {
+	Start(ctx context.Context)
+	Shutdown(ctx context.Context) error
+}
+
+type namedWorker struct {
+	name   string
+	worker Worker
+}
+
+type namedCloser struct {
+	name   string
+	closer io.Closer
+}
+
+// App runs the server and the workers until its context is done, then shuts
+// them down in order: the server stops accepting requests, the workers finish
+// or persist their jobs and the closers (producer, database) are closed last.
+type App struct {
+	logger          *slog.Logger
+	server          Server
+	workers         []namedWorker
+	closers         []namedCloser
+	shutdownTimeout time.Duration
+	drainTimeout    time.Duration
+}
+
+type Option func(*App)
+
+// WithWorker adds a worker. Workers are stopped in the order they are added.
+func WithWorker(name string, worker Worker) Option {
+	return func(a *App) {
+		a.workers = append(a.workers, namedWorker{name: name, worker: worker})
+	}
+}
+
+// WithCloser adds a resource closed after every worker stopped. Closers are
+// closed in the order they are added.
+func WithCloser(name string, closer io.Closer) Option {
+	return func(a *App) {
+		a.closers = append(a.closers, namedCloser{name: name, closer: closer})
+	}
+}
+
+// WithShutdownTimeout bounds the time the server waits for the requests in
+// flight.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(a *App) {
+		if timeout > 0 {
+			a.shutdownTimeout = timeout
+		}
+	}
+}
+
+// WithDrainTimeout bounds the time the workers have to finish or persist
+// their jobs. It starts once the server stopped, so a slow server does not
+// eat into it.
+func WithDrainTimeout(timeout time.Duration) Option {
+	return func(a *App) {
+		if timeout > 0 {
+			a.drainTimeout = timeout
+		}
+	}
+}
+
+func New(server Server, opts ...Option) *App {
+	a := &App{
+		logger:          slog.Default().With("group", "app"),
+		server:          server,
+		shutdownTimeout: defaultShutdownTimeout,
+		drainTimeout:    defaultDrainTimeout,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Run starts the workers and serves until ctx is done or the server fails,
+// then shuts everything down. It returns the server error, if any, joined with
+// the shutdown errors.
+func (a *App) Run(ctx context.Context) error {
+	// The workers outlive ctx: they are stopped by Shutdown, after the server.
+	workerCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	for _, w := range a.workers {
+		w.worker.Start(workerCtx)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- a.server.ListenAndServe()
+	}()
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		a.logger.Info("shutting down")
+	case err := <-served:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.logger.Error("server stopped", slog.String("error", err.Error()))
+			serveErr = fmt.Errorf("server: %w", err)
+		}
+	}
+
+	return errors.Join(serveErr, a.shutdown())
+}
+
+// shutdown gives the server and the workers a deadline each, so the workers
+// still have their whole drain time when the server used all of its own.
+func (a *App) shutdown() error {
+	var errs []error
+	if err := a.shutdownServer(); err != nil {
+		errs = append(errs, fmt.Errorf("server: %w", err))
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), a.drainTimeout)
+	defer cancel()
+
+	for _, w := range a.workers {
+		if err := w.worker.Shutdown(drainCtx); err != nil {
+			a.logger.Error("worker shutdown", slog.String("worker", w.name), slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("%s: %w", w.name, err))
+		}
+	}
+
+	// Closers run even past the deadline, so buffered messages are flushed
+	// and the database is not left open.
+	for _, c := range a.closers {
+		if err := c.closer.Close(); err != nil {
+			a.logger.Error("close", slog.String("resource", c.name), slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (a *App) shutdownServer() error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+
+	return a.server.Shutdown(ctx)
+}
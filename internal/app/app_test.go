@@ -0,0 +1,186 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects the lifecycle events of the fakes, in order.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+type fakeServer struct {
+	rec     *recorder
+	serving chan struct{}
+	closed  chan struct{}
+	err     error
+	// slow makes Shutdown wait for its deadline, as with a stuck request.
+	slow bool
+}
+
+func newFakeServer(rec *recorder) *fakeServer {
+	return &fakeServer{rec: rec, serving: make(chan struct{}), closed: make(chan struct{})}
+}
+
+func (s *fakeServer) ListenAndServe() error {
+	s.rec.add("server:serve")
+	close(s.serving)
+	if s.err != nil {
+		return s.err
+	}
+
+	<-s.closed
+	return http.ErrServerClosed
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	s.rec.add("server:shutdown")
+	close(s.closed)
+	if s.slow {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+type fakeWorker struct {
+	rec  *recorder
+	name string
+	err  error
+	// drainErr is the state of the context Shutdown received.
+	drainErr error
+}
+
+func (w *fakeWorker) Start(context.Context) {
+	w.rec.add(w.name + ":start")
+}
+
+func (w *fakeWorker) Shutdown(ctx context.Context) error {
+	w.rec.add(w.name + ":shutdown")
+	w.drainErr = ctx.Err()
+	return w.err
+}
+
+type fakeCloser struct {
+	rec  *recorder
+	name string
+}
+
+func (c *fakeCloser) Close() error {
+	c.rec.add(c.name + ":close")
+	return nil
+}
+
+func TestApp_ShutsDownInOrder(t *testing.T) {
+	rec := &recorder{}
+	server := newFakeServer(rec)
+	app := New(server,
+		WithWorker("csv", &fakeWorker{rec: rec, name: "csv"}),
+		WithWorker("export", &fakeWorker{rec: rec, name: "export"}),
+		WithCloser("kafka", &fakeCloser{rec: rec, name: "kafka"}),
+		WithCloser("sqlite", &fakeCloser{rec: rec, name: "sqlite"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.Run(ctx) }()
+
+	<-server.serving
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected run error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected Run to return after the context is done")
+	}
+
+	want := []string{
+		"csv:start", "export:start", "server:serve",
+		"server:shutdown", "csv:shutdown", "export:shutdown", "kafka:close", "sqlite:close",
+	}
+	if got := rec.list(); !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestApp_ClosesAfterWorkerError(t *testing.T) {
+	rec := &recorder{}
+	server := newFakeServer(rec)
+	stuck := errors.New("jobs interrupted")
+	app := New(server,
+		WithWorker("csv", &fakeWorker{rec: rec, name: "csv", err: stuck}),
+		WithCloser("sqlite", &fakeCloser{rec: rec, name: "sqlite"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := app.Run(ctx); !errors.Is(err, stuck) {
+		t.Fatalf("expected the worker error, got %v", err)
+	}
+
+	got := rec.list()
+	if got[len(got)-1] != "sqlite:close" {
+		t.Fatalf("expected the database to be closed last, got %v", got)
+	}
+}
+
+func TestApp_ShutsDownWhenServerFails(t *testing.T) {
+	rec := &recorder{}
+	server := newFakeServer(rec)
+	server.err = errors.New("address already in use")
+	app := New(server,
+		WithWorker("csv", &fakeWorker{rec: rec, name: "csv"}),
+		WithCloser("sqlite", &fakeCloser{rec: rec, name: "sqlite"}))
+
+	if err := app.Run(context.Background()); !errors.Is(err, server.err) {
+		t.Fatalf("expected the server error, got %v", err)
+	}
+
+	if got := rec.list(); !slices.Contains(got, "csv:shutdown") || !slices.Contains(got, "sqlite:close") {
+		t.Fatalf("expected workers and closers to stop after a server error, got %v", got)
+	}
+}
+
+func TestApp_WorkersDrainAfterSlowServer(t *testing.T) {
+	rec := &recorder{}
+	server := newFakeServer(rec)
+	server.slow = true
+	worker := &fakeWorker{rec: rec, name: "csv"}
+	app := New(server,
+		WithWorker("csv", worker),
+		WithShutdownTimeout(10*time.Millisecond),
+		WithDrainTimeout(time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := app.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the server deadline error, got %v", err)
+	}
+
+	if worker.drainErr != nil {
+		t.Fatalf("expected the worker to keep its drain time, got %v", worker.drainErr)
+	}
+}
@@ -0,0 +1,193 @@
+// Package archive expands compressed uploads: gzip files and zip archives of
+// several files.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	KindPlain = "plain"
+	KindGzip  = "gzip"
+	KindZip   = "zip"
+)
+
+var (
+	ErrTooManyEntries = errors.New("archive has too many files")
+	ErrTooLarge       = errors.New("decompressed upload is too large")
+	ErrEmpty          = errors.New("archive has no files")
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+	// zipEmptyMagic starts a zip archive without files.
+	zipEmptyMagic = []byte("PK\x05\x06")
+)
+
+// Limits bound what an upload may expand to. Zero means no limit.
+type Limits struct {
+	// MaxEntries caps the files of an upload.
+	MaxEntries int
+	// MaxBytes caps the decompressed size of all the files of an upload.
+	MaxBytes int64
+}
+
+// Budget is what the uploads of one request have used of the limits so far.
+// Walking several uploads with the same budget bounds them together, so a
+// request cannot get past the limits by splitting the files among parts.
+type Budget struct {
+	limits  Limits
+	entries int
+	read    int64
+}
+
+// NewBudget returns an unused budget for limits.
+func NewBudget(limits Limits) *Budget {
+	return &Budget{limits: limits}
+}
+
+// Detect returns the kind of upload from its first bytes.
+func Detect(r io.ReaderAt) string {
+	head := make([]byte, 4)
+	n, _ := r.ReadAt(head, 0)
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		return KindGzip
+	case bytes.HasPrefix(head, zipMagic), bytes.HasPrefix(head, zipEmptyMagic):
+		return KindZip
+	default:
+		return KindPlain
+	}
+}
+
+// Walk calls fn with each file of the upload: the upload itself when it is
+// not compressed, the decompressed content of a gzip file or every file of a
+// zip archive. Decompressed bytes are counted as they are read, so headers
+// that understate the size cannot get past the limits; reading past them
+// fails with ErrTooLarge.
+func Walk(name string, r io.ReaderAt, size int64, limits Limits, fn func(name string, r io.Reader) error) error {
+	return NewBudget(limits).Walk(name, r, size, fn)
+}
+
+// Walk is like the Walk function, but counts the files and decompressed
+// bytes on the budget, along with those of the uploads walked before.
+func (b *Budget) Walk(name string, r io.ReaderAt, size int64, fn func(name string, r io.Reader) error) error {
+	switch Detect(r) {
+	case KindGzip:
+		if err := b.addEntries(1); err != nil {
+			return err
+		}
+		return walkGzip(name, r, size, b, fn)
+	case KindZip:
+		return walkZip(r, size, b, fn)
+	default:
+		if err := b.addEntries(1); err != nil {
+			return err
+		}
+		return fn(name, io.NewSectionReader(r, 0, size))
+	}
+}
+
+func (b *Budget) addEntries(n int) error {
+	if b.limits.MaxEntries > 0 && b.entries+n > b.limits.MaxEntries {
+		return fmt.Errorf("%w: %d files, at most %d", ErrTooManyEntries, b.entries+n, b.limits.MaxEntries)
+	}
+
+	b.entries += n
+	return nil
+}
+
+func walkGzip(name string, r io.ReaderAt, size int64, b *Budget, fn func(name string, r io.Reader) error) error {
+	gz, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return fmt.Errorf("invalid gzip file: %w", err)
+	}
+	defer gz.Close()
+
+	// "openings.csv.gz" holds "openings.csv".
+	inner := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".gzip")
+	if inner == name && gz.Name != "" {
+		inner = gz.Name
+	}
+
+	return fn(inner, &limitedReader{r: gz, budget: b})
+}
+
+func walkZip(r io.ReaderAt, size int64, b *Budget, fn func(name string, r io.Reader) error) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("invalid zip file: %w", err)
+	}
+
+	var files []*zip.File
+	for _, file := range zr.File {
+		if !skipEntry(file) {
+			files = append(files, file)
+		}
+	}
+
+	if len(files) == 0 {
+		return ErrEmpty
+	}
+	if err := b.addEntries(len(files)); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		// The declared size rejects an obvious bomb before inflating it.
+		if b.limits.MaxBytes > 0 && b.read+int64(file.UncompressedSize64) > b.limits.MaxBytes {
+			return ErrTooLarge
+		}
+
+		if err := walkZipFile(file, b, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func walkZipFile(file *zip.File, b *Budget, fn func(name string, r io.Reader) error) error {
+	rc, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("invalid zip entry %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+
+	return fn(file.Name, &limitedReader{r: rc, budget: b})
+}
+
+// skipEntry ignores folders and the metadata some archivers add.
+func skipEntry(file *zip.File) bool {
+	if file.FileInfo().IsDir() {
+		return true
+	}
+
+	base := path.Base(file.Name)
+	return strings.HasPrefix(file.Name, "__MACOSX/") || strings.HasPrefix(base, ".")
+}
+
+type limitedReader struct {
+	r      io.Reader
+	budget *Budget
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.budget.read += int64(n)
+	if l.budget.limits.MaxBytes > 0 && l.budget.read > l.budget.limits.MaxBytes {
+		return n, ErrTooLarge
+	}
+
+	return n, err
+}
@@ -0,0 +1,140 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// walkAll returns the content of every file of the upload, by name.
+func walkAll(t *testing.T, name string, data []byte, limits Limits) (map[string]string, []string, error) {
+	t.Helper()
+
+	files := map[string]string{}
+	var order []string
+	err := Walk(name, bytes.NewReader(data), int64(len(data)), limits, func(name string, r io.Reader) error {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		files[name] = string(content)
+		order = append(order, name)
+		return nil
+	})
+
+	return files, order, err
+}
+
+func gzipBytes(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func zipBytes(t *testing.T, files ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		w, err := zw.Create(files[i])
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		if _, err := w.Write([]byte(files[i+1])); err != nil {
+			t.Fatalf("zip write: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestWalk_ExpandsUploads(t *testing.T) {
+	files, _, err := walkAll(t, "openings.csv", []byte("role\nGo Dev\n"), Limits{})
+	if err != nil || files["openings.csv"] != "role\nGo Dev\n" {
+		t.Fatalf("expected the plain upload as is, got %v, %v", files, err)
+	}
+
+	files, _, err = walkAll(t, "openings.csv.gz", gzipBytes(t, "role\nGo Dev\n"), Limits{})
+	if err != nil || files["openings.csv"] != "role\nGo Dev\n" {
+		t.Fatalf("expected the decompressed gzip file, got %v, %v", files, err)
+	}
+
+	data := zipBytes(t,
+		"week/first.csv", "role\nGo Dev\n",
+		"week/", "",
+		"__MACOSX/week/._first.csv", "junk",
+		".DS_Store", "junk",
+		"week/second.csv", "role\nSRE\n")
+	files, order, err := walkAll(t, "week.zip", data, Limits{})
+	if err != nil {
+		t.Fatalf("unexpected walk error: %v", err)
+	}
+	if strings.Join(order, ",") != "week/first.csv,week/second.csv" || files["week/second.csv"] != "role\nSRE\n" {
+		t.Fatalf("expected the two csv files in order, got %v", files)
+	}
+}
+
+func TestWalk_EnforcesLimits(t *testing.T) {
+	data := zipBytes(t, "a.csv", "a", "b.csv", "b", "c.csv", "c")
+	if _, _, err := walkAll(t, "bundle.zip", data, Limits{MaxEntries: 2}); !errors.Is(err, ErrTooManyEntries) {
+		t.Fatalf("expected ErrTooManyEntries, got %v", err)
+	}
+
+	big := strings.Repeat("0", 1<<20)
+	if _, _, err := walkAll(t, "bomb.csv.gz", gzipBytes(t, big), Limits{MaxBytes: 1 << 10}); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge for gzip, got %v", err)
+	}
+
+	// The limit covers all the files of the archive together.
+	data = zipBytes(t, "a.csv", strings.Repeat("a", 600), "b.csv", strings.Repeat("b", 600))
+	if _, _, err := walkAll(t, "bundle.zip", data, Limits{MaxBytes: 1000}); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge for zip, got %v", err)
+	}
+
+	if _, _, err := walkAll(t, "empty.zip", zipBytes(t), Limits{}); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("expected ErrEmpty, got %v", err)
+	}
+}
+
+func TestBudget_SharedByUploads(t *testing.T) {
+	drain := func(name string, r io.Reader) error {
+		_, err := io.Copy(io.Discard, r)
+		return err
+	}
+	walk := func(b *Budget, name string, data []byte) error {
+		return b.Walk(name, bytes.NewReader(data), int64(len(data)), drain)
+	}
+
+	b := NewBudget(Limits{MaxEntries: 2})
+	if err := walk(b, "a.csv", []byte("a")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := walk(b, "bundle.zip", zipBytes(t, "b.csv", "b", "c.csv", "c")); !errors.Is(err, ErrTooManyEntries) {
+		t.Fatalf("expected ErrTooManyEntries across uploads, got %v", err)
+	}
+
+	b = NewBudget(Limits{MaxBytes: 1000})
+	if err := walk(b, "a.csv.gz", gzipBytes(t, strings.Repeat("a", 600))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := walk(b, "b.csv.gz", gzipBytes(t, strings.Repeat("b", 600))); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge across uploads, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// UsedChallenges remembers the challenge tokens already exchanged for a
+// session, until they expire, so each one works only once.
+type UsedChallenges struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+func NewUsedChallenges() *UsedChallenges {
+	return &UsedChallenges{used: make(map[string]time.Time)}
+}
+
+// Consume marks the challenge as used. It returns false when it was used
+// before or carries no ID.
+func (u *UsedChallenges) Consume(claims *Claims) bool {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return false
+	}
+
+	now := time.Now()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for id, expiresAt := range u.used {
+		if now.After(expiresAt) {
+			delete(u.used, id)
+		}
+	}
+
+	if _, ok := u.used[claims.ID]; ok {
+		return false
+	}
+
+	u.used[claims.ID] = claims.ExpiresAt.Time
+	return true
+}
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -9,22 +11,158 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	RoleAdmin     = "admin"
+	RoleRecruiter = "recruiter"
+)
+
+const (
+	// SessionTokenTTL is the lifetime of access tokens bound to a refresh
+	// session. Routes that check the session reject them as soon as it is
+	// revoked; elsewhere the short lifetime bounds their use.
+	SessionTokenTTL = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+	// ChallengeTokenTTL bounds the time between the password step and the
+	// second factor.
+	ChallengeTokenTTL = 5 * time.Minute
+)
+
+const (
+	PurposeTwoFactor       = "2fa"
+	PurposeTwoFactorEnroll = "2fa_enroll"
+)
+
 var secretKey = []byte("my-secret-key")
 
-func GenerateToken(email string) (string, error) {
-	claims := jwt.MapClaims{
-		"email": email,
-		"exp":   time.Now().Add(time.Hour * 24).Unix(),
+type Claims struct {
+	Email     string   `json:"email"`
+	Roles     []string `json:"roles,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+	// Purpose is set on restricted tokens (e.g. 2FA challenges) that must not
+	// be accepted as access tokens.
+	Purpose string `json:"purpose,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Claims) IsAdmin() bool {
+	return c.HasRole(RoleAdmin)
+}
+
+func GenerateToken(email string, roles ...string) (string, error) {
+	return signToken(Claims{
+		Email: email,
+		Roles: roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
+		},
+	})
+}
+
+// GenerateSessionToken issues a short-lived access token bound to a refresh
+// session.
+func GenerateSessionToken(sessionID, email string, roles ...string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(SessionTokenTTL)
+
+	token, err := signToken(Claims{
+		Email:     email,
+		Roles:     roles,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	})
+
+	return token, expiresAt, err
+}
+
+// NewRefreshToken returns an opaque refresh token and the hash to persist.
+func NewRefreshToken() (string, string, error) {
+	token, err := randomString(32)
+	if err != nil {
+		return "", "", err
 	}
 
+	return token, HashRefreshToken(token), nil
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func signToken(claims Claims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(secretKey)
 }
 
-func ValidateToken(tokenString string) error {
+// GenerateChallengeToken issues a short-lived token proving the password step
+// succeeded. It can only be exchanged for a session after the second factor.
+func GenerateChallengeToken(purpose, email string, roles ...string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ChallengeTokenTTL)
+
+	// The ID lets the token be marked as used; see UsedChallenges.
+	id, err := randomString(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	token, err := signToken(Claims{
+		Email:   email,
+		Roles:   roles,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+
+	return token, expiresAt, err
+}
+
+func ValidateChallengeToken(tokenString string, purposes ...string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, purpose := range purposes {
+		if claims.Purpose == purpose {
+			return claims, nil
+		}
+	}
+
+	return nil, errors.New("invalid or expired challenge token")
+}
+
+func ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != "" {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	return claims, nil
+}
+
+func parseToken(tokenString string) (*Claims, error) {
 	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
@@ -32,8 +170,8 @@ func ValidateToken(tokenString string) error {
 	})
 
 	if err != nil || !token.Valid {
-		return errors.New("invalid or expired token")
+		return nil, errors.New("invalid or expired token")
 	}
 
-	return nil
+	return claims, nil
 }
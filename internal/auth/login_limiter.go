@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginAttempts is the failure state tracked for one IP or account.
+type LoginAttempts struct {
+	Failures     int
+	LastFailure  time.Time
+	BlockedUntil time.Time
+}
+
+// LoginAttemptStore persists login failure counters. MemoryLoginAttemptStore
+// works for a single process; a shared store can be plugged in to throttle
+// across replicas.
+type LoginAttemptStore interface {
+	Get(ctx context.Context, key string) (LoginAttempts, error)
+	Save(ctx context.Context, key string, attempts LoginAttempts, ttl time.Duration) error
+	Reset(ctx context.Context, key string) error
+}
+
+type LoginLimiterConfig struct {
+	// MaxAccountFailures locks the account after this many consecutive failures.
+	MaxAccountFailures int
+	// MaxIPFailures locks the client IP after this many consecutive failures.
+	MaxIPFailures int
+	// BaseDelay is the backoff after the first failure; it doubles on each
+	// subsequent failure up to MaxDelay.
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	// ResetAfter forgets failures after this period without new ones.
+	ResetAfter time.Duration
+}
+
+type LoginLimiter struct {
+	logger *slog.Logger
+	store  LoginAttemptStore
+	cfg    LoginLimiterConfig
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+func NewLoginLimiter(store LoginAttemptStore, cfg LoginLimiterConfig) *LoginLimiter {
+	return &LoginLimiter{
+		logger: slog.Default().With("group", "login_limiter"),
+		store:  store,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// Allow returns how long the caller must wait before trying again, or zero
+// when the attempt may proceed.
+func (l *LoginLimiter) Allow(ctx context.Context, ip, email string) (time.Duration, error) {
+	now := l.now()
+	var retryAfter time.Duration
+
+	for _, key := range []string{ipKey(ip), accountKey(email)} {
+		attempts, err := l.store.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+
+		if wait := attempts.BlockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// LoginReservation is an attempt counted as failed before the credentials
+// are checked. Refund gives it back when they turn out valid.
+type LoginReservation struct {
+	ip    string
+	email string
+	// ipBefore is the IP state before the reservation and ipFailures the
+	// count it left.
+	ipBefore   LoginAttempts
+	ipFailures int
+}
+
+// Reserve checks and counts an attempt in one step: unless the IP or the
+// account must wait, it records the attempt as failed and returns its
+// reservation. Parallel attempts therefore see each other's failure and
+// cannot all pass the check before any failure is recorded. The step is
+// atomic within the process.
+func (l *LoginLimiter) Reserve(ctx context.Context, ip, email string) (*LoginReservation, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	retryAfter, err := l.Allow(ctx, ip, email)
+	if err != nil || retryAfter > 0 {
+		return nil, retryAfter, err
+	}
+
+	ipBefore, err := l.store.Get(ctx, ipKey(ip))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if _, err := l.recordFailure(ctx, ip, email); err != nil {
+		return nil, 0, err
+	}
+
+	ipAfter, err := l.store.Get(ctx, ipKey(ip))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &LoginReservation{ip: ip, email: email, ipBefore: ipBefore, ipFailures: ipAfter.Failures}, 0, nil
+}
+
+// Refund gives back a reserved attempt that succeeded: the account failures
+// are cleared and the IP returns to its state before the reservation, or
+// loses the one failure when others were recorded since. A nil reservation
+// is ignored.
+func (l *LoginLimiter) Refund(ctx context.Context, r *LoginReservation) error {
+	if r == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.store.Reset(ctx, accountKey(r.email)); err != nil {
+		return err
+	}
+
+	key := ipKey(r.ip)
+	attempts, err := l.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case attempts.Failures == r.ipFailures:
+		attempts = r.ipBefore
+	case attempts.Failures > 0:
+		attempts.Failures--
+	}
+
+	if attempts.Failures == 0 {
+		return l.store.Reset(ctx, key)
+	}
+
+	return l.store.Save(ctx, key, attempts, l.cfg.ResetAfter+max(0, attempts.BlockedUntil.Sub(l.now())))
+}
+
+// RecordFailure registers a failed attempt for both the IP and the account and
+// returns the resulting wait time.
+func (l *LoginLimiter) RecordFailure(ctx context.Context, ip, email string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.recordFailure(ctx, ip, email)
+}
+
+func (l *LoginLimiter) recordFailure(ctx context.Context, ip, email string) (time.Duration, error) {
+	accountWait, err := l.registerFailure(ctx, accountKey(email), l.cfg.MaxAccountFailures, slog.String("email", email), slog.String("ip", ip))
+	if err != nil {
+		return 0, err
+	}
+
+	ipWait, err := l.registerFailure(ctx, ipKey(ip), l.cfg.MaxIPFailures, slog.String("ip", ip))
+	if err != nil {
+		return 0, err
+	}
+
+	if ipWait > accountWait {
+		return ipWait, nil
+	}
+
+	return accountWait, nil
+}
+
+// RecordSuccess clears the account failures. IP failures are kept so that a
+// valid login does not reset an ongoing spray across many accounts.
+func (l *LoginLimiter) RecordSuccess(ctx context.Context, email string) error {
+	return l.store.Reset(ctx, accountKey(email))
+}
+
+func (l *LoginLimiter) registerFailure(ctx context.Context, key string, maxFailures int, attrs ...any) (time.Duration, error) {
+	now := l.now()
+
+	attempts, err := l.store.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	if !attempts.LastFailure.IsZero() && now.Sub(attempts.LastFailure) > l.cfg.ResetAfter {
+		attempts = LoginAttempts{}
+	}
+
+	attempts.Failures++
+	attempts.LastFailure = now
+
+	wait := l.backoff(attempts.Failures)
+	if maxFailures > 0 && attempts.Failures >= maxFailures {
+		wait = l.cfg.LockoutDuration
+
+		l.logger.Warn("login lockout",
+			append(attrs,
+				slog.String("key", key),
+				slog.Int("failures", attempts.Failures),
+				slog.Time("locked_until", now.Add(wait)))...)
+	}
+
+	attempts.BlockedUntil = now.Add(wait)
+
+	if err := l.store.Save(ctx, key, attempts, l.cfg.ResetAfter+wait); err != nil {
+		return 0, err
+	}
+
+	return wait, nil
+}
+
+func (l *LoginLimiter) backoff(failures int) time.Duration {
+	delay := l.cfg.BaseDelay
+	for i := 1; i < failures && delay < l.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > l.cfg.MaxDelay {
+		delay = l.cfg.MaxDelay
+	}
+
+	return delay
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+type memoryLoginEntry struct {
+	attempts  LoginAttempts
+	expiresAt time.Time
+}
+
+type MemoryLoginAttemptStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryLoginEntry
+	lastSweep time.Time
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{entries: map[string]memoryLoginEntry{}}
+}
+
+func (s *MemoryLoginAttemptStore) Get(_ context.Context, key string) (LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return LoginAttempts{}, nil
+	}
+
+	if time.Now().After(entry.expiresAt) {
+		delete(s.entries, key)
+		return LoginAttempts{}, nil
+	}
+
+	return entry.attempts, nil
+}
+
+func (s *MemoryLoginAttemptStore) Save(_ context.Context, key string, attempts LoginAttempts, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, entry := range s.entries {
+			if now.After(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	s.entries[key] = memoryLoginEntry{attempts: attempts, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	ctx := context.Background()
+	cfg := LoginLimiterConfig{
+		MaxAccountFailures: 3,
+		MaxIPFailures:      10,
+		BaseDelay:          time.Second,
+		MaxDelay:           time.Minute,
+		LockoutDuration:    15 * time.Minute,
+		ResetAfter:         time.Hour,
+	}
+
+	t.Run("backoff grows exponentially and locks the account", func(t *testing.T) {
+		limiter := NewLoginLimiter(NewMemoryLoginAttemptStore(), cfg)
+		now := time.Now()
+		limiter.now = func() time.Time { return now }
+
+		expected := []time.Duration{time.Second, 2 * time.Second, 15 * time.Minute}
+		for i, want := range expected {
+			wait, err := limiter.RecordFailure(ctx, "10.0.0.1", "user@test.com")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if wait != want {
+				t.Fatalf("failure %d: expected wait %s, got %s", i+1, want, wait)
+			}
+		}
+
+		retryAfter, err := limiter.Allow(ctx, "10.0.0.2", "USER@test.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if retryAfter != 15*time.Minute {
+			t.Fatalf("expected account locked from another ip, got retry after %s", retryAfter)
+		}
+
+		now = now.Add(16 * time.Minute)
+		retryAfter, _ = limiter.Allow(ctx, "10.0.0.2", "user@test.com")
+		if retryAfter != 0 {
+			t.Fatalf("expected lockout to expire, got retry after %s", retryAfter)
+		}
+	})
+
+	t.Run("ip is throttled across accounts", func(t *testing.T) {
+		limiter := NewLoginLimiter(NewMemoryLoginAttemptStore(), LoginLimiterConfig{
+			MaxAccountFailures: 100,
+			MaxIPFailures:      2,
+			BaseDelay:          time.Millisecond,
+			MaxDelay:           time.Millisecond,
+			LockoutDuration:    time.Hour,
+			ResetAfter:         time.Hour,
+		})
+
+		_, _ = limiter.RecordFailure(ctx, "10.0.0.1", "a@test.com")
+		wait, _ := limiter.RecordFailure(ctx, "10.0.0.1", "b@test.com")
+		if wait != time.Hour {
+			t.Fatalf("expected ip lockout, got %s", wait)
+		}
+
+		retryAfter, _ := limiter.Allow(ctx, "10.0.0.1", "c@test.com")
+		if retryAfter <= 0 {
+			t.Fatalf("expected ip to be blocked for a new account")
+		}
+	})
+
+	t.Run("success resets the account counter", func(t *testing.T) {
+		limiter := NewLoginLimiter(NewMemoryLoginAttemptStore(), cfg)
+		now := time.Now()
+		limiter.now = func() time.Time { return now }
+
+		_, _ = limiter.RecordFailure(ctx, "10.0.0.1", "user@test.com")
+		_, _ = limiter.RecordFailure(ctx, "10.0.0.1", "user@test.com")
+		if err := limiter.RecordSuccess(ctx, "user@test.com"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		wait, _ := limiter.RecordFailure(ctx, "10.0.0.9", "user@test.com")
+		if wait != time.Second {
+			t.Fatalf("expected backoff to restart after success, got %s", wait)
+		}
+	})
+
+	t.Run("parallel attempts reserve one at a time", func(t *testing.T) {
+		limiter := NewLoginLimiter(NewMemoryLoginAttemptStore(), cfg)
+		now := time.Now()
+		limiter.now = func() time.Time { return now }
+
+		var wg sync.WaitGroup
+		var allowed atomic.Int32
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				reservation, retryAfter, err := limiter.Reserve(ctx, "10.0.0.1", "user@test.com")
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				if reservation != nil && retryAfter == 0 {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if allowed.Load() != 1 {
+			t.Fatalf("expected a single attempt to pass the check, got %d", allowed.Load())
+		}
+
+		retryAfter, _ := limiter.Allow(ctx, "10.0.0.1", "user@test.com")
+		if retryAfter != time.Second {
+			t.Fatalf("expected the reserved attempt to count as a failure, got %s", retryAfter)
+		}
+	})
+
+	t.Run("refund gives the reserved attempt back", func(t *testing.T) {
+		limiter := NewLoginLimiter(NewMemoryLoginAttemptStore(), cfg)
+		now := time.Now()
+		limiter.now = func() time.Time { return now }
+
+		_, _ = limiter.RecordFailure(ctx, "10.0.0.1", "a@test.com")
+		now = now.Add(time.Minute)
+
+		reservation, _, err := limiter.Reserve(ctx, "10.0.0.1", "user@test.com")
+		if err != nil || reservation == nil {
+			t.Fatalf("expected a reservation, got %v (%v)", reservation, err)
+		}
+		if err := limiter.Refund(ctx, reservation); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		retryAfter, _ := limiter.Allow(ctx, "10.0.0.1", "user@test.com")
+		if retryAfter != 0 {
+			t.Fatalf("expected no wait after a refund, got %s", retryAfter)
+		}
+
+		// The earlier failure of the IP is kept.
+		attempts, _ := limiter.store.Get(ctx, ipKey("10.0.0.1"))
+		if attempts.Failures != 1 {
+			t.Fatalf("expected the ip to keep its earlier failure, got %d", attempts.Failures)
+		}
+	})
+}
@@ -0,0 +1,465 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcLoginTTL             = 10 * time.Minute
+	oidcJWKSMinInterval      = 30 * time.Second
+	defaultOIDCPendingLogins = 10000
+)
+
+var (
+	ErrOIDCInvalidState  = errors.New("invalid or expired oidc state")
+	ErrOIDCNoRoles       = errors.New("no role mapped for the user groups")
+	ErrOIDCTooManyLogins = errors.New("too many oidc logins in progress")
+)
+
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+	// GroupRoles maps an identity provider group to a local role.
+	GroupRoles map[string]string
+	// MaxPendingLogins caps the logins started and not yet completed; new
+	// logins are refused while it is reached. Defaults to 10000.
+	MaxPendingLogins int
+}
+
+type OIDCIdentity struct {
+	Subject string
+	Email   string
+	Groups  []string
+	Roles   []string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcPendingLogin struct {
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// OIDCProvider implements the OpenID Connect authorization code flow with
+// PKCE against a single identity provider.
+type OIDCProvider struct {
+	cfg        OIDCConfig
+	httpClient *http.Client
+	discovery  oidcDiscovery
+
+	mu            sync.Mutex
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+	pending       map[string]oidcPendingLogin
+}
+
+func NewOIDCProvider(ctx context.Context, cfg OIDCConfig, httpClient *http.Client) (*OIDCProvider, error) {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	if cfg.MaxPendingLogins <= 0 {
+		cfg.MaxPendingLogins = defaultOIDCPendingLogins
+	}
+
+	p := &OIDCProvider{
+		cfg:        cfg,
+		httpClient: httpClient,
+		keys:       map[string]interface{}{},
+		pending:    map[string]oidcPendingLogin{},
+	}
+
+	issuer := strings.TrimSuffix(cfg.IssuerURL, "/")
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", &p.discovery); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+
+	if strings.TrimSuffix(p.discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc discovery issuer mismatch: got %s", p.discovery.Issuer)
+	}
+
+	if p.discovery.AuthorizationEndpoint == "" || p.discovery.TokenEndpoint == "" || p.discovery.JWKSURI == "" {
+		return nil, errors.New("oidc discovery document is incomplete")
+	}
+
+	return p, nil
+}
+
+// AuthCodeURL starts a login and returns the identity provider URL the user
+// must be redirected to. It returns ErrOIDCTooManyLogins while
+// MaxPendingLogins logins are in progress, so unfinished logins cannot grow
+// the state without bound.
+func (p *OIDCProvider) AuthCodeURL() (string, error) {
+	state, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	verifier, err := randomString(48)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	for key, login := range p.pending {
+		if now.After(login.expiresAt) {
+			delete(p.pending, key)
+		}
+	}
+	if len(p.pending) >= p.cfg.MaxPendingLogins {
+		p.mu.Unlock()
+		return "", ErrOIDCTooManyLogins
+	}
+	p.pending[state] = oidcPendingLogin{
+		verifier:  verifier,
+		nonce:     nonce,
+		expiresAt: now.Add(oidcLoginTTL),
+	}
+	p.mu.Unlock()
+
+	authURL, err := url.Parse(p.discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", pkceChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange redeems the authorization code returned to the callback and
+// validates the resulting ID token.
+func (p *OIDCProvider) Exchange(ctx context.Context, state, code string) (*OIDCIdentity, error) {
+	p.mu.Lock()
+	login, ok := p.pending[state]
+	delete(p.pending, state)
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(login.expiresAt) {
+		return nil, ErrOIDCInvalidState
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", login.verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oidc token response read failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("invalid oidc token response: %w", err)
+	}
+
+	if tokenResp.IDToken == "" {
+		return nil, errors.New("oidc token response has no id_token")
+	}
+
+	return p.verifyIDToken(ctx, tokenResp.IDToken, login.nonce)
+}
+
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, rawToken, nonce string) (*OIDCIdentity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(p.discovery.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, errors.New("invalid id token: nonce mismatch")
+	}
+
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		return nil, errors.New("invalid id token: email is not verified")
+	}
+
+	identity := &OIDCIdentity{
+		Groups: stringsClaim(claims[p.cfg.GroupsClaim]),
+	}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+
+	if identity.Email == "" {
+		return nil, errors.New("invalid id token: email claim is required")
+	}
+
+	identity.Roles = MapGroupsToRoles(identity.Groups, p.cfg.GroupRoles)
+	if len(identity.Roles) == 0 {
+		return nil, ErrOIDCNoRoles
+	}
+
+	return identity, nil
+}
+
+func (p *OIDCProvider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	stale := time.Since(p.keysFetchedAt) > oidcJWKSMinInterval
+	p.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	// Unknown key id: the provider may have rotated its keys.
+	if !stale {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *OIDCProvider) refreshKeys(ctx context.Context) error {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.discovery.JWKSURI, &set); err != nil {
+		return fmt.Errorf("oidc jwks fetch failed: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, target)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		point := append([]byte{4}, append(x, y...)...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// MapGroupsToRoles returns the distinct local roles granted by the given
+// identity provider groups.
+func MapGroupsToRoles(groups []string, mapping map[string]string) []string {
+	roles := make([]string, 0)
+	seen := map[string]bool{}
+
+	for _, group := range groups {
+		role, ok := mapping[group]
+		if !ok || seen[role] {
+			continue
+		}
+
+		seen[role] = true
+		roles = append(roles, role)
+	}
+
+	return roles
+}
+
+func stringsClaim(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCServerMock is a minimal OpenID Connect provider served from httptest.
+// Its authorize endpoint approves every request and redirects straight back to
+// the client with a code, so tests can drive the whole login flow.
+type OIDCServerMock struct {
+	Server   *httptest.Server
+	ClientID string
+	// Claims are added to every ID token issued, e.g. email and groups.
+	Claims jwt.MapClaims
+
+	key *rsa.PrivateKey
+	kid string
+
+	mu    sync.Mutex
+	codes map[string]oidcMockGrant
+}
+
+type oidcMockGrant struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+}
+
+func NewOIDCServerMock(clientID string, claims jwt.MapClaims) (*OIDCServerMock, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &OIDCServerMock{
+		ClientID: clientID,
+		Claims:   claims,
+		key:      key,
+		kid:      "mock-key",
+		codes:    map[string]oidcMockGrant{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.handleDiscovery)
+	mux.HandleFunc("/authorize", m.handleAuthorize)
+	mux.HandleFunc("/token", m.handleToken)
+	mux.HandleFunc("/jwks", m.handleJWKS)
+	m.Server = httptest.NewServer(mux)
+
+	return m, nil
+}
+
+func (m *OIDCServerMock) URL() string {
+	return m.Server.URL
+}
+
+func (m *OIDCServerMock) Close() {
+	m.Server.Close()
+}
+
+// SignIDToken signs arbitrary claims with the mock provider key.
+func (m *OIDCServerMock) SignIDToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	return token.SignedString(m.key)
+}
+
+func (m *OIDCServerMock) handleDiscovery(w http.ResponseWriter, _ *http.Request) {
+	writeMockJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 m.URL(),
+		"authorization_endpoint": m.URL() + "/authorize",
+		"token_endpoint":         m.URL() + "/token",
+		"jwks_uri":               m.URL() + "/jwks",
+	})
+}
+
+func (m *OIDCServerMock) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != m.ClientID || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code, err := randomString(16)
+	if err != nil {
+		http.Error(w, "server_error", http.StatusInternalServerError)
+		return
+	}
+
+	m.mu.Lock()
+	m.codes[code] = oidcMockGrant{
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		redirectURI: query.Get("redirect_uri"),
+	}
+	m.mu.Unlock()
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *OIDCServerMock) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	m.mu.Lock()
+	grant, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != grant.redirectURI {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	if pkceChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   m.URL(),
+		"aud":   m.ClientID,
+		"sub":   "mock-subject",
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": grant.nonce,
+	}
+	for key, value := range m.Claims {
+		claims[key] = value
+	}
+
+	idToken, err := m.SignIDToken(claims)
+	if err != nil {
+		writeMockJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (m *OIDCServerMock) handleJWKS(w http.ResponseWriter, _ *http.Request) {
+	pub := m.key.PublicKey
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": m.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestOIDCProvider_LoginFlow(t *testing.T) {
+	idp := newTestOIDCServer(t, jwt.MapClaims{
+		"email":  "recruiter@company.com",
+		"groups": []string{"hr-recruiters", "everyone"},
+	})
+	provider := newTestOIDCProvider(t, idp)
+
+	state, code := authorize(t, provider)
+
+	identity, err := provider.Exchange(context.Background(), state, code)
+	if err != nil {
+		t.Fatalf("expected successful exchange, got %v", err)
+	}
+	if identity.Email != "recruiter@company.com" {
+		t.Fatalf("expected email recruiter@company.com, got %s", identity.Email)
+	}
+	if len(identity.Roles) != 1 || identity.Roles[0] != RoleRecruiter {
+		t.Fatalf("expected roles [%s], got %v", RoleRecruiter, identity.Roles)
+	}
+
+	if _, err := provider.Exchange(context.Background(), state, code); !errors.Is(err, ErrOIDCInvalidState) {
+		t.Fatalf("expected state to be single use, got %v", err)
+	}
+}
+
+func TestOIDCProvider_CapsPendingLogins(t *testing.T) {
+	idp := newTestOIDCServer(t, jwt.MapClaims{"email": "recruiter@company.com", "groups": []string{"hr-recruiters"}})
+	provider := newTestOIDCProvider(t, idp)
+	provider.cfg.MaxPendingLogins = 1
+
+	state, code := authorize(t, provider)
+
+	if _, err := provider.AuthCodeURL(); !errors.Is(err, ErrOIDCTooManyLogins) {
+		t.Fatalf("expected ErrOIDCTooManyLogins, got %v", err)
+	}
+
+	if _, err := provider.Exchange(context.Background(), state, code); err != nil {
+		t.Fatalf("expected successful exchange, got %v", err)
+	}
+	if _, err := provider.AuthCodeURL(); err != nil {
+		t.Fatalf("expected a completed login to free its slot, got %v", err)
+	}
+
+	provider.mu.Lock()
+	for key, login := range provider.pending {
+		login.expiresAt = time.Now().Add(-time.Second)
+		provider.pending[key] = login
+	}
+	provider.mu.Unlock()
+
+	if _, err := provider.AuthCodeURL(); err != nil {
+		t.Fatalf("expected an expired login to free its slot, got %v", err)
+	}
+}
+
+func TestOIDCProvider_RejectsUnmappedGroups(t *testing.T) {
+	idp := newTestOIDCServer(t, jwt.MapClaims{
+		"email":  "someone@company.com",
+		"groups": []string{"everyone"},
+	})
+	provider := newTestOIDCProvider(t, idp)
+
+	state, code := authorize(t, provider)
+
+	if _, err := provider.Exchange(context.Background(), state, code); !errors.Is(err, ErrOIDCNoRoles) {
+		t.Fatalf("expected ErrOIDCNoRoles, got %v", err)
+	}
+}
+
+func TestOIDCProvider_RejectsPKCEMismatch(t *testing.T) {
+	idp := newTestOIDCServer(t, jwt.MapClaims{"email": "recruiter@company.com", "groups": []string{"hr-recruiters"}})
+	provider := newTestOIDCProvider(t, idp)
+
+	state, code := authorize(t, provider)
+
+	provider.mu.Lock()
+	login := provider.pending[state]
+	login.verifier = "tampered-verifier"
+	provider.pending[state] = login
+	provider.mu.Unlock()
+
+	if _, err := provider.Exchange(context.Background(), state, code); err == nil {
+		t.Fatalf("expected token endpoint to reject the code verifier")
+	}
+}
+
+func TestOIDCProvider_VerifyIDToken(t *testing.T) {
+	idp := newTestOIDCServer(t, nil)
+	provider := newTestOIDCProvider(t, idp)
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":    idp.URL(),
+			"aud":    idp.ClientID,
+			"sub":    "subject",
+			"email":  "admin@company.com",
+			"groups": []string{"hr-admins"},
+			"nonce":  "expected-nonce",
+			"exp":    time.Now().Add(time.Minute).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(jwt.MapClaims)
+		wantErr bool
+	}{
+		{name: "valid", mutate: func(jwt.MapClaims) {}},
+		{name: "wrong audience", mutate: func(c jwt.MapClaims) { c["aud"] = "other-client" }, wantErr: true},
+		{name: "wrong issuer", mutate: func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }, wantErr: true},
+		{name: "expired", mutate: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, wantErr: true},
+		{name: "nonce mismatch", mutate: func(c jwt.MapClaims) { c["nonce"] = "other" }, wantErr: true},
+		{name: "unverified email", mutate: func(c jwt.MapClaims) { c["email_verified"] = false }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.mutate(claims)
+
+			raw, err := idp.SignIDToken(claims)
+			if err != nil {
+				t.Fatalf("failed signing token: %v", err)
+			}
+
+			identity, err := provider.verifyIDToken(context.Background(), raw, "expected-nonce")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected verification error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected valid token, got %v", err)
+			}
+			if len(identity.Roles) != 1 || identity.Roles[0] != RoleAdmin {
+				t.Fatalf("expected roles [%s], got %v", RoleAdmin, identity.Roles)
+			}
+		})
+	}
+}
+
+func newTestOIDCServer(t *testing.T, claims jwt.MapClaims) *OIDCServerMock {
+	t.Helper()
+
+	idp, err := NewOIDCServerMock("opportunities-api", claims)
+	if err != nil {
+		t.Fatalf("failed starting oidc mock: %v", err)
+	}
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+func newTestOIDCProvider(t *testing.T, idp *OIDCServerMock) *OIDCProvider {
+	t.Helper()
+
+	provider, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		IssuerURL:   idp.URL(),
+		ClientID:    idp.ClientID,
+		RedirectURL: "http://localhost/callback",
+		GroupRoles: map[string]string{
+			"hr-recruiters": RoleRecruiter,
+			"hr-admins":     RoleAdmin,
+		},
+	}, idp.Server.Client())
+	if err != nil {
+		t.Fatalf("failed creating oidc provider: %v", err)
+	}
+
+	return provider
+}
+
+// authorize follows the provider redirect and returns the callback state and code.
+func authorize(t *testing.T, provider *OIDCProvider) (string, string) {
+	t.Helper()
+
+	authURL, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatalf("failed building auth url: %v", err)
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected redirect from authorize endpoint, got %d", resp.StatusCode)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid callback url: %v", err)
+	}
+
+	return callback.Query().Get("state"), callback.Query().Get("code")
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	// totpSkew accepts codes from one step before and after the current one
+	// to tolerate clock drift on the user device.
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random base32 secret (RFC 6238, 160 bits).
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPProvisioningURI builds the otpauth:// URI rendered as a QR code by
+// authenticator apps.
+func TOTPProvisioningURI(secret, issuer, account string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPCode returns the code for the given time.
+func TOTPCode(secret string, at time.Time) (string, error) {
+	return totpCodeAt(secret, at.Unix()/totpPeriod)
+}
+
+// ValidateTOTP checks the code against the steps around the given time and
+// returns the matched time step. Callers should reject steps that are not
+// greater than the last accepted one to prevent code reuse.
+func ValidateTOTP(secret, code string, at time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := at.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := totpCodeAt(secret, step)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+func totpCodeAt(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret: %w", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// GenerateRecoveryCodes returns single-use recovery codes and their bcrypt
+// hashes. Only the hashes should be stored.
+func GenerateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+
+		raw := totpEncoding.EncodeToString(buf)[:10]
+		code := raw[:5] + "-" + raw[5:]
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, string(hash))
+	}
+
+	return codes, hashes, nil
+}
+
+// IsRecoveryCode reports whether the code has the shape of a recovery code,
+// such as "ABCDE-FGHIJ", so that other input never reaches bcrypt.
+func IsRecoveryCode(code string) bool {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 11 || code[5] != '-' {
+		return false
+	}
+
+	for i := 0; i < len(code); i++ {
+		if i == 5 {
+			continue
+		}
+		if c := code[i]; !(c >= 'A' && c <= 'Z' || c >= '2' && c <= '7') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchRecoveryCode returns the index of the hash matching the code, or -1.
+// Codes that are not shaped like a recovery code are rejected up front.
+func MatchRecoveryCode(code string, hashes []string) int {
+	if !IsRecoveryCode(code) {
+		return -1
+	}
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	for i, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) == nil {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+
+	for _, v := range vectors {
+		code, err := TOTPCode(secret, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if code != v.code {
+			t.Fatalf("at %d expected %s, got %s", v.unix, v.code, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	previous, _ := TOTPCode(secret, now.Add(-30*time.Second))
+	if _, ok := ValidateTOTP(secret, previous, now); !ok {
+		t.Fatalf("expected code from the previous step to be accepted")
+	}
+
+	old, _ := TOTPCode(secret, now.Add(-2*time.Minute))
+	if _, ok := ValidateTOTP(secret, old, now); ok {
+		t.Fatalf("expected old code to be rejected")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("SECRET", "Opportunities API", "admin@admin.com")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Opportunities%20API:admin@admin.com?") {
+		t.Fatalf("unexpected uri label: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=SECRET") || !strings.Contains(uri, "issuer=Opportunities+API") {
+		t.Fatalf("unexpected uri params: %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d", recoveryCodeCount, len(codes))
+	}
+
+	if index := MatchRecoveryCode(strings.ToLower(codes[3]), hashes); index != 3 {
+		t.Fatalf("expected code to match hash 3, got %d", index)
+	}
+	if index := MatchRecoveryCode("AAAAA-BBBBB", hashes); index != -1 {
+		t.Fatalf("expected unknown code not to match, got %d", index)
+	}
+
+	for _, code := range []string{"123456", "AAAAA-BBBB1", "AAAAABBBBBB", ""} {
+		if IsRecoveryCode(code) {
+			t.Fatalf("expected %q not to look like a recovery code", code)
+		}
+	}
+}
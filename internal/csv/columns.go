@@ -0,0 +1,138 @@
+package csv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"opportunities/internal/schemas"
+)
+
+// ColumnMapping renames source columns to opening fields, for example
+// "Job Title" -> "role". Keys are matched case-insensitively.
+type ColumnMapping map[string]string
+
+// ParseOptions controls how the file is read and how the header is matched
+// to opening fields. The zero value detects the delimiter and encoding,
+// matches columns by name, in any order, and ignores unknown columns.
+type ParseOptions struct {
+	Mapping              ColumnMapping
+	RejectUnknownColumns bool
+	// Delimiter and Encoding override the detection when set. See
+	// ParseDelimiter and ParseEncoding.
+	Delimiter string
+	Encoding  string
+	// Locale selects how booleans and amounts are written. See ParseLocale;
+	// empty means DefaultLocale.
+	Locale string
+	// Rules, when set, rewrite and check every row.
+	Rules RowRules
+}
+
+// RowRules are team rules applied to each row. Transform rewrites the raw
+// values, keyed by field, before they are parsed; Validate checks the parsed
+// opening.
+type RowRules interface {
+	Transform(values map[string]string)
+	Validate(opening schemas.Openings) error
+}
+
+// columnIndex maps each opening field to its position in the file.
+type columnIndex map[string]int
+
+func (c columnIndex) value(row []string, field string) string {
+	index, ok := c[field]
+	if !ok {
+		return ""
+	}
+
+	return strings.TrimSpace(row[index])
+}
+
+func knownColumn(name string) bool {
+	if name == externalIDColumn {
+		return true
+	}
+
+	for _, column := range expectedHeader {
+		if column == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Field returns the opening field a source column (or JSON key) maps to. It
+// reports false for names that are not opening fields.
+func (o ParseOptions) Field(name string) (string, bool) {
+	name = normalizeColumn(name)
+	for source, target := range o.Mapping {
+		if normalizeColumn(source) == name {
+			name = normalizeColumn(target)
+			break
+		}
+	}
+
+	return name, knownColumn(name)
+}
+
+// ValidateMapping checks that every mapping target is an opening field.
+func ValidateMapping(mapping ColumnMapping) error {
+	for source, target := range mapping {
+		if normalizeColumn(source) == "" {
+			return fmt.Errorf("mapping source column cannot be empty")
+		}
+
+		if !knownColumn(normalizeColumn(target)) {
+			return fmt.Errorf("mapping target %q is not one of %v or %q", target, expectedHeader, externalIDColumn)
+		}
+	}
+
+	return nil
+}
+
+func resolveColumns(header []string, opts ParseOptions) (columnIndex, error) {
+	mapping := make(map[string]string, len(opts.Mapping))
+	for source, target := range opts.Mapping {
+		mapping[normalizeColumn(source)] = normalizeColumn(target)
+	}
+
+	columns := make(columnIndex, len(header))
+	for i, cell := range header {
+		name := normalizeColumn(cell)
+		if target, ok := mapping[name]; ok {
+			name = target
+		}
+
+		if !knownColumn(name) {
+			if opts.RejectUnknownColumns {
+				return nil, fmt.Errorf("invalid csv header. unknown column %q", strings.TrimSpace(cell))
+			}
+			continue
+		}
+
+		if _, duplicated := columns[name]; duplicated {
+			return nil, fmt.Errorf("invalid csv header. column %q appears more than once", name)
+		}
+
+		columns[name] = i
+	}
+
+	missing := make([]string, 0)
+	for _, column := range expectedHeader {
+		if _, ok := columns[column]; !ok {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("invalid csv header. missing columns %v", missing)
+	}
+
+	return columns, nil
+}
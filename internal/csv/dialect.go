@@ -0,0 +1,169 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Supported encodings. Files in other encodings must be converted before
+// upload.
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingWindows1252 = "windows-1252"
+	EncodingLatin1      = "iso-8859-1"
+)
+
+// delimiters are the candidates of the detection, in order of preference on
+// ties.
+var delimiters = []string{",", ";", "\t", "|"}
+
+// dialectSampleSize is how much of the file is read to detect the dialect.
+const dialectSampleSize = 64 << 10
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Dialect is how a file was read: the field delimiter, the encoding it was
+// converted from and whether it started with a UTF-8 BOM.
+type Dialect struct {
+	Delimiter string `json:"delimiter"`
+	Encoding  string `json:"encoding"`
+	BOM       bool   `json:"bom"`
+}
+
+// ParseDelimiter validates a delimiter chosen for an upload. "tab" is
+// accepted for a tab character and an empty value means detect.
+func ParseDelimiter(raw string) (string, error) {
+	if strings.EqualFold(strings.TrimSpace(raw), "tab") {
+		return "\t", nil
+	}
+
+	for _, delimiter := range delimiters {
+		if raw == delimiter {
+			return raw, nil
+		}
+	}
+	if raw == "" {
+		return "", nil
+	}
+
+	return "", fmt.Errorf(`delimiter must be one of ",", ";", "|" or "tab"`)
+}
+
+// ParseEncoding validates an encoding chosen for an upload and returns its
+// canonical name. An empty value means detect.
+func ParseEncoding(raw string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "":
+		return "", nil
+	case "utf-8", "utf8":
+		return EncodingUTF8, nil
+	case "windows-1252", "cp1252":
+		return EncodingWindows1252, nil
+	case "iso-8859-1", "latin-1", "latin1":
+		return EncodingLatin1, nil
+	default:
+		return "", fmt.Errorf("encoding must be %q, %q or %q", EncodingUTF8, EncodingWindows1252, EncodingLatin1)
+	}
+}
+
+// DetectDialect reports how r would be read with opts.
+func DetectDialect(r io.Reader, opts ParseOptions) (Dialect, error) {
+	_, dialect, err := openDialect(r, opts)
+	return dialect, err
+}
+
+// openDialect detects what opts leaves unset from the start of r. It returns
+// a reader of the UTF-8 text after the BOM.
+func openDialect(r io.Reader, opts ParseOptions) (io.Reader, Dialect, error) {
+	buffered := bufio.NewReaderSize(r, dialectSampleSize)
+
+	sample, err := buffered.Peek(dialectSampleSize)
+	if err != nil && err != io.EOF {
+		return nil, Dialect{}, fmt.Errorf("invalid csv format: %w", err)
+	}
+	full := len(sample) == dialectSampleSize
+
+	dialect := Dialect{Delimiter: opts.Delimiter, Encoding: opts.Encoding}
+
+	if bytes.HasPrefix(sample, utf8BOM) {
+		dialect.BOM = true
+		sample = sample[len(utf8BOM):]
+		buffered.Discard(len(utf8BOM))
+	}
+
+	if dialect.Encoding == "" {
+		dialect.Encoding = detectEncoding(sample, full, dialect.BOM)
+	}
+	if dialect.Delimiter == "" {
+		dialect.Delimiter = detectDelimiter(sample)
+	}
+
+	var text io.Reader = buffered
+	switch dialect.Encoding {
+	case EncodingWindows1252:
+		text = charmap.Windows1252.NewDecoder().Reader(buffered)
+	case EncodingLatin1:
+		text = charmap.ISO8859_1.NewDecoder().Reader(buffered)
+	}
+
+	return text, dialect, nil
+}
+
+// detectEncoding assumes UTF-8 when the sample is valid UTF-8 and
+// Windows-1252 otherwise, which is what spreadsheets save as "CSV" on
+// Windows. A full sample may end in the middle of a character, so only its
+// complete lines are checked; the rows after the sample are checked by
+// parseRow.
+func detectEncoding(sample []byte, full, bom bool) string {
+	if bom {
+		return EncodingUTF8
+	}
+
+	if full {
+		if end := bytes.LastIndexByte(sample, '\n'); end >= 0 {
+			sample = sample[:end]
+		}
+	}
+
+	if utf8.Valid(sample) {
+		return EncodingUTF8
+	}
+
+	return EncodingWindows1252
+}
+
+// detectDelimiter picks the candidate that appears most often in the header,
+// outside quotes. Headers rarely contain delimiters, unlike data rows.
+func detectDelimiter(sample []byte) string {
+	counts := make(map[byte]int, len(delimiters))
+	quoted := false
+
+	for _, b := range sample {
+		if b == '"' {
+			quoted = !quoted
+			continue
+		}
+		if quoted {
+			continue
+		}
+		if b == '\n' {
+			break
+		}
+		counts[b]++
+	}
+
+	best := delimiters[0]
+	for _, delimiter := range delimiters[1:] {
+		if counts[delimiter[0]] > counts[best[0]] {
+			best = delimiter
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,131 @@
+package csv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Excel in pt-BR saves CSV files with ";" and a UTF-8 BOM or in Windows-1252.
+func TestNewOpeningStream_DetectsDialect(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		opts     ParseOptions
+		expected Dialect
+		company  string
+	}{
+		{
+			name:     "comma separated UTF-8",
+			content:  []byte("role,company,location,remote,link,salary\nGo Dev,Açaí,BR,true,https://acme.com,1000\n"),
+			expected: Dialect{Delimiter: ",", Encoding: EncodingUTF8},
+			company:  "Açaí",
+		},
+		{
+			name:     "semicolon with BOM",
+			content:  []byte("\xEF\xBB\xBFrole;company;location;remote;link;salary\nGo Dev;Açaí, Ltda;BR;true;https://acme.com;1000\n"),
+			expected: Dialect{Delimiter: ";", Encoding: EncodingUTF8, BOM: true},
+			company:  "Açaí, Ltda",
+		},
+		{
+			name:     "semicolon in Windows-1252",
+			content:  []byte("role;company;location;remote;link;salary\nGo Dev;A\xe7a\xed;S\xe3o Paulo;true;https://acme.com;1000\n"),
+			expected: Dialect{Delimiter: ";", Encoding: EncodingWindows1252},
+			company:  "Açaí",
+		},
+		{
+			name:     "tab and pipe in quoted header",
+			content:  []byte("role\t\"company|name\"\tlocation\tremote\tlink\tsalary\n"),
+			opts:     ParseOptions{Mapping: ColumnMapping{"company|name": "company"}},
+			expected: Dialect{Delimiter: "\t", Encoding: EncodingUTF8},
+		},
+		{
+			name:     "overrides",
+			content:  []byte("role|company|location|remote|link|salary\nGo Dev|Acme;BR|BR|true|https://acme.com|1000\n"),
+			opts:     ParseOptions{Delimiter: "|", Encoding: EncodingLatin1},
+			expected: Dialect{Delimiter: "|", Encoding: EncodingLatin1},
+			company:  "Acme;BR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := NewOpeningStream(bytes.NewReader(tt.content), tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if stream.Dialect() != tt.expected {
+				t.Fatalf("expected dialect %+v, got %+v", tt.expected, stream.Dialect())
+			}
+			if tt.company == "" {
+				return
+			}
+
+			parsed, rowErrors, err := stream.ReadChunk(10, 1)
+			if err != nil || len(rowErrors) != 0 || len(parsed) != 1 {
+				t.Fatalf("expected 1 parsed row, got %+v %+v %v", parsed, rowErrors, err)
+			}
+			if parsed[0].Opening.Company != tt.company {
+				t.Fatalf("expected company %q, got %q", tt.company, parsed[0].Opening.Company)
+			}
+		})
+	}
+}
+
+// A UTF-8 sample does not prove the rest of the file is UTF-8.
+func TestNewOpeningStream_InvalidUTF8AfterSample(t *testing.T) {
+	row := "Go Dev,Acme,BR,true,https://acme.com,1000\n"
+	content := "role,company,location,remote,link,salary\n" +
+		strings.Repeat(row, dialectSampleSize/len(row)+1) +
+		"Go Dev,A\xe7a\xed,BR,true,https://acme.com,1000\n" + row
+
+	stream, err := NewOpeningStream(strings.NewReader(content), ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stream.Dialect().Encoding != EncodingUTF8 {
+		t.Fatalf("expected the sample to be detected as UTF-8, got %q", stream.Dialect().Encoding)
+	}
+
+	parsed, rowErrors, err := stream.ReadChunk(dialectSampleSize, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	badLine := dialectSampleSize/len(row) + 3
+	if len(rowErrors) != 1 || rowErrors[0].LineNumber != badLine || !strings.Contains(rowErrors[0].Message, "column 2 is not valid UTF-8") {
+		t.Fatalf("expected a UTF-8 error on line %d, got %+v", badLine, rowErrors)
+	}
+	if want := dialectSampleSize/len(row) + 2; len(parsed) != want {
+		t.Fatalf("expected %d parsed rows, got %d", want, len(parsed))
+	}
+}
+
+func TestParseDelimiterAndEncoding(t *testing.T) {
+	if delimiter, err := ParseDelimiter("TAB"); err != nil || delimiter != "\t" {
+		t.Fatalf("expected tab, got %q, %v", delimiter, err)
+	}
+	if _, err := ParseDelimiter(":"); err == nil {
+		t.Fatal("expected error for an unsupported delimiter")
+	}
+	if encoding, err := ParseEncoding("Latin-1"); err != nil || encoding != EncodingLatin1 {
+		t.Fatalf("expected %s, got %q, %v", EncodingLatin1, encoding, err)
+	}
+	if _, err := ParseEncoding("utf-16"); err == nil {
+		t.Fatal("expected error for an unsupported encoding")
+	}
+}
+
+func TestAnnotateErrors_KeepsDialect(t *testing.T) {
+	content := "\xEF\xBB\xBFrole;company;location;remote;link;salary\nGo Dev;A\xc3\xa7a\xc3\xad;BR;true;https://acme.com;0\n"
+
+	var out bytes.Buffer
+	if err := AnnotateErrors(strings.NewReader(content), &out, []RowError{{LineNumber: 2, Message: "salary must be greater than zero"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "\xEF\xBB\xBFrole;company;location;remote;link;salary;error\nGo Dev;Açaí;BR;true;https://acme.com;0;salary must be greater than zero\n"
+	if out.String() != expected {
+		t.Fatalf("unexpected report:\n%q", out.String())
+	}
+}
@@ -0,0 +1,67 @@
+package csv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const errorReportColumn = "error"
+
+// AnnotateErrors copies the CSV from r to w with an extra "error" column
+// filled with the messages of each failing line. Lines follow the same
+// numbering as OpeningStream (the header is line 1). Rows are streamed, so
+// the file is never held in memory.
+func AnnotateErrors(r io.Reader, w io.Writer, rowErrors []RowError) error {
+	return AnnotateErrorsWithOptions(r, w, rowErrors, ParseOptions{})
+}
+
+// AnnotateErrorsWithOptions reads r like the import did. The report keeps the
+// delimiter and BOM of the upload and is written in UTF-8.
+func AnnotateErrorsWithOptions(r io.Reader, w io.Writer, rowErrors []RowError, opts ParseOptions) error {
+	text, dialect, err := openDialect(r, opts)
+	if err != nil {
+		return err
+	}
+
+	reader := newReader(text, dialect)
+	reader.ReuseRecord = true
+
+	messages := make(map[int][]string, len(rowErrors))
+	for _, rowErr := range rowErrors {
+		messages[rowErr.LineNumber] = append(messages[rowErr.LineNumber], rowErr.Message)
+	}
+
+	if dialect.BOM {
+		if _, err := w.Write(utf8BOM); err != nil {
+			return err
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = reader.Comma
+
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid csv format: %w", err)
+		}
+
+		cell := strings.Join(messages[line], "; ")
+		if line == 1 {
+			cell = errorReportColumn
+		}
+
+		if err := writer.Write(append(row, cell)); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
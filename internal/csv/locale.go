@@ -0,0 +1,194 @@
+package csv
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	LocalePTBR = "pt-BR"
+	LocaleENUS = "en-US"
+	// DefaultLocale applies when an upload does not choose one.
+	DefaultLocale = LocalePTBR
+)
+
+var ErrUnsupportedLocale = errors.New("locale must be pt-BR or en-US")
+
+// Normalization is a value the locale rules rewrote, such as "sim" read as
+// true or "R$ 5.000,00" read as 5000.
+type Normalization struct {
+	Field string
+	Raw   string
+	Value string
+}
+
+// locale describes how booleans and amounts are written.
+type locale struct {
+	name      string
+	thousands string
+	decimal   string
+	booleans  map[string]bool
+	// multipliers are amount suffixes such as "5k", checked in order.
+	multipliers []multiplier
+	example     string
+}
+
+type multiplier struct {
+	suffix string
+	factor int64
+}
+
+var locales = map[string]locale{
+	LocalePTBR: {
+		name:      LocalePTBR,
+		thousands: ".",
+		decimal:   ",",
+		booleans: map[string]bool{
+			"sim": true, "s": true, "verdadeiro": true,
+			"não": false, "nao": false, "n": false, "falso": false,
+		},
+		multipliers: []multiplier{{"mil", 1000}, {"k", 1000}},
+		example:     "5000, R$ 5.000,00 or 5k",
+	},
+	LocaleENUS: {
+		name:      LocaleENUS,
+		thousands: ",",
+		decimal:   ".",
+		booleans: map[string]bool{
+			"yes": true, "y": true,
+			"no": false, "n": false,
+		},
+		multipliers: []multiplier{{"k", 1000}},
+		example:     "5000, $5,000.00 or 5k",
+	},
+}
+
+// currencySymbols are stripped from amounts, longest first.
+var currencySymbols = []string{"US$", "R$", "BRL", "USD", "$"}
+
+// ParseLocale validates the locale chosen for an upload. An empty value means
+// DefaultLocale.
+func ParseLocale(raw string) (string, error) {
+	switch strings.ToLower(strings.ReplaceAll(strings.TrimSpace(raw), "_", "-")) {
+	case "":
+		return DefaultLocale, nil
+	case "pt-br", "pt":
+		return LocalePTBR, nil
+	case "en-us", "en":
+		return LocaleENUS, nil
+	default:
+		return "", ErrUnsupportedLocale
+	}
+}
+
+func lookupLocale(name string) locale {
+	if loc, ok := locales[name]; ok {
+		return loc
+	}
+
+	return locales[DefaultLocale]
+}
+
+// parseBool accepts what strconv.ParseBool accepts and the words of the
+// locale, such as "sim" and "não".
+func (l locale) parseBool(raw string) (bool, bool) {
+	if value, err := strconv.ParseBool(raw); err == nil {
+		return value, true
+	}
+
+	value, ok := l.booleans[strings.ToLower(raw)]
+	return value, ok
+}
+
+// parseAmount reads a whole amount written with the separators of the
+// locale, an optional currency symbol and an optional multiplier suffix:
+// "R$ 5.000,00", "120.000" and "5k" are all valid in pt-BR.
+func (l locale) parseAmount(raw string) (int64, error) {
+	invalid := fmt.Errorf("salary must be an amount such as %s", l.example)
+
+	text := raw
+	for _, symbol := range currencySymbols {
+		if trimmed, ok := cutFold(text, symbol); ok {
+			text = trimmed
+			break
+		}
+	}
+	text = strings.Join(strings.FieldsFunc(text, isAmountSpace), "")
+
+	factor := int64(1)
+	for _, m := range l.multipliers {
+		if len(text) > len(m.suffix) && strings.EqualFold(text[len(text)-len(m.suffix):], m.suffix) {
+			text = text[:len(text)-len(m.suffix)]
+			factor = m.factor
+			break
+		}
+	}
+
+	whole, fraction, _ := strings.Cut(text, l.decimal)
+	// Cents are allowed on plain amounts; a multiplier allows as many
+	// decimals as it has zeros, so "5,125k" is 5125.
+	maxDecimals := 2
+	if factor > 1 {
+		maxDecimals = len(strconv.FormatInt(factor, 10)) - 1
+	}
+	if whole == "" || len(fraction) > maxDecimals || !isDigits(fraction) {
+		return 0, invalid
+	}
+
+	groups := strings.Split(whole, l.thousands)
+	for i, group := range groups {
+		if !isDigits(group) || group == "" ||
+			(len(groups) > 1 && ((i == 0 && len(group) > 3) || (i > 0 && len(group) != 3))) {
+			return 0, invalid
+		}
+	}
+
+	value, err := strconv.ParseInt(strings.Join(groups, ""), 10, 64)
+	if err != nil || value > (1<<63-1)/factor {
+		return 0, invalid
+	}
+	value *= factor
+
+	if fraction != "" {
+		cents, _ := strconv.ParseInt(fraction, 10, 64)
+		scale := int64(1)
+		for range fraction {
+			scale *= 10
+		}
+		if cents*factor%scale != 0 {
+			return 0, fmt.Errorf("salary must be a whole amount")
+		}
+		value += cents * factor / scale
+	}
+
+	return value, nil
+}
+
+// cutFold removes symbol from the start or the end of text, ignoring case.
+func cutFold(text, symbol string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if len(text) >= len(symbol) && strings.EqualFold(text[:len(symbol)], symbol) {
+		return text[len(symbol):], true
+	}
+	if len(text) >= len(symbol) && strings.EqualFold(text[len(text)-len(symbol):], symbol) {
+		return text[:len(text)-len(symbol)], true
+	}
+
+	return text, false
+}
+
+func isAmountSpace(r rune) bool {
+	return r == ' ' || r == '\u00a0' || r == '\u202f' || r == '\t'
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,90 @@
+package csv
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseLocale(t *testing.T) {
+	for raw, expected := range map[string]string{"": LocalePTBR, "pt_BR": LocalePTBR, "pt": LocalePTBR, "EN-us": LocaleENUS, "en": LocaleENUS} {
+		if locale, err := ParseLocale(raw); err != nil || locale != expected {
+			t.Fatalf("ParseLocale(%q) = %q, %v, expected %q", raw, locale, err, expected)
+		}
+	}
+
+	if _, err := ParseLocale("fr-FR"); !errors.Is(err, ErrUnsupportedLocale) {
+		t.Fatalf("expected ErrUnsupportedLocale, got %v", err)
+	}
+}
+
+func TestLocale_ParseAmount(t *testing.T) {
+	tests := []struct {
+		locale string
+		raw    string
+		value  int64
+		valid  bool
+	}{
+		{LocalePTBR, "R$ 5.000,00", 5000, true},
+		{LocalePTBR, "120.000", 120000, true},
+		{LocalePTBR, "5k", 5000, true},
+		{LocalePTBR, "7,5 mil", 7500, true},
+		{LocalePTBR, "R$ 1.234.567", 1234567, true},
+		{LocalePTBR, "5.000,50", 0, false},
+		{LocalePTBR, "12.34", 0, false},
+		{LocalePTBR, "5,000.00", 0, false},
+		{LocaleENUS, "$5,000.00", 5000, true},
+		{LocaleENUS, "120,000 USD", 120000, true},
+		{LocaleENUS, "7.5K", 7500, true},
+		{LocaleENUS, "5.000,00", 0, false},
+		{LocaleENUS, "abc", 0, false},
+	}
+
+	for _, tt := range tests {
+		value, err := lookupLocale(tt.locale).parseAmount(tt.raw)
+		if tt.valid && (err != nil || value != tt.value) {
+			t.Fatalf("%s %q: expected %d, got %d, %v", tt.locale, tt.raw, tt.value, value, err)
+		}
+		if !tt.valid && err == nil {
+			t.Fatalf("%s %q: expected an error, got %d", tt.locale, tt.raw, value)
+		}
+	}
+}
+
+func TestParseAndValidateWithOptions_Locale(t *testing.T) {
+	content := []byte("role,company,location,remote,link,salary\n" +
+		"Go Dev,Acme,BR,sim,https://acme.com,\"R$ 5.000,00\"\n" +
+		"SRE,Acme,BR,true,https://acme.com,1000\n" +
+		"QA,Acme,BR,yes,https://acme.com,5k\n")
+
+	parsed, rowErrors, err := ParseAndValidateWithOptions(content, ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed) != 2 || len(rowErrors) != 1 || rowErrors[0].LineNumber != 4 || rowErrors[0].Message != "remote must be a boolean" {
+		t.Fatalf("expected yes to be rejected in pt-BR, got %v, %v", parsed, rowErrors)
+	}
+
+	first := parsed[0]
+	if !first.Opening.Remote || first.Opening.Salary != 5000 {
+		t.Fatalf("expected remote true and salary 5000, got %+v", first.Opening)
+	}
+	expected := []Normalization{{Field: "remote", Raw: "sim", Value: "true"}, {Field: "salary", Raw: "R$ 5.000,00", Value: "5000"}}
+	if !reflect.DeepEqual(first.Normalized, expected) {
+		t.Fatalf("expected %v, got %v", expected, first.Normalized)
+	}
+	if parsed[1].Normalized != nil {
+		t.Fatalf("expected plain values not to be reported, got %v", parsed[1].Normalized)
+	}
+
+	parsed, rowErrors, err = ParseAndValidateWithOptions(content, ParseOptions{Locale: LocaleENUS})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed) != 2 || len(rowErrors) != 1 || rowErrors[0].LineNumber != 2 {
+		t.Fatalf("expected the pt-BR row to be rejected in en-US, got %v, %v", parsed, rowErrors)
+	}
+	if parsed[1].Opening.Salary != 5000 || !parsed[1].Opening.Remote {
+		t.Fatalf("expected yes and 5k to be read in en-US, got %+v", parsed[1].Opening)
+	}
+}
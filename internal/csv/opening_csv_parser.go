@@ -1,11 +1,13 @@
 package csv
 
 import (
-	"encoding/csv"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"runtime"
 	"strconv"
-	"strings"
-	"sync"
+	"unicode/utf8"
 
 	"opportunities/internal/schemas"
 )
@@ -14,11 +16,27 @@ const (
 	openingCSVChunkSize = 100
 )
 
+// expectedHeader lists the required columns. They are matched by name and
+// may appear in any order.
 var expectedHeader = []string{"role", "company", "location", "remote", "link", "salary"}
 
+// externalIDColumn is optional. It identifies the opening in the source
+// system and is the preferred key for upsert imports.
+const externalIDColumn = "external_id"
+
+// Header returns the columns written by exports: the required ones followed
+// by external_id, so exported files can be imported again.
+func Header() []string {
+	header := make([]string, 0, len(expectedHeader)+1)
+	header = append(header, expectedHeader...)
+	return append(header, externalIDColumn)
+}
+
 type ParsedOpening struct {
 	LineNumber int
 	Opening    schemas.Openings
+	// Normalized lists the values read through the locale rules.
+	Normalized []Normalization
 }
 
 type RowError struct {
@@ -27,125 +45,102 @@ type RowError struct {
 }
 
 func ValidateHeader(content []byte) error {
-	reader := csv.NewReader(strings.NewReader(string(content)))
-	header, err := reader.Read()
+	return ValidateHeaderWithOptions(content, ParseOptions{})
+}
+
+func ValidateHeaderWithOptions(content []byte, opts ParseOptions) error {
+	return ValidateHeaderReader(bytes.NewReader(content), opts)
+}
+
+// ValidateHeaderReader checks only the first record, so it can run on an
+// upload of any size.
+func ValidateHeaderReader(r io.Reader, opts ParseOptions) error {
+	text, dialect, err := openDialect(r, opts)
 	if err != nil {
-		return fmt.Errorf("invalid csv header: %w", err)
+		return err
 	}
 
-	if len(header) != len(expectedHeader) {
-		return fmt.Errorf("invalid csv header. expected %v", expectedHeader)
-	}
+	reader := newReader(text, dialect)
 
-	for i := range expectedHeader {
-		if strings.TrimSpace(strings.ToLower(header[i])) != expectedHeader[i] {
-			return fmt.Errorf("invalid csv header. expected %v", expectedHeader)
-		}
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("invalid csv header: %w", err)
 	}
 
-	return nil
+	_, err = resolveColumns(header, opts)
+	return err
 }
 
 func ParseAndValidate(content []byte) ([]ParsedOpening, []RowError, error) {
-	reader := csv.NewReader(strings.NewReader(string(content)))
-	rows, err := reader.ReadAll()
-	if err != nil {
-		return nil, nil, fmt.Errorf("invalid csv format: %w", err)
-	}
-
-	if len(rows) == 0 {
-		return nil, nil, fmt.Errorf("csv file is empty")
-	}
+	return ParseAndValidateWithOptions(content, ParseOptions{})
+}
 
-	if err := validateHeaderRow(rows[0]); err != nil {
+// ParseAndValidateWithOptions parses a whole in-memory file. Large files
+// should be read with NewOpeningStream instead.
+func ParseAndValidateWithOptions(content []byte, opts ParseOptions) ([]ParsedOpening, []RowError, error) {
+	stream, err := NewOpeningStream(bytes.NewReader(content), opts)
+	if err != nil {
 		return nil, nil, err
 	}
 
-	rawRows := rows[1:]
-	if len(rawRows) == 0 {
-		return []ParsedOpening{}, nil, nil
-	}
-
-	parsed := make([]ParsedOpening, 0, len(rawRows))
+	parsed := make([]ParsedOpening, 0)
 	rowErrors := make([]RowError, 0)
 
-	for start := 0; start < len(rawRows); start += openingCSVChunkSize {
-		end := start + openingCSVChunkSize
-		if end > len(rawRows) {
-			end = len(rawRows)
+	for {
+		chunk, chunkErrors, err := stream.ReadChunk(openingCSVChunkSize, runtime.NumCPU())
+		if errors.Is(err, io.EOF) {
+			return parsed, rowErrors, nil
 		}
-
-		chunkRows := rawRows[start:end]
-		chunkResults := make([]chunkParseResult, len(chunkRows))
-		wg := sync.WaitGroup{}
-
-		for i, row := range chunkRows {
-			index := i
-			lineNumber := start + i + 2
-			data := row
-			wg.Add(1)
-
-			go func() {
-				defer wg.Done()
-				chunkResults[index] = parseRow(lineNumber, data)
-			}()
+		if err != nil {
+			return nil, nil, err
 		}
 
-		wg.Wait()
-
-		for _, result := range chunkResults {
-			if result.Err != nil {
-				rowErrors = append(rowErrors, RowError{
-					LineNumber: result.LineNumber,
-					Message:    result.Err.Error(),
-				})
-				continue
-			}
-
-			parsed = append(parsed, ParsedOpening{
-				LineNumber: result.LineNumber,
-				Opening:    result.Opening,
-			})
-		}
+		parsed = append(parsed, chunk...)
+		rowErrors = append(rowErrors, chunkErrors...)
 	}
-
-	return parsed, rowErrors, nil
 }
 
 type chunkParseResult struct {
 	LineNumber int
 	Opening    schemas.Openings
+	Normalized []Normalization
 	Err        error
 }
 
-func validateHeaderRow(header []string) error {
-	if len(header) != len(expectedHeader) {
-		return fmt.Errorf("invalid csv header. expected %v", expectedHeader)
+func parseRow(lineNumber, width int, columns columnIndex, loc locale, rules RowRules, row []string) chunkParseResult {
+	if len(row) != width {
+		return chunkParseResult{
+			LineNumber: lineNumber,
+			Err:        fmt.Errorf("invalid column count, expected %d, got %d", width, len(row)),
+		}
 	}
 
-	for i := range expectedHeader {
-		if strings.TrimSpace(strings.ToLower(header[i])) != expectedHeader[i] {
-			return fmt.Errorf("invalid csv header. expected %v", expectedHeader)
+	// The encoding is detected from the start of the file only, so a UTF-8
+	// file may still hold invalid bytes further down.
+	for i, field := range row {
+		if !utf8.ValidString(field) {
+			return chunkParseResult{
+				LineNumber: lineNumber,
+				Err:        fmt.Errorf("column %d is not valid UTF-8; set encoding to the one the file was saved in", i+1),
+			}
 		}
 	}
 
-	return nil
-}
-
-func parseRow(lineNumber int, row []string) chunkParseResult {
-	if len(row) != len(expectedHeader) {
-		return chunkParseResult{
-			LineNumber: lineNumber,
-			Err:        fmt.Errorf("invalid column count, expected %d, got %d", len(expectedHeader), len(row)),
-		}
+	values := make(map[string]string, len(expectedHeader)+1)
+	for _, field := range Header() {
+		values[field] = columns.value(row, field)
+	}
+	if rules != nil {
+		rules.Transform(values)
 	}
 
-	role := strings.TrimSpace(row[0])
-	company := strings.TrimSpace(row[1])
-	location := strings.TrimSpace(row[2])
-	remoteRaw := strings.TrimSpace(row[3])
-	link := strings.TrimSpace(row[4])
-	salaryRaw := strings.TrimSpace(row[5])
+	role := values["role"]
+	company := values["company"]
+	location := values["location"]
+	remoteRaw := values["remote"]
+	link := values["link"]
+	salaryRaw := values["salary"]
+	externalID := values[externalIDColumn]
 
 	if role == "" {
 		return chunkParseResult{LineNumber: lineNumber, Err: fmt.Errorf("role is required")}
@@ -163,29 +158,48 @@ func parseRow(lineNumber int, row []string) chunkParseResult {
 		return chunkParseResult{LineNumber: lineNumber, Err: fmt.Errorf("link is required")}
 	}
 
+	var normalized []Normalization
+
 	remote, err := strconv.ParseBool(remoteRaw)
 	if err != nil {
-		return chunkParseResult{LineNumber: lineNumber, Err: fmt.Errorf("remote must be a boolean")}
+		var ok bool
+		if remote, ok = loc.parseBool(remoteRaw); !ok {
+			return chunkParseResult{LineNumber: lineNumber, Err: fmt.Errorf("remote must be a boolean")}
+		}
+		normalized = append(normalized, Normalization{Field: "remote", Raw: remoteRaw, Value: strconv.FormatBool(remote)})
 	}
 
 	salary, err := strconv.ParseInt(salaryRaw, 10, 64)
 	if err != nil {
-		return chunkParseResult{LineNumber: lineNumber, Err: fmt.Errorf("salary must be an integer")}
+		if salary, err = loc.parseAmount(salaryRaw); err != nil {
+			return chunkParseResult{LineNumber: lineNumber, Err: err}
+		}
+		normalized = append(normalized, Normalization{Field: "salary", Raw: salaryRaw, Value: strconv.FormatInt(salary, 10)})
 	}
 
 	if salary <= 0 {
 		return chunkParseResult{LineNumber: lineNumber, Err: fmt.Errorf("salary must be greater than zero")}
 	}
 
+	opening := schemas.Openings{
+		Role:       role,
+		Company:    company,
+		Location:   location,
+		Remote:     remote,
+		Link:       link,
+		Salary:     salary,
+		ExternalID: externalID,
+	}
+
+	if rules != nil {
+		if err := rules.Validate(opening); err != nil {
+			return chunkParseResult{LineNumber: lineNumber, Err: err}
+		}
+	}
+
 	return chunkParseResult{
 		LineNumber: lineNumber,
-		Opening: schemas.Openings{
-			Role:     role,
-			Company:  company,
-			Location: location,
-			Remote:   remote,
-			Link:     link,
-			Salary:   salary,
-		},
+		Opening:    opening,
+		Normalized: normalized,
 	}
 }
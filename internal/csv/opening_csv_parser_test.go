@@ -1,6 +1,9 @@
 package csv
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 func TestValidateHeader(t *testing.T) {
 	t.Run("valid header", func(t *testing.T) {
@@ -53,3 +56,91 @@ func TestParseAndValidate(t *testing.T) {
 		}
 	})
 }
+
+func TestParseAndValidate_ExternalID(t *testing.T) {
+	content := []byte("role,company,location,remote,link,salary,external_id\nGo Dev,Acme,BR,true,https://acme.com,1000, ATS-1 \n")
+	parsed, rowErrors, err := ParseAndValidate(content)
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("expected no errors, got %v %v", err, rowErrors)
+	}
+	if parsed[0].Opening.ExternalID != "ATS-1" {
+		t.Fatalf("expected external id ATS-1, got %q", parsed[0].Opening.ExternalID)
+	}
+}
+
+func TestParseAndValidateWithOptions(t *testing.T) {
+	t.Run("columns in any order with unknown columns ignored", func(t *testing.T) {
+		content := []byte("notes,salary,link,Remote,location,company,role\nhot,1000,https://acme.com,true,BR,Acme,Go Dev\n")
+		parsed, rowErrors, err := ParseAndValidate(content)
+		if err != nil || len(rowErrors) != 0 {
+			t.Fatalf("expected no errors, got %v %v", err, rowErrors)
+		}
+		if parsed[0].Opening.Role != "Go Dev" || parsed[0].Opening.Salary != 1000 {
+			t.Fatalf("unexpected opening: %+v", parsed[0].Opening)
+		}
+	})
+
+	t.Run("unknown columns rejected on request", func(t *testing.T) {
+		content := []byte("role,company,location,remote,link,salary,notes\n")
+		if err := ValidateHeaderWithOptions(content, ParseOptions{RejectUnknownColumns: true}); err == nil {
+			t.Fatalf("expected error for unknown column")
+		}
+	})
+
+	t.Run("mapped columns", func(t *testing.T) {
+		content := []byte("Job Title,Employer,City,Remote,URL,Pay\nGo Dev,Acme,BR,false,https://acme.com,1000\n")
+		opts := ParseOptions{Mapping: ColumnMapping{
+			"job title": "role",
+			"Employer":  "company",
+			"City":      "location",
+			"URL":       "link",
+			"Pay":       "salary",
+		}}
+		parsed, rowErrors, err := ParseAndValidateWithOptions(content, opts)
+		if err != nil || len(rowErrors) != 0 {
+			t.Fatalf("expected no errors, got %v %v", err, rowErrors)
+		}
+		if parsed[0].Opening.Role != "Go Dev" || parsed[0].Opening.Company != "Acme" {
+			t.Fatalf("unexpected opening: %+v", parsed[0].Opening)
+		}
+	})
+
+	t.Run("duplicated column", func(t *testing.T) {
+		content := []byte("role,title,company,location,remote,link,salary\n")
+		opts := ParseOptions{Mapping: ColumnMapping{"title": "role"}}
+		if err := ValidateHeaderWithOptions(content, opts); err == nil {
+			t.Fatalf("expected error for duplicated column")
+		}
+	})
+
+	t.Run("invalid mapping target", func(t *testing.T) {
+		if err := ValidateMapping(ColumnMapping{"Job Title": "title"}); err == nil {
+			t.Fatalf("expected error for unknown mapping target")
+		}
+	})
+}
+
+func TestAnnotateErrors(t *testing.T) {
+	content := []byte("role,company,location,remote,link,salary\n" +
+		"Go Dev,Acme,BR,true,https://acme.com,0\n" +
+		"Go Dev,Acme,BR,true,https://acme.com,1000\n" +
+		"Go Dev,Acme,BR,maybe,https://acme.com,1000\n")
+
+	_, rowErrors, err := ParseAndValidate(content)
+	if err != nil {
+		t.Fatalf("expected no parse error, got %v", err)
+	}
+
+	var report bytes.Buffer
+	if err := AnnotateErrors(bytes.NewReader(content), &report, rowErrors); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := "role,company,location,remote,link,salary,error\n" +
+		"Go Dev,Acme,BR,true,https://acme.com,0,salary must be greater than zero\n" +
+		"Go Dev,Acme,BR,true,https://acme.com,1000,\n" +
+		"Go Dev,Acme,BR,maybe,https://acme.com,1000,remote must be a boolean\n"
+	if report.String() != expected {
+		t.Fatalf("unexpected report:\n%s", report.String())
+	}
+}
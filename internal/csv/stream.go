@@ -0,0 +1,127 @@
+package csv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// OpeningStream reads openings from a CSV one chunk at a time, so memory use
+// depends on the chunk size and not on the size of the file.
+type OpeningStream struct {
+	reader  *csv.Reader
+	dialect Dialect
+	columns columnIndex
+	width   int
+	line    int
+	locale  locale
+	rules   RowRules
+}
+
+// NewOpeningStream detects the dialect and reads and resolves the header.
+// Rows are read by ReadChunk.
+func NewOpeningStream(r io.Reader, opts ParseOptions) (*OpeningStream, error) {
+	text, dialect, err := openDialect(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := newReader(text, dialect)
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("csv file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv format: %w", err)
+	}
+
+	columns, err := resolveColumns(header, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpeningStream{
+		reader:  reader,
+		dialect: dialect,
+		columns: columns,
+		width:   len(header),
+		line:    1,
+		locale:  lookupLocale(opts.Locale),
+		rules:   opts.Rules,
+	}, nil
+}
+
+// Dialect returns how the file is read.
+func (s *OpeningStream) Dialect() Dialect {
+	return s.dialect
+}
+
+func newReader(r io.Reader, dialect Dialect) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = rune(dialect.Delimiter[0])
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+// ReadChunk reads up to size rows and validates them with a fixed number of
+// workers. Results keep the file order. It returns io.EOF once every row was
+// read.
+func (s *OpeningStream) ReadChunk(size, workers int) ([]ParsedOpening, []RowError, error) {
+	rows := make([][]string, 0, size)
+	for len(rows) < size {
+		record, err := s.reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid csv format: %w", err)
+		}
+
+		rows = append(rows, record)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil, io.EOF
+	}
+
+	firstLine := s.line + 1
+	s.line += len(rows)
+
+	results := make([]chunkParseResult, len(rows))
+	workers = max(1, min(workers, len(rows)))
+
+	wg := sync.WaitGroup{}
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			for i := offset; i < len(rows); i += workers {
+				results[i] = parseRow(firstLine+i, s.width, s.columns, s.locale, s.rules, rows[i])
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	parsed := make([]ParsedOpening, 0, len(rows))
+	rowErrors := make([]RowError, 0)
+	for _, result := range results {
+		if result.Err != nil {
+			rowErrors = append(rowErrors, RowError{
+				LineNumber: result.LineNumber,
+				Message:    result.Err.Error(),
+			})
+			continue
+		}
+
+		parsed = append(parsed, ParsedOpening{
+			LineNumber: result.LineNumber,
+			Opening:    result.Opening,
+			Normalized: result.Normalized,
+		})
+	}
+
+	return parsed, rowErrors, nil
+}
@@ -0,0 +1,130 @@
+package csv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestOpeningStream(t *testing.T) {
+	content := "role,company,location,remote,link,salary\n" +
+		"Go Dev,Acme,BR,true,https://acme.com/1,1000\n" +
+		"Go Dev,Acme,BR,true,https://acme.com/2,0\n" +
+		"Go Dev,Acme,BR\n" +
+		"Go Dev,Acme,BR,true,https://acme.com/4,1000\n"
+
+	stream, err := NewOpeningStream(strings.NewReader(content), ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, rowErrors, err := stream.ReadChunk(3, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed) != 1 || parsed[0].LineNumber != 2 {
+		t.Fatalf("expected line 2 parsed, got %+v", parsed)
+	}
+	if len(rowErrors) != 2 || rowErrors[0].LineNumber != 3 || rowErrors[1].LineNumber != 4 {
+		t.Fatalf("expected errors on lines 3 and 4, got %+v", rowErrors)
+	}
+
+	parsed, _, err = stream.ReadChunk(3, 2)
+	if err != nil || len(parsed) != 1 || parsed[0].LineNumber != 5 {
+		t.Fatalf("expected line 5 in the second chunk, got %+v %v", parsed, err)
+	}
+
+	if _, _, err := stream.ReadChunk(3, 2); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+// BenchmarkOpeningStream reads generated files of growing size. The
+// peak-heap-KB metric stays flat because only one chunk is in memory at a
+// time; compare with BenchmarkParseAndValidate, which grows with the file.
+func BenchmarkOpeningStream(b *testing.B) {
+	for _, rows := range []int{10_000, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("rows=%d", rows), func(b *testing.B) {
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				stream, err := NewOpeningStream(newGeneratedCSV(rows), ParseOptions{})
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				for chunk := 0; ; chunk++ {
+					if _, _, err := stream.ReadChunk(500, runtime.NumCPU()); err != nil {
+						if errors.Is(err, io.EOF) {
+							break
+						}
+						b.Fatal(err)
+					}
+
+					if chunk%50 == 0 {
+						peak = max(peak, heapInUse())
+					}
+				}
+			}
+
+			b.ReportMetric(float64(peak)/1024, "peak-heap-KB")
+		})
+	}
+}
+
+func BenchmarkParseAndValidate(b *testing.B) {
+	for _, rows := range []int{10_000, 100_000} {
+		b.Run(fmt.Sprintf("rows=%d", rows), func(b *testing.B) {
+			content, _ := io.ReadAll(newGeneratedCSV(rows))
+
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				parsed, _, err := ParseAndValidate(content)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				peak = max(peak, heapInUse())
+				runtime.KeepAlive(parsed)
+			}
+
+			b.ReportMetric(float64(peak)/1024, "peak-heap-KB")
+		})
+	}
+}
+
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+// generatedCSV produces rows on demand so the benchmark input itself does not
+// occupy memory.
+type generatedCSV struct {
+	rows    int
+	next    int
+	pending bytes.Buffer
+}
+
+func newGeneratedCSV(rows int) *generatedCSV {
+	g := &generatedCSV{rows: rows}
+	g.pending.WriteString("role,company,location,remote,link,salary\n")
+	return g
+}
+
+func (g *generatedCSV) Read(p []byte) (int, error) {
+	for g.pending.Len() < len(p) && g.next < g.rows {
+		g.next++
+		fmt.Fprintf(&g.pending, "Go Developer %d,Acme,Remote,true,https://acme.com/jobs/%d,%d\n", g.next, g.next, 1000+g.next)
+	}
+
+	if g.pending.Len() == 0 {
+		return 0, io.EOF
+	}
+
+	return g.pending.Read(p)
+}
@@ -0,0 +1,43 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	csvutil "opportunities/internal/csv"
+	"opportunities/internal/schemas"
+)
+
+// csvWriter writes the importer's header, so an export can be uploaded back
+// to POST /opening/csv.
+type csvWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvutil.Header()); err != nil {
+		return nil, err
+	}
+
+	return &csvWriter{writer: writer, record: make([]string, len(csvutil.Header()))}, nil
+}
+
+func (c *csvWriter) Write(opening schemas.Openings) error {
+	for i, value := range fields(opening) {
+		if text, ok := value.(string); ok {
+			c.record[i] = escapeFormula(text)
+			continue
+		}
+		c.record[i] = fmt.Sprint(value)
+	}
+
+	return c.writer.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
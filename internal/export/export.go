@@ -0,0 +1,93 @@
+// Package export writes openings in the formats offered for download. Every
+// writer streams rows as they come, so memory does not grow with the export.
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"opportunities/internal/schemas"
+)
+
+// Supported formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+var ErrUnsupportedFormat = fmt.Errorf("format must be %q, %q or %q", FormatCSV, FormatNDJSON, FormatXLSX)
+
+// Writer writes openings one at a time. Close flushes what is buffered and
+// finishes the file; it does not close the underlying writer.
+type Writer interface {
+	Write(opening schemas.Openings) error
+	Close() error
+}
+
+// ParseFormat validates a requested format. An empty value means CSV.
+func ParseFormat(raw string) (string, error) {
+	switch format := strings.ToLower(strings.TrimSpace(raw)); format {
+	case "":
+		return FormatCSV, nil
+	case FormatCSV, FormatNDJSON, FormatXLSX:
+		return format, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// NewWriter returns the writer of a format.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// ContentType returns the MIME type of a format.
+func ContentType(format string) string {
+	switch format {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// formulaPrefixes are the leading characters that make a spreadsheet read a
+// cell as a formula.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormula prefixes text that a spreadsheet would run as a formula with
+// an apostrophe, so an opening cannot smuggle a formula into the CSV or XLSX
+// export.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+// fields returns the exported values in the order of csvutil.Header.
+func fields(opening schemas.Openings) []any {
+	return []any{
+		opening.Role,
+		opening.Company,
+		opening.Location,
+		opening.Remote,
+		opening.Link,
+		opening.Salary,
+		opening.ExternalID,
+	}
+}
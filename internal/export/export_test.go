@@ -0,0 +1,157 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	csvutil "opportunities/internal/csv"
+	"opportunities/internal/importer"
+	"opportunities/internal/schemas"
+)
+
+var testOpenings = []schemas.Openings{
+	{Role: "Go Dev", Company: "Acme, Inc.", Location: "BR", Remote: true, Link: "https://acme.com/1", Salary: 2000, ExternalID: "42"},
+	{Role: "SRE \"lead\"", Company: "Acme", Location: "PT", Link: "https://acme.com/2", Salary: 3000},
+}
+
+func writeAll(t *testing.T, format string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf)
+	if err != nil {
+		t.Fatalf("unexpected writer error: %v", err)
+	}
+	for _, opening := range testOpenings {
+		if err := writer.Write(opening); err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// The CSV and NDJSON exports must be accepted back by the importer.
+func TestWriters_RoundTripThroughImporter(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			reader, err := importer.Parsers()[format].Open(bytes.NewReader(writeAll(t, format)), csvutil.ParseOptions{})
+			if err != nil {
+				t.Fatalf("unexpected open error: %v", err)
+			}
+
+			parsed, rowErrors, err := reader.ReadChunk(10, 1)
+			if err != nil {
+				t.Fatalf("unexpected read error: %v", err)
+			}
+			if len(rowErrors) != 0 {
+				t.Fatalf("unexpected row errors: %+v", rowErrors)
+			}
+			if len(parsed) != len(testOpenings) {
+				t.Fatalf("expected %d openings, got %d", len(testOpenings), len(parsed))
+			}
+			for i, row := range parsed {
+				if row.Opening.Role != testOpenings[i].Role || row.Opening.Company != testOpenings[i].Company ||
+					row.Opening.Remote != testOpenings[i].Remote || row.Opening.Salary != testOpenings[i].Salary ||
+					row.Opening.ExternalID != testOpenings[i].ExternalID {
+					t.Fatalf("opening %d did not round-trip: %+v", i, row.Opening)
+				}
+			}
+
+			if _, _, err := reader.ReadChunk(10, 1); !errors.Is(err, io.EOF) {
+				t.Fatalf("expected EOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	content := writeAll(t, FormatXLSX)
+
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("expected a zip archive: %v", err)
+	}
+
+	var sheet string
+	for _, file := range archive.File {
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("unexpected open error: %v", err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		sheet = string(data)
+	}
+
+	if sheet == "" {
+		t.Fatal("expected xl/worksheets/sheet1.xml in the archive")
+	}
+	for _, expected := range []string{"external_id", "Acme, Inc.", "SRE &#34;lead&#34;", "<v>3000</v>"} {
+		if !strings.Contains(sheet, expected) {
+			t.Fatalf("expected sheet to contain %q", expected)
+		}
+	}
+}
+
+func TestWriters_EscapeFormulas(t *testing.T) {
+	opening := schemas.Openings{Role: "=HYPERLINK(\"https://evil.test\")", Company: "+Acme", Location: "-BR", Link: "@SUM(A1)", Salary: 2000, ExternalID: "ATS-1"}
+
+	var buf bytes.Buffer
+	writer, _ := NewWriter(FormatCSV, &buf)
+	if err := writer.Write(opening); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	writer.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if expected := `"'=HYPERLINK(""https://evil.test"")",'+Acme,'-BR,false,'@SUM(A1),2000,ATS-1`; lines[1] != expected {
+		t.Fatalf("expected %s, got %s", expected, lines[1])
+	}
+
+	buf.Reset()
+	writer, _ = NewWriter(FormatXLSX, &buf)
+	if err := writer.Write(opening); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	writer.Close()
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("expected a zip archive: %v", err)
+	}
+	rc, err := archive.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+	sheet, _ := io.ReadAll(rc)
+	rc.Close()
+
+	for _, expected := range []string{"&#39;=HYPERLINK", "&#39;+Acme", "&#39;-BR", "&#39;@SUM(A1)", "<v>2000</v>"} {
+		if !strings.Contains(string(sheet), expected) {
+			t.Fatalf("expected sheet to contain %q", expected)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, err := ParseFormat(""); err != nil || format != FormatCSV {
+		t.Fatalf("expected csv by default, got %q, %v", format, err)
+	}
+	if format, err := ParseFormat("XLSX"); err != nil || format != FormatXLSX {
+		t.Fatalf("expected xlsx, got %q, %v", format, err)
+	}
+	if _, err := ParseFormat("pdf"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"opportunities/internal/schemas"
+)
+
+// ndjsonOpening uses the keys accepted by the NDJSON importer.
+type ndjsonOpening struct {
+	Role       string `json:"role"`
+	Company    string `json:"company"`
+	Location   string `json:"location"`
+	Remote     bool   `json:"remote"`
+	Link       string `json:"link"`
+	Salary     int64  `json:"salary"`
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+type ndjsonWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	buffer := bufio.NewWriter(w)
+	return &ndjsonWriter{buffer: buffer, encoder: json.NewEncoder(buffer)}
+}
+
+func (n *ndjsonWriter) Write(opening schemas.Openings) error {
+	return n.encoder.Encode(ndjsonOpening{
+		Role:       opening.Role,
+		Company:    opening.Company,
+		Location:   opening.Location,
+		Remote:     opening.Remote,
+		Link:       opening.Link,
+		Salary:     opening.Salary,
+		ExternalID: opening.ExternalID,
+	})
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.buffer.Flush()
+}
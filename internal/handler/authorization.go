@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"opportunities/internal/auth"
+	"opportunities/internal/schemas"
+)
+
+// canModifyOpening reports whether the caller may update or delete the
+// opening: admins may change anything, everyone else only what they own.
+// Openings without an owner (created before ownership existed) are admin-only.
+func canModifyOpening(claims *auth.Claims, opening schemas.Openings) bool {
+	if claims == nil {
+		return false
+	}
+
+	if claims.IsAdmin() {
+		return true
+	}
+
+	return opening.Owner != "" && opening.Owner == claims.Email
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"log/slog"
 	"net/http"
+	"opportunities/internal/middleware"
 	"opportunities/internal/schemas"
 
 	"github.com/gin-gonic/gin"
@@ -32,6 +33,22 @@ func (h *OpeningHandler) CreateOpeningHandler(c *gin.Context) {
 		return
 	}
 
+	owner := ""
+	if claims, ok := middleware.Claims(c); ok {
+		owner = claims.Email
+	}
+
+	// The rules may fill fields, so they run before the required checks.
+	set := h.rules.For(owner)
+	if !set.Empty() {
+		values := request.values()
+		set.Transform(values)
+		if err := request.setValues(values); err != nil {
+			sendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
 	if err := request.Validate(); err != nil {
 		h.logger.Error("validation ", slog.String("error", err.Error()))
 		sendError(c, http.StatusBadRequest, err.Error())
@@ -45,6 +62,12 @@ func (h *OpeningHandler) CreateOpeningHandler(c *gin.Context) {
 		Remote:   *request.Remote,
 		Link:     request.Link,
 		Salary:   request.Salary,
+		Owner:    owner,
+	}
+
+	if err := set.Validate(opening); err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	if err := h.repo.Create(&opening); err != nil {
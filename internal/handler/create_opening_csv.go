@@ -1,17 +1,27 @@
 package handler
 
 import (
-	"io"
+	"errors"
+	"fmt"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"opportunities/internal/archive"
 	csvutil "opportunities/internal/csv"
+	"opportunities/internal/importer"
+	"opportunities/internal/middleware"
 	"opportunities/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// maxIdempotencyKeyLength bounds the Idempotency-Key header.
+const maxIdempotencyKeyLength = 255
+
 // @BasePath /api/v1
 
 // CreateOpeningCSVHandler godoc
@@ -20,59 +30,309 @@ import (
 // @Tags Opening
 // @Accept multipart/form-data
 // @Produce json
-// @Param file formData file true "CSV file"
-// @Success 202 {object} OpeningCSVAcceptedResponse
+// @Param file formData file true "CSV file, .csv.gz or .zip of CSV files; repeat the part to send several files"
+// @Param mode formData string false "Import mode: strict (default) or partial"
+// @Param upsert formData bool false "Update openings matched by external_id or link instead of inserting"
+// @Param close_missing formData bool false "Close the source's openings missing from the file (requires upsert and source)"
+// @Param source formData string false "Name of the system the file was exported from"
+// @Param mapping formData string false "JSON object mapping source columns to fields, e.g. {\"Job Title\": \"role\"}"
+// @Param mapping_preset formData string false "Name of a saved mapping preset"
+// @Param reject_unknown_columns formData bool false "Fail on columns that are not mapped to a field"
+// @Param delimiter formData string false "CSV delimiter: , ; | or tab (default: detected)"
+// @Param encoding formData string false "CSV encoding: utf-8, windows-1252 or iso-8859-1 (default: detected)"
+// @Param locale formData string false "How CSV booleans and amounts are written: pt-BR (default) or en-US"
+// @Param Idempotency-Key header string false "Key of the upload; a retry with the same key returns the original job"
+// @Success 200 {object} OpeningCSVAcceptedResponse "Repeated upload; returns the original job"
+// @Success 202 {object} OpeningCSVAcceptedResponse "Single file"
+// @Success 202 {object} OpeningCSVBatchAcceptedResponse "Archive or several files; one job per file"
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
 // @Failure 503 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /opening/csv [post]
 func (h *OpeningHandler) CreateOpeningCSVHandler(c *gin.Context) {
+	if !h.parseUpload(c) {
+		return
+	}
+
+	h.acceptImport(c, func(string) (string, error) { return importer.FormatCSV, nil })
+}
+
+// ImportOpeningsHandler godoc
+// @Summary Import openings from CSV, NDJSON or JSON
+// @Description Upload a file in any supported format and process openings asynchronously
+// @Tags Opening
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV, NDJSON or JSON array file, gzip-compressed or in a .zip; repeat the part to send several files"
+// @Param format formData string false "csv, ndjson or json (default: guessed from the file extension)"
+// @Param mode formData string false "Import mode: strict (default) or partial"
+// @Param upsert formData bool false "Update openings matched by external_id or link instead of inserting"
+// @Param close_missing formData bool false "Close the source's openings missing from the file (requires upsert and source)"
+// @Param source formData string false "Name of the system the file was exported from"
+// @Param mapping formData string false "JSON object mapping source columns or keys to fields"
+// @Param mapping_preset formData string false "Name of a saved mapping preset"
+// @Param reject_unknown_columns formData bool false "Fail on columns or keys that are not mapped to a field"
+// @Param delimiter formData string false "CSV delimiter: , ; | or tab (default: detected)"
+// @Param encoding formData string false "CSV encoding: utf-8, windows-1252 or iso-8859-1 (default: detected)"
+// @Param locale formData string false "How CSV booleans and amounts are written: pt-BR (default) or en-US"
+// @Param Idempotency-Key header string false "Key of the upload; a retry with the same key returns the original job"
+// @Success 200 {object} OpeningCSVAcceptedResponse "Repeated upload; returns the original job"
+// @Success 202 {object} OpeningCSVAcceptedResponse "Single file"
+// @Success 202 {object} OpeningCSVBatchAcceptedResponse "Archive or several files; one job per file"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/import [post]
+func (h *OpeningHandler) ImportOpeningsHandler(c *gin.Context) {
+	if !h.parseUpload(c) {
+		return
+	}
+
+	raw := c.PostForm("format")
+	if _, err := importer.ParseFormat(raw, ""); err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Without a format, each file of the upload is guessed from its name.
+	h.acceptImport(c, func(name string) (string, error) {
+		return importer.ParseFormat(raw, name)
+	})
+}
+
+// parseUpload reads the multipart form of an upload, whose body may not pass
+// the configured size; a larger body is answered with 413. A malformed form
+// is left to the checks of its fields.
+func (h *OpeningHandler) parseUpload(c *gin.Context) bool {
 	if h.csvService == nil {
 		sendError(c, http.StatusServiceUnavailable, "csv service unavailable")
+		return false
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.csvService.UploadMaxBytes())
+	if _, err := c.MultipartForm(); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			sendError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("upload exceeds %d bytes", tooLarge.Limit))
+			return false
+		}
+	}
+
+	return true
+}
+
+// acceptImport checks and spools an upload and queues its import, once
+// parseUpload has read it. The upload may have several file parts, each one
+// plain, gzip-compressed or a zip archive; formatFor picks the format of
+// every file from its name. A single plain file becomes one job; otherwise
+// each file becomes a job of a batch.
+func (h *OpeningHandler) acceptImport(c *gin.Context, formatFor func(name string) (string, error)) {
+	mode, err := service.ParseImportMode(c.PostForm("mode"))
+	if err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	fileHeader, err := c.FormFile("file")
+	upsert, err := parseFormBool(c, "upsert")
 	if err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	closeMissing, err := parseFormBool(c, "close_missing")
+	if err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source := strings.TrimSpace(c.PostForm("source"))
+	if closeMissing && (!upsert || source == "") {
+		sendError(c, http.StatusBadRequest, "close_missing requires upsert and source")
+		return
+	}
+
+	idempotencyKey := strings.TrimSpace(c.GetHeader("Idempotency-Key"))
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		sendError(c, http.StatusBadRequest, fmt.Sprintf("Idempotency-Key must have at most %d characters", maxIdempotencyKeyLength))
+		return
+	}
+
+	columns, ok := h.csvParseOptions(c)
+	if !ok {
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["file"]) == 0 {
 		sendError(c, http.StatusBadRequest, "file is required")
 		return
 	}
 
+	// The files are spooled to disk so queued jobs do not hold them in memory.
+	// The parts share one archive budget, so the limits hold for the whole
+	// request.
+	var uploads []service.SpooledUpload
+	budget := h.csvService.UploadBudget()
+	batch := len(form.File["file"]) > 1
+	for _, fileHeader := range form.File["file"] {
+		spooled, compressed, err := h.spoolUpload(fileHeader, budget, formatFor, columns)
+		if err != nil {
+			h.csvService.Discard(uploads)
+			h.sendUploadError(c, err)
+			return
+		}
+
+		uploads = append(uploads, spooled...)
+		batch = batch || compressed
+	}
+
+	owner := ""
+	if claims, ok := middleware.Claims(c); ok {
+		owner = claims.Email
+	}
+
+	newJob := func(upload service.SpooledUpload) service.OpeningCSVJob {
+		return service.OpeningCSVJob{
+			RequestID:      uuid.NewString(),
+			Owner:          owner,
+			FileName:       upload.Name,
+			Format:         upload.Format,
+			Mode:           mode,
+			Upsert:         upsert,
+			CloseMissing:   closeMissing,
+			Source:         source,
+			Columns:        columns,
+			IdempotencyKey: idempotencyKey,
+			ContentHash:    upload.ContentHash,
+			Path:           upload.Path,
+		}
+	}
+
+	if !batch {
+		h.enqueueImport(c, newJob(uploads[0]))
+		return
+	}
+
+	batchID := uuid.NewString()
+	items := make([]openingCSVBatchItem, 0, len(uploads))
+	accepted, rejected := 0, 0
+	var firstErr error
+	for i, upload := range uploads {
+		job := newJob(upload)
+		job.BatchID = batchID
+		if idempotencyKey != "" {
+			// Each file of a retried batch matches the same file of the first
+			// one.
+			job.IdempotencyKey = fmt.Sprintf("%s/%d", idempotencyKey, i)
+		}
+
+		item := openingCSVBatchItem{
+			RequestID: job.RequestID,
+			FileName:  job.FileName,
+			Status:    "accepted",
+			Format:    job.Format,
+			Mode:      job.Mode,
+		}
+
+		original, duplicate, err := h.csvService.EnqueueOnce(job)
+		switch {
+		case err != nil:
+			if enqueueErrorStatus(err) == http.StatusInternalServerError {
+				h.logger.Error("acceptImport enqueue batch job", slog.String("error", err.Error()))
+			}
+			item.RequestID = ""
+			item.Status = "rejected"
+			item.Error = err.Error()
+			rejected++
+			if firstErr == nil {
+				firstErr = err
+			}
+		case duplicate:
+			item.RequestID = original.RequestID
+			item.Status = "duplicate"
+		default:
+			accepted++
+		}
+
+		items = append(items, item)
+	}
+
+	status := http.StatusAccepted
+	if accepted == 0 {
+		if rejected > 0 {
+			sendEnqueueError(c, firstErr)
+			return
+		}
+		// Every file was uploaded before.
+		status = http.StatusOK
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.JSON(status, gin.H{
+		"message": "openingCsvBatchAccepted",
+		"data": openingCSVBatchData{
+			BatchID: batchID,
+			Jobs:    items,
+		},
+	})
+}
+
+// spoolUpload spools the files of one file part.
+func (h *OpeningHandler) spoolUpload(fileHeader *multipart.FileHeader, budget *archive.Budget, formatFor func(name string) (string, error), columns csvutil.ParseOptions) ([]service.SpooledUpload, bool, error) {
 	file, err := fileHeader.Open()
 	if err != nil {
-		h.logger.Error("CreateOpeningCSVHandler open file", slog.String("error", err.Error()))
-		sendError(c, http.StatusBadRequest, "invalid file")
-		return
+		return nil, false, &service.UploadError{File: fileHeader.Filename, Err: errors.New("invalid file")}
 	}
 	defer file.Close()
 
-	content, err := io.ReadAll(file)
-	if err != nil {
-		h.logger.Error("CreateOpeningCSVHandler read file", slog.String("error", err.Error()))
-		sendError(c, http.StatusBadRequest, "invalid file")
+	return h.csvService.SpoolUpload(fileHeader.Filename, file, fileHeader.Size, budget, formatFor, columns)
+}
+
+// sendUploadError answers an upload that could not be spooled.
+func (h *OpeningHandler) sendUploadError(c *gin.Context, err error) {
+	if errors.Is(err, archive.ErrTooLarge) || errors.Is(err, archive.ErrTooManyEntries) {
+		sendError(c, http.StatusRequestEntityTooLarge, err.Error())
 		return
 	}
 
-	if err := csvutil.ValidateHeader(content); err != nil {
+	var uploadErr *service.UploadError
+	if errors.As(err, &uploadErr) {
 		sendError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	requestID := uuid.NewString()
+	h.logger.Error("acceptImport spool file", slog.String("error", err.Error()))
+	sendError(c, http.StatusInternalServerError, "failed to store uploaded file")
+}
 
-	err = h.csvService.Enqueue(service.OpeningCSVJob{
-		RequestID: requestID,
-		Content:   content,
-	})
+// enqueueImport queues the job of a single file upload.
+func (h *OpeningHandler) enqueueImport(c *gin.Context, job service.OpeningCSVJob) {
+	original, duplicate, err := h.csvService.EnqueueOnce(job)
 	if err != nil {
-		if err == service.ErrCSVQueueFull {
-			sendError(c, http.StatusServiceUnavailable, err.Error())
-			return
+		if enqueueErrorStatus(err) == http.StatusInternalServerError {
+			h.logger.Error("acceptImport enqueue job", slog.String("error", err.Error()))
 		}
+		sendEnqueueError(c, err)
+		return
+	}
 
-		h.logger.Error("CreateOpeningCSVHandler enqueue job", slog.String("error", err.Error()))
-		sendError(c, http.StatusInternalServerError, "failed to enqueue csv processing")
+	if duplicate {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.JSON(http.StatusOK, gin.H{
+			"message": "openingCsvDuplicate",
+			"data": gin.H{
+				"request_id": original.RequestID,
+				"status":     original.Status,
+				"format":     original.Format,
+				"mode":       original.Mode,
+			},
+		})
 		return
 	}
 
@@ -80,8 +340,45 @@ func (h *OpeningHandler) CreateOpeningCSVHandler(c *gin.Context) {
 	c.JSON(http.StatusAccepted, gin.H{
 		"message": "openingCsvAccepted",
 		"data": gin.H{
-			"request_id": requestID,
+			"request_id": job.RequestID,
 			"status":     "accepted",
+			"format":     job.Format,
+			"mode":       job.Mode,
 		},
 	})
 }
+
+func enqueueErrorStatus(err error) int {
+	switch err {
+	case service.ErrCSVQueueFull, service.ErrCSVServiceStopped:
+		return http.StatusServiceUnavailable
+	case service.ErrIdempotencyKeyReused:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func sendEnqueueError(c *gin.Context, err error) {
+	status := enqueueErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		sendError(c, status, "failed to enqueue csv processing")
+		return
+	}
+
+	sendError(c, status, err.Error())
+}
+
+func parseFormBool(c *gin.Context, field string) (bool, error) {
+	raw := c.PostForm(field)
+	if raw == "" {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean", field)
+	}
+
+	return value, nil
+}
@@ -1,18 +1,24 @@
 package handler
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"opportunities/internal/auth"
 	"opportunities/internal/middleware"
 	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
 	"opportunities/internal/service"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestCreateOpeningCSVHandler_WithAuth(t *testing.T) {
@@ -78,6 +84,47 @@ func TestCreateOpeningCSVHandler_WithAuth(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, recorder.Code)
 	})
 
+	invalidOptions := []struct {
+		name    string
+		fields  map[string]string
+		message string
+	}{
+		{"unknown import mode", map[string]string{"mode": "lenient"}, "mode must be"},
+		{"non boolean upsert", map[string]string{"upsert": "maybe"}, "upsert must be a boolean"},
+		{"close_missing without source", map[string]string{"upsert": "true", "close_missing": "true"}, "close_missing requires"},
+		{"unsupported locale", map[string]string{"locale": "fr-FR"}, "locale must be"},
+	}
+	for _, tt := range invalidOptions {
+		t.Run("Should return 400 for "+tt.name, func(t *testing.T) {
+			mockRepo := new(repository.OpeningRepositoryMock)
+			csvService := service.NewOpeningCSVService(mockRepo, nil, 1)
+			h := New(mockRepo, csvService)
+			r := gin.Default()
+			r.Use(middleware.Auth())
+			r.POST("/opening/csv", h.CreateOpeningCSVHandler)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			for name, value := range tt.fields {
+				_ = writer.WriteField(name, value)
+			}
+			part, _ := writer.CreateFormFile("file", "openings.csv")
+			_, _ = part.Write([]byte("role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,1000\n"))
+			_ = writer.Close()
+
+			token, _ := auth.GenerateToken("test@test.com")
+			req, _ := http.NewRequest("POST", "/opening/csv", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), tt.message)
+		})
+	}
+
 	t.Run("Should return 503 when queue is full", func(t *testing.T) {
 		mockRepo := new(repository.OpeningRepositoryMock)
 		csvService := service.NewOpeningCSVService(mockRepo, nil, 0)
@@ -97,6 +144,311 @@ func TestCreateOpeningCSVHandler_WithAuth(t *testing.T) {
 
 		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
 	})
+
+	t.Run("Should return 413 when the upload body is too large", func(t *testing.T) {
+		mockRepo := new(repository.OpeningRepositoryMock)
+		csvService := service.NewOpeningCSVService(mockRepo, nil, 1, service.WithUploadMaxBytes(1024), service.WithSpoolDir(t.TempDir()))
+		h := New(mockRepo, csvService)
+		r := gin.Default()
+		r.Use(middleware.Auth())
+		r.POST("/opening/csv", h.CreateOpeningCSVHandler)
+
+		token, _ := auth.GenerateToken("test@test.com")
+		content := "role,company,location,remote,link,salary\n" + strings.Repeat("Go Dev,Acme,BR,true,https://acme.com,1000\n", 100)
+		body, contentType := newCSVMultipartBody(t, "file", "openings.csv", content)
+		req, _ := http.NewRequest("POST", "/opening/csv", body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "upload exceeds 1024 bytes")
+	})
+
+	repeated := []struct {
+		name         string
+		original     schemas.ImportJob
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Should return 200 with the original job for a repeated Idempotency-Key",
+			original:     schemas.ImportJob{RequestID: "req-original", Status: schemas.ImportJobRunning, Format: "csv", Mode: "strict"},
+			expectedCode: http.StatusOK,
+			expectedBody: `"request_id":"req-original"`,
+		},
+		{
+			name:         "Should return 422 when the Idempotency-Key was used for another file",
+			original:     schemas.ImportJob{RequestID: "req-original", ContentHash: "other-file"},
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: service.ErrIdempotencyKeyReused.Error(),
+		},
+	}
+	for _, tt := range repeated {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.OpeningRepositoryMock)
+			mockJobs := new(repository.ImportJobRepositoryMock)
+			mockJobs.On("FindByIdempotencyKey", "test@test.com", "retry-1", mock.Anything).Return(tt.original, nil).Once()
+			csvService := service.NewOpeningCSVService(mockRepo, nil, 1, service.WithImportJobs(mockJobs), service.WithSpoolDir(t.TempDir()))
+			h := New(mockRepo, csvService)
+			r := gin.Default()
+			r.Use(middleware.Auth())
+			r.POST("/opening/csv", h.CreateOpeningCSVHandler)
+
+			token, _ := auth.GenerateToken("test@test.com")
+			body, contentType := newCSVMultipartBody(t, "file", "openings.csv", "role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,1000\n")
+			req, _ := http.NewRequest("POST", "/opening/csv", body)
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Idempotency-Key", "retry-1")
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), tt.expectedBody)
+			mockJobs.AssertExpectations(t)
+			mockJobs.AssertNotCalled(t, "Create", mock.Anything)
+		})
+	}
+}
+
+func TestImportOpeningsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		fileName     string
+		format       string
+		content      string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "NDJSON guessed from the extension",
+			fileName:     "openings.ndjson",
+			content:      `{"role":"Go Dev","company":"Acme","location":"BR","remote":true,"link":"https://acme.com","salary":1000}`,
+			expectedCode: http.StatusAccepted,
+			expectedBody: `"format":"ndjson"`,
+		},
+		{
+			name:         "JSON array with explicit format",
+			fileName:     "export.txt",
+			format:       "json",
+			content:      `[{"role":"Go Dev","company":"Acme","location":"BR","remote":true,"link":"https://acme.com","salary":1000}]`,
+			expectedCode: http.StatusAccepted,
+			expectedBody: `"format":"json"`,
+		},
+		{
+			name:         "CSV guessed from the extension",
+			fileName:     "openings.csv",
+			content:      "role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,1000\n",
+			expectedCode: http.StatusAccepted,
+			expectedBody: `"format":"csv"`,
+		},
+		{
+			name:         "Unsupported format",
+			fileName:     "openings.xml",
+			format:       "xml",
+			content:      "<openings/>",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "format must be",
+		},
+		{
+			name:         "Not JSON",
+			fileName:     "openings.json",
+			content:      "role,company",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid json format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.OpeningRepositoryMock)
+			h := New(mockRepo, service.NewOpeningCSVService(mockRepo, nil, 1, service.WithSpoolDir(t.TempDir())))
+			r := gin.Default()
+			r.Use(middleware.Auth())
+			r.POST("/opening/import", h.ImportOpeningsHandler)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, _ := writer.CreateFormFile("file", tt.fileName)
+			_, _ = part.Write([]byte(tt.content))
+			if tt.format != "" {
+				_ = writer.WriteField("format", tt.format)
+			}
+			_ = writer.Close()
+
+			token, _ := auth.GenerateToken("test@test.com")
+			req, _ := http.NewRequest("POST", "/opening/import", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), tt.expectedBody)
+		})
+	}
+}
+
+func TestCreateOpeningCSVHandler_CompressedAndMultipleFiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	valid := "role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,1000\n"
+
+	tests := []struct {
+		name         string
+		files        []multipartFile
+		limits       []int64
+		expectedCode int
+		expectedJobs int
+		expectedBody string
+	}{
+		{
+			name:         "Gzip file",
+			files:        []multipartFile{{"openings.csv.gz", gzipContent(t, valid)}},
+			expectedCode: http.StatusAccepted,
+			expectedJobs: 1,
+			expectedBody: `"file_name":"openings.csv"`,
+		},
+		{
+			name:         "Zip archive with several files",
+			files:        []multipartFile{{"week.zip", zipContent(t, "first.csv", valid, "second.csv", valid)}},
+			expectedCode: http.StatusAccepted,
+			expectedJobs: 2,
+			expectedBody: `"file_name":"second.csv"`,
+		},
+		{
+			name:         "Several file parts",
+			files:        []multipartFile{{"first.csv", []byte(valid)}, {"second.csv.gz", gzipContent(t, valid)}},
+			expectedCode: http.StatusAccepted,
+			expectedJobs: 2,
+			expectedBody: `"batch_id"`,
+		},
+		{
+			name:         "Invalid file in the archive",
+			files:        []multipartFile{{"week.zip", zipContent(t, "first.csv", valid, "broken.csv", "role,company\n")}},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "broken.csv",
+		},
+		{
+			name:         "Too many files in the archive",
+			files:        []multipartFile{{"week.zip", zipContent(t, "first.csv", valid, "second.csv", valid)}},
+			limits:       []int64{1, 0},
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedBody: "too many files",
+		},
+		{
+			name:         "Decompressed size over the limit",
+			files:        []multipartFile{{"bomb.csv.gz", gzipContent(t, valid+strings.Repeat("Go Dev,Acme,BR,true,https://acme.com,1000\n", 1000))}},
+			limits:       []int64{0, 1024},
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedBody: "too large",
+		},
+		{
+			name:         "Too many files across the parts",
+			files:        []multipartFile{{"first.zip", zipContent(t, "a.csv", valid, "b.csv", valid)}, {"second.zip", zipContent(t, "c.csv", valid, "d.csv", valid)}},
+			limits:       []int64{3, 0},
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedBody: "too many files",
+		},
+		{
+			name:         "Plain parts count toward the file limit",
+			files:        []multipartFile{{"first.csv", []byte(valid)}, {"second.csv", []byte(valid)}, {"third.csv", []byte(valid)}},
+			limits:       []int64{2, 0},
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedBody: "too many files",
+		},
+		{
+			name:         "Decompressed size over the limit across the parts",
+			files:        []multipartFile{{"first.csv.gz", gzipContent(t, valid+strings.Repeat("Go Dev,Acme,BR,true,https://acme.com,1000\n", 10))}, {"second.csv.gz", gzipContent(t, valid+strings.Repeat("Go Dev,Acme,BR,true,https://acme.com,1000\n", 10))}},
+			limits:       []int64{0, 800},
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedBody: "too large",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spoolDir := t.TempDir()
+			opts := []service.OpeningCSVServiceOption{service.WithSpoolDir(spoolDir)}
+			if tt.limits != nil {
+				opts = append(opts, service.WithArchiveLimits(int(tt.limits[0]), tt.limits[1]))
+			}
+
+			mockRepo := new(repository.OpeningRepositoryMock)
+			csvService := service.NewOpeningCSVService(mockRepo, nil, 5, opts...)
+			h := New(mockRepo, csvService)
+			r := gin.Default()
+			r.Use(middleware.Auth())
+			r.POST("/opening/csv", h.CreateOpeningCSVHandler)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			for _, file := range tt.files {
+				part, _ := writer.CreateFormFile("file", file.name)
+				_, _ = part.Write(file.content)
+			}
+			_ = writer.Close()
+
+			token, _ := auth.GenerateToken("test@test.com")
+			req, _ := http.NewRequest("POST", "/opening/csv", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), tt.expectedBody)
+
+			total, _ := csvService.QueueStats("test@test.com")
+			assert.Equal(t, tt.expectedJobs, total.Queued)
+
+			// A rejected upload leaves nothing spooled.
+			entries, _ := os.ReadDir(spoolDir)
+			assert.Len(t, entries, tt.expectedJobs)
+		})
+	}
+}
+
+type multipartFile struct {
+	name    string
+	content []byte
+}
+
+func gzipContent(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(content))
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close error: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func zipContent(t *testing.T, files ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		w, _ := zw.Create(files[i])
+		_, _ = w.Write([]byte(files[i+1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close error: %v", err)
+	}
+
+	return buf.Bytes()
 }
 
 func newCSVMultipartBody(t *testing.T, fieldName, fileName, fileContent string) (*bytes.Buffer, string) {
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	csvutil "opportunities/internal/csv"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CSVMappingRequest struct {
+	Mapping map[string]string `json:"mapping"`
+}
+
+// @BasePath /api/v1
+
+// ListCSVMappingsHandler godoc
+// @Summary List CSV mapping presets
+// @Description List the caller's saved CSV column mappings
+// @Tags Opening
+// @Produce json
+// @Success 200 {object} ListCSVMappingsResponse
+// @Security BearerAuth
+// @Router /opening/csv/mappings [get]
+func (h *OpeningHandler) ListCSVMappingsHandler(c *gin.Context) {
+	if h.csvMappings == nil {
+		sendError(c, http.StatusServiceUnavailable, "csv mapping presets are not configured")
+		return
+	}
+
+	claims, _ := middleware.Claims(c)
+
+	presets, err := h.csvMappings.List(claims.Email)
+	if err != nil {
+		h.logger.Error("ListCSVMappingsHandler list presets", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error getting csv mapping presets")
+		return
+	}
+
+	sendSuccess(c, "csvMappings", presets)
+}
+
+// SaveCSVMappingHandler godoc
+// @Summary Save CSV mapping preset
+// @Description Create or replace a named CSV column mapping (source column -> opening field)
+// @Tags Opening
+// @Accept json
+// @Produce json
+// @Param name path string true "Preset name"
+// @Param request body CSVMappingRequest true "Mapping"
+// @Success 200 {object} CSVMappingResponse
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/csv/mappings/{name} [put]
+func (h *OpeningHandler) SaveCSVMappingHandler(c *gin.Context) {
+	if h.csvMappings == nil {
+		sendError(c, http.StatusServiceUnavailable, "csv mapping presets are not configured")
+		return
+	}
+
+	var req CSVMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Mapping) == 0 {
+		sendError(c, http.StatusBadRequest, errParamIsRequired("mapping", "object").Error())
+		return
+	}
+
+	if err := csvutil.ValidateMapping(req.Mapping); err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	claims, _ := middleware.Claims(c)
+
+	preset := schemas.CSVMappingPreset{
+		Owner:   claims.Email,
+		Name:    strings.TrimSpace(c.Param("name")),
+		Mapping: req.Mapping,
+	}
+	if err := h.csvMappings.Save(&preset); err != nil {
+		h.logger.Error("SaveCSVMappingHandler save preset", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error saving csv mapping preset")
+		return
+	}
+
+	sendSuccess(c, "saveCsvMapping", preset)
+}
+
+// DeleteCSVMappingHandler godoc
+// @Summary Delete CSV mapping preset
+// @Description Delete one of the caller's CSV column mappings
+// @Tags Opening
+// @Produce json
+// @Param name path string true "Preset name"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/csv/mappings/{name} [delete]
+func (h *OpeningHandler) DeleteCSVMappingHandler(c *gin.Context) {
+	if h.csvMappings == nil {
+		sendError(c, http.StatusServiceUnavailable, "csv mapping presets are not configured")
+		return
+	}
+
+	claims, _ := middleware.Claims(c)
+	name := c.Param("name")
+
+	if err := h.csvMappings.Delete(claims.Email, name); err != nil {
+		if errors.Is(err, repository.ErrMappingPresetNotFound) {
+			sendError(c, http.StatusNotFound, fmt.Sprintf("csv mapping preset %s not found", name))
+			return
+		}
+
+		h.logger.Error("DeleteCSVMappingHandler delete preset", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error deleting csv mapping preset")
+		return
+	}
+
+	sendSuccess(c, "deleteCsvMapping", gin.H{"name": name})
+}
+
+// csvParseOptions builds the column options of an upload from the
+// mapping_preset, mapping, reject_unknown_columns, delimiter, encoding and
+// locale form fields. Entries in mapping override the preset. It writes the
+// error response itself.
+func (h *OpeningHandler) csvParseOptions(c *gin.Context) (csvutil.ParseOptions, bool) {
+	opts := csvutil.ParseOptions{Mapping: csvutil.ColumnMapping{}}
+
+	reject, err := parseFormBool(c, "reject_unknown_columns")
+	if err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return opts, false
+	}
+	opts.RejectUnknownColumns = reject
+
+	if opts.Delimiter, err = csvutil.ParseDelimiter(c.PostForm("delimiter")); err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return opts, false
+	}
+
+	if opts.Encoding, err = csvutil.ParseEncoding(c.PostForm("encoding")); err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return opts, false
+	}
+
+	if opts.Locale, err = csvutil.ParseLocale(c.PostForm("locale")); err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return opts, false
+	}
+
+	if name := strings.TrimSpace(c.PostForm("mapping_preset")); name != "" {
+		if h.csvMappings == nil {
+			sendError(c, http.StatusServiceUnavailable, "csv mapping presets are not configured")
+			return opts, false
+		}
+
+		claims, _ := middleware.Claims(c)
+		owner := ""
+		if claims != nil {
+			owner = claims.Email
+		}
+
+		preset, err := h.csvMappings.Get(owner, name)
+		if err != nil {
+			if errors.Is(err, repository.ErrMappingPresetNotFound) {
+				sendError(c, http.StatusBadRequest, fmt.Sprintf("csv mapping preset %s not found", name))
+				return opts, false
+			}
+
+			h.logger.Error("csvParseOptions get preset", slog.String("error", err.Error()))
+			sendError(c, http.StatusInternalServerError, "error getting csv mapping preset")
+			return opts, false
+		}
+
+		for source, target := range preset.Mapping {
+			opts.Mapping[source] = target
+		}
+	}
+
+	if raw := c.PostForm("mapping"); raw != "" {
+		var mapping csvutil.ColumnMapping
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			sendError(c, http.StatusBadRequest, "mapping must be a JSON object of source column to field")
+			return opts, false
+		}
+
+		if err := csvutil.ValidateMapping(mapping); err != nil {
+			sendError(c, http.StatusBadRequest, err.Error())
+			return opts, false
+		}
+
+		for source, target := range mapping {
+			opts.Mapping[source] = target
+		}
+	}
+
+	return opts, true
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"opportunities/internal/auth"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+	"opportunities/internal/service"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCSVMappingHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(mappings *repository.CSVMappingRepositoryMock) *gin.Engine {
+		mockRepo := new(repository.OpeningRepositoryMock)
+		h := New(mockRepo, service.NewOpeningCSVService(mockRepo, nil, 1), WithCSVMappings(mappings))
+		r := gin.Default()
+		r.Use(middleware.Auth())
+		r.POST("/opening/csv", h.CreateOpeningCSVHandler)
+		r.GET("/opening/csv/mappings", h.ListCSVMappingsHandler)
+		r.PUT("/opening/csv/mappings/:name", h.SaveCSVMappingHandler)
+		r.DELETE("/opening/csv/mappings/:name", h.DeleteCSVMappingHandler)
+		r.GET("/opening/csv/:request_id", h.ShowOpeningCSVJobHandler)
+		return r
+	}
+
+	token, _ := auth.GenerateToken("recruiter@test.com", auth.RoleRecruiter)
+
+	t.Run("Should save a preset", func(t *testing.T) {
+		mappings := new(repository.CSVMappingRepositoryMock)
+		mappings.On("Save", mock.MatchedBy(func(p *schemas.CSVMappingPreset) bool {
+			return p.Owner == "recruiter@test.com" && p.Name == "ats" && p.Mapping["Job Title"] == "role"
+		})).Return(nil).Once()
+		r := newRouter(mappings)
+
+		req, _ := http.NewRequest("PUT", "/opening/csv/mappings/ats", bytes.NewBufferString(`{"mapping":{"Job Title":"role"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mappings.AssertExpectations(t)
+	})
+
+	t.Run("Should reject a mapping to an unknown field", func(t *testing.T) {
+		r := newRouter(new(repository.CSVMappingRepositoryMock))
+
+		req, _ := http.NewRequest("PUT", "/opening/csv/mappings/ats", bytes.NewBufferString(`{"mapping":{"Job Title":"title"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Should return 404 when deleting an unknown preset", func(t *testing.T) {
+		mappings := new(repository.CSVMappingRepositoryMock)
+		mappings.On("Delete", "recruiter@test.com", "missing").Return(repository.ErrMappingPresetNotFound).Once()
+		r := newRouter(mappings)
+
+		req, _ := http.NewRequest("DELETE", "/opening/csv/mappings/missing", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("Should accept an upload that uses a preset and an inline mapping", func(t *testing.T) {
+		mappings := new(repository.CSVMappingRepositoryMock)
+		mappings.On("Get", "recruiter@test.com", "ats").Return(schemas.CSVMappingPreset{
+			Mapping: map[string]string{"Job Title": "role", "Employer": "company"},
+		}, nil).Once()
+		r := newRouter(mappings)
+
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		_ = writer.WriteField("mapping_preset", "ats")
+		_ = writer.WriteField("mapping", `{"Pay": "salary"}`)
+		part, _ := writer.CreateFormFile("file", "openings.csv")
+		_, _ = part.Write([]byte("Job Title,Employer,location,remote,link,Pay,notes\nGo Dev,Acme,BR,true,https://acme.com,1000,hot\n"))
+		_ = writer.Close()
+
+		req, _ := http.NewRequest("POST", "/opening/csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+		mappings.AssertExpectations(t)
+	})
+}
@@ -2,7 +2,9 @@ package handler
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
+	"opportunities/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,6 +21,8 @@ import (
 // @Param id query string true "Opening identification"
 // @Success 200 {object} DeleteOpeningResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /opening [delete]
@@ -30,7 +34,20 @@ func (h *OpeningHandler) DeleteOpeningHandler(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.Delete(id).Error; err != nil {
+	opening, err := h.repo.Get(id)
+	if err != nil {
+		sendError(c, http.StatusNotFound, fmt.Sprintf("opening %s not found", id))
+		return
+	}
+
+	claims, _ := middleware.Claims(c)
+	if !canModifyOpening(claims, opening) {
+		sendError(c, http.StatusForbidden, "only the opening owner or an admin can delete it")
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		h.logger.Error("DeleteOpeningHandler delete opening", slog.String("error", err.Error()))
 		sendError(c, http.StatusInternalServerError,
 			fmt.Sprintf("error deleting opening %s", id))
 		return
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"opportunities/internal/export"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+	"opportunities/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @BasePath /api/v1
+
+// ExportOpeningsHandler godoc
+// @Summary Export openings
+// @Description Download the openings as CSV, NDJSON or XLSX. The CSV can be imported back. With async=true the file is built in the background and downloaded from /openings/export/{id}/download
+// @Tags Openings
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce json
+// @Param format query string false "csv (default), ndjson or xlsx"
+// @Param mine query bool false "Only openings owned by the caller (requires token)"
+// @Param async query bool false "Build the file in the background (requires token)"
+// @Success 200 {file} file
+// @Success 202 {object} ExportJobResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /openings/export [get]
+func (h *OpeningHandler) ExportOpeningsHandler(c *gin.Context) {
+	if h.exports == nil {
+		sendError(c, http.StatusServiceUnavailable, "export service unavailable")
+		return
+	}
+
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	async := false
+	if raw := c.Query("async"); raw != "" {
+		async, err = strconv.ParseBool(raw)
+		if err != nil {
+			sendError(c, http.StatusBadRequest, "async must be a boolean")
+			return
+		}
+	}
+
+	filter := repository.OpeningFilter{}
+	mine := c.Query("mine") == "true"
+	claims, authenticated := middleware.Claims(c)
+
+	if mine {
+		if !authenticated {
+			sendError(c, http.StatusUnauthorized, "a valid token is required to filter by owner")
+			return
+		}
+		filter.Owner = claims.Email
+	}
+
+	if async {
+		if !authenticated {
+			sendError(c, http.StatusUnauthorized, "a valid token is required for async exports")
+			return
+		}
+
+		job, err := h.exports.Enqueue(claims.Email, format, mine)
+		if err != nil {
+			if errors.Is(err, service.ErrExportQueueFull) || errors.Is(err, service.ErrExportStopped) {
+				sendError(c, http.StatusServiceUnavailable, err.Error())
+				return
+			}
+
+			h.logger.Error("ExportOpeningsHandler enqueue export", slog.String("error", err.Error()))
+			sendError(c, http.StatusInternalServerError, "failed to enqueue export")
+			return
+		}
+
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "openingsExportAccepted",
+			"data":    job,
+		})
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="openings.%s"`, format))
+	c.Status(http.StatusOK)
+
+	if _, err := h.exports.Export(c.Writer, format, filter); err != nil {
+		h.logger.Error("ExportOpeningsHandler write export", slog.String("error", err.Error()))
+		if !c.Writer.Written() {
+			sendError(c, http.StatusInternalServerError, "error exporting openings")
+		}
+	}
+}
+
+// ShowExportJobHandler godoc
+// @Summary Show export job
+// @Description Show the status of an async export
+// @Tags Openings
+// @Produce json
+// @Param id path string true "Export identification"
+// @Success 200 {object} ExportJobResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /openings/export/{id} [get]
+func (h *OpeningHandler) ShowExportJobHandler(c *gin.Context) {
+	job, ok := h.loadExportJob(c)
+	if !ok {
+		return
+	}
+
+	sendSuccess(c, "openingsExport", job)
+}
+
+// DownloadExportHandler godoc
+// @Summary Download export
+// @Description Download the file of a finished async export
+// @Tags Openings
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param id path string true "Export identification"
+// @Success 200 {file} file
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /openings/export/{id}/download [get]
+func (h *OpeningHandler) DownloadExportHandler(c *gin.Context) {
+	job, ok := h.loadExportJob(c)
+	if !ok {
+		return
+	}
+
+	file, err := h.exports.Open(job)
+	if err != nil {
+		if errors.Is(err, service.ErrExportNotReady) {
+			sendError(c, http.StatusConflict, fmt.Sprintf("export %s is %s", job.ID, job.Status))
+			return
+		}
+		if errors.Is(err, service.ErrExportExpired) {
+			sendError(c, http.StatusNotFound, fmt.Sprintf("export %s file expired", job.ID))
+			return
+		}
+
+		h.logger.Error("DownloadExportHandler open file", slog.String("error", err.Error()))
+		sendError(c, http.StatusNotFound, fmt.Sprintf("export %s file not found", job.ID))
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", export.ContentType(job.Format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="openings.%s"`, job.Format))
+	c.Status(http.StatusOK)
+
+	if _, err := io.Copy(c.Writer, file); err != nil {
+		h.logger.Error("DownloadExportHandler write file", slog.String("error", err.Error()))
+	}
+}
+
+// loadExportJob fetches the export in the id path param and checks that the
+// caller requested it (or is an admin). It writes the error response itself.
+func (h *OpeningHandler) loadExportJob(c *gin.Context) (schemas.ExportJob, bool) {
+	if h.exports == nil {
+		sendError(c, http.StatusServiceUnavailable, "export service unavailable")
+		return schemas.ExportJob{}, false
+	}
+
+	id := c.Param("id")
+
+	job, err := h.exports.GetJob(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrExportJobNotFound) {
+			sendError(c, http.StatusNotFound, fmt.Sprintf("export %s not found", id))
+			return schemas.ExportJob{}, false
+		}
+
+		h.logger.Error("loadExportJob get job", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error getting export")
+		return schemas.ExportJob{}, false
+	}
+
+	claims, _ := middleware.Claims(c)
+	if claims == nil || (!claims.IsAdmin() && job.Owner != claims.Email) {
+		sendError(c, http.StatusForbidden, "only the requester or an admin can access this export")
+		return schemas.ExportJob{}, false
+	}
+
+	return job, true
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"opportunities/internal/auth"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+	"opportunities/internal/service"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExportOpeningsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	openings := []schemas.Openings{{Role: "Go Dev", Company: "Acme", Location: "BR", Remote: true, Link: "https://acme.com", Salary: 1000}}
+
+	tests := []struct {
+		name         string
+		query        string
+		email        string
+		mockBehavior func(m *repository.OpeningRepositoryMock, jobs *repository.ExportJobRepositoryMock)
+		expectedCode int
+		expectedType string
+		expectedBody string
+	}{
+		{
+			name:  "Streams CSV by default",
+			query: "",
+			mockBehavior: func(m *repository.OpeningRepositoryMock, _ *repository.ExportJobRepositoryMock) {
+				m.On("ListInBatches", repository.OpeningFilter{}, mock.Anything, mock.Anything).Return(openings, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedType: "text/csv; charset=utf-8",
+			expectedBody: "role,company,location,remote,link,salary,external_id\nGo Dev,Acme,BR,true,https://acme.com,1000,\n",
+		},
+		{
+			name:  "Filters NDJSON by owner",
+			query: "?format=ndjson&mine=true",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.OpeningRepositoryMock, _ *repository.ExportJobRepositoryMock) {
+				m.On("ListInBatches", repository.OpeningFilter{Owner: "owner@test.com"}, mock.Anything, mock.Anything).Return(openings, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedType: "application/x-ndjson",
+			expectedBody: `"role":"Go Dev"`,
+		},
+		{
+			name:         "Unsupported format",
+			query:        "?format=pdf",
+			mockBehavior: func(*repository.OpeningRepositoryMock, *repository.ExportJobRepositoryMock) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Mine without token",
+			query:        "?mine=true",
+			mockBehavior: func(*repository.OpeningRepositoryMock, *repository.ExportJobRepositoryMock) {},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Async without token",
+			query:        "?async=true",
+			mockBehavior: func(*repository.OpeningRepositoryMock, *repository.ExportJobRepositoryMock) {},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:  "Async export is queued",
+			query: "?async=true&format=xlsx",
+			email: "owner@test.com",
+			mockBehavior: func(_ *repository.OpeningRepositoryMock, jobs *repository.ExportJobRepositoryMock) {
+				jobs.On("Create", mock.MatchedBy(func(job *schemas.ExportJob) bool {
+					return job.Owner == "owner@test.com" && job.Format == "xlsx" && job.Status == schemas.ImportJobQueued
+				})).Return(nil).Once()
+			},
+			expectedCode: http.StatusAccepted,
+			expectedBody: `"status":"queued"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.OpeningRepositoryMock)
+			mockJobs := new(repository.ExportJobRepositoryMock)
+			tt.mockBehavior(mockRepo, mockJobs)
+			exports := service.NewOpeningExportService(mockRepo, mockJobs, t.TempDir(), 1)
+
+			r := gin.Default()
+			r.Use(middleware.OptionalAuth())
+			r.GET("/openings/export", New(mockRepo, nil, WithExports(exports)).ExportOpeningsHandler)
+
+			req, _ := http.NewRequest("GET", "/openings/export"+tt.query, nil)
+			if tt.email != "" {
+				token, _ := auth.GenerateToken(tt.email)
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			if tt.expectedType != "" {
+				assert.Equal(t, tt.expectedType, recorder.Header().Get("Content-Type"))
+			}
+			if tt.expectedBody != "" {
+				assert.True(t, strings.Contains(recorder.Body.String(), tt.expectedBody), recorder.Body.String())
+			}
+			mockRepo.AssertExpectations(t)
+			mockJobs.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExportJobHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	queued := schemas.ExportJob{ID: "exp-1", Owner: "owner@test.com", Format: "csv", Status: schemas.ImportJobQueued}
+
+	tests := []struct {
+		name         string
+		path         string
+		email        string
+		roles        []string
+		job          schemas.ExportJob
+		err          error
+		expectedCode int
+	}{
+		{name: "Owner sees the export", path: "/openings/export/exp-1", email: "owner@test.com", job: queued, expectedCode: http.StatusOK},
+		{name: "Admin sees the export", path: "/openings/export/exp-1", email: "admin@test.com", roles: []string{auth.RoleAdmin}, job: queued, expectedCode: http.StatusOK},
+		{name: "Other user is forbidden", path: "/openings/export/exp-1", email: "other@test.com", job: queued, expectedCode: http.StatusForbidden},
+		{name: "Unknown export", path: "/openings/export/exp-1", email: "owner@test.com", err: repository.ErrExportJobNotFound, expectedCode: http.StatusNotFound},
+		{name: "Download before it is ready", path: "/openings/export/exp-1/download", email: "owner@test.com", job: queued, expectedCode: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockJobs := new(repository.ExportJobRepositoryMock)
+			mockJobs.On("Get", "exp-1").Return(tt.job, tt.err).Once()
+			exports := service.NewOpeningExportService(nil, mockJobs, t.TempDir(), 1)
+			h := New(nil, nil, WithExports(exports))
+
+			r := gin.Default()
+			r.Use(middleware.Auth())
+			r.GET("/openings/export/:id", h.ShowExportJobHandler)
+			r.GET("/openings/export/:id/download", h.DownloadExportHandler)
+
+			token, _ := auth.GenerateToken(tt.email, tt.roles...)
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			mockJobs.AssertExpectations(t)
+		})
+	}
+}
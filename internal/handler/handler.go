@@ -2,20 +2,83 @@ package handler
 
 import (
 	"log/slog"
+	"opportunities/internal/auth"
 	"opportunities/internal/repository"
+	"opportunities/internal/rules"
 	"opportunities/internal/service"
 )
 
 type OpeningHandler struct {
-	logger     *slog.Logger
-	repo       repository.OpeningRepository
-	csvService *service.OpeningCSVService
+	logger       *slog.Logger
+	repo         repository.OpeningRepository
+	csvService   *service.OpeningCSVService
+	oidc         *auth.OIDCProvider
+	loginLimiter *auth.LoginLimiter
+	sessions     repository.SessionRepository
+	twoFactor    *service.TwoFactorService
+	challenges   *auth.UsedChallenges
+	csvMappings  repository.CSVMappingRepository
+	exports      *service.OpeningExportService
+	rules        *rules.Store
 }
 
-func New(repo repository.OpeningRepository, csvService *service.OpeningCSVService) *OpeningHandler {
-	return &OpeningHandler{
+type Option func(*OpeningHandler)
+
+func WithOIDC(provider *auth.OIDCProvider) Option {
+	return func(h *OpeningHandler) {
+		h.oidc = provider
+	}
+}
+
+func WithLoginLimiter(limiter *auth.LoginLimiter) Option {
+	return func(h *OpeningHandler) {
+		h.loginLimiter = limiter
+	}
+}
+
+func WithSessions(sessions repository.SessionRepository) Option {
+	return func(h *OpeningHandler) {
+		h.sessions = sessions
+	}
+}
+
+func WithTwoFactor(twoFactor *service.TwoFactorService) Option {
+	return func(h *OpeningHandler) {
+		h.twoFactor = twoFactor
+	}
+}
+
+func WithCSVMappings(csvMappings repository.CSVMappingRepository) Option {
+	return func(h *OpeningHandler) {
+		h.csvMappings = csvMappings
+	}
+}
+
+func WithExports(exports *service.OpeningExportService) Option {
+	return func(h *OpeningHandler) {
+		h.exports = exports
+	}
+}
+
+// WithRules applies the opening rules of store to created and updated
+// openings and enables the rules endpoints.
+func WithRules(store *rules.Store) Option {
+	return func(h *OpeningHandler) {
+		h.rules = store
+	}
+}
+
+func New(repo repository.OpeningRepository, csvService *service.OpeningCSVService, opts ...Option) *OpeningHandler {
+	h := &OpeningHandler{
 		logger:     slog.Default().With("group", "handler"),
 		repo:       repo,
 		csvService: csvService,
+		challenges: auth.NewUsedChallenges(),
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
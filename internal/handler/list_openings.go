@@ -2,6 +2,8 @@ package handler
 
 import (
 	"net/http"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,11 +16,24 @@ import (
 // @Tags Openings
 // @Accept json
 // @Produce json
+// @Param mine query bool false "Only openings owned by the caller (requires token)"
 // @Success 200 {object} ListOpeningsResponse
+// @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /openings [get]
 func (h *OpeningHandler) ListOpeningHandler(c *gin.Context) {
-	openings, err := h.repo.List()
+	filter := repository.OpeningFilter{}
+
+	if c.Query("mine") == "true" {
+		claims, ok := middleware.Claims(c)
+		if !ok {
+			sendError(c, http.StatusUnauthorized, "a valid token is required to filter by owner")
+			return
+		}
+		filter.Owner = claims.Email
+	}
+
+	openings, err := h.repo.List(filter)
 	if err != nil {
 		sendError(c, http.StatusInternalServerError, "error getting openings")
 		return
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"log/slog"
+	"math"
 	"net/http"
 	"opportunities/internal/auth"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,9 +23,12 @@ type LoginRequest struct {
 // @Accept json
 // @Produce json
 // @Param request body LoginRequest true "Login credentials"
+// @Description When two-factor authentication is enabled (or required by the user's role)
+// @Description the response carries a challenge_token to be exchanged at /login/2fa.
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
 // @Router /login [post]
 func (h *OpeningHandler) LoginHandler(c *gin.Context) {
 	var req LoginRequest
@@ -30,15 +37,101 @@ func (h *OpeningHandler) LoginHandler(c *gin.Context) {
 		return
 	}
 
-	if req.Email == "admin@admin.com" && req.Password == "123456" {
-		token, err := auth.GenerateToken(req.Email)
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+
+	// The attempt is counted as failed before the password is checked and
+	// refunded on success, so parallel guesses cannot skip the backoff.
+	var reservation *auth.LoginReservation
+	if h.loginLimiter != nil {
+		var retryAfter time.Duration
+		var err error
+		reservation, retryAfter, err = h.loginLimiter.Reserve(ctx, ip, req.Email)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+			h.logger.Error("LoginHandler check attempts", slog.String("error", err.Error()))
+		} else if retryAfter > 0 {
+			sendTooManyAttempts(c, retryAfter)
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"token": token})
+	}
+
+	if req.Email == "admin@admin.com" && req.Password == "123456" {
+		if h.loginLimiter != nil {
+			if err := h.loginLimiter.Refund(ctx, reservation); err != nil {
+				h.logger.Error("LoginHandler reset attempts", slog.String("error", err.Error()))
+			}
+		}
+
+		h.completeLogin(c, req.Email, []string{auth.RoleAdmin})
 		return
 	}
 
 	c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 }
+
+// completeLogin finishes the password step: it issues the session or, when the
+// account needs a second factor, a short-lived challenge token.
+func (h *OpeningHandler) completeLogin(c *gin.Context, email string, roles []string) {
+	if h.twoFactor != nil {
+		purpose, err := h.twoFactorPurpose(email, roles)
+		if err != nil {
+			h.logger.Error("LoginHandler check two-factor", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not complete login"})
+			return
+		}
+
+		if purpose != "" {
+			token, expiresAt, err := auth.GenerateChallengeToken(purpose, email, roles...)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"two_factor_required": true,
+				"enrollment_required": purpose == auth.PurposeTwoFactorEnroll,
+				"challenge_token":     token,
+				"expires_at":          expiresAt,
+			})
+			return
+		}
+	}
+
+	response, err := h.issueSession(c, email, roles)
+	if err != nil {
+		h.logger.Error("LoginHandler issue session", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *OpeningHandler) twoFactorPurpose(email string, roles []string) (string, error) {
+	enabled, err := h.twoFactor.Enabled(email)
+	if err != nil {
+		return "", err
+	}
+	if enabled {
+		return auth.PurposeTwoFactor, nil
+	}
+
+	required, err := h.twoFactor.Required(roles)
+	if err != nil {
+		return "", err
+	}
+	if required {
+		return auth.PurposeTwoFactorEnroll, nil
+	}
+
+	return "", nil
+}
+
+func sendTooManyAttempts(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "too many login attempts",
+		"retry_after": seconds,
+	})
+}
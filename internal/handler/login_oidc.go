@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"opportunities/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCLoginHandler godoc
+// @Summary OIDC login
+// @Description Redirect to the company identity provider (authorization code flow with PKCE)
+// @Tags Auth
+// @Success 302
+// @Failure 503 {object} map[string]string
+// @Router /login/oidc [get]
+func (h *OpeningHandler) OIDCLoginHandler(c *gin.Context) {
+	if h.oidc == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "oidc login is not configured"})
+		return
+	}
+
+	authURL, err := h.oidc.AuthCodeURL()
+	if errors.Is(err, auth.ErrOIDCTooManyLogins) {
+		h.logger.Warn("OIDCLoginHandler pending logins limit reached")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("OIDCLoginHandler build authorization url", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not start oidc login"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallbackHandler godoc
+// @Summary OIDC callback
+// @Description Exchange the authorization code for the API JWT token
+// @Tags Auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "Login state"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /login/oidc/callback [get]
+func (h *OpeningHandler) OIDCCallbackHandler(c *gin.Context) {
+	if h.oidc == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "oidc login is not configured"})
+		return
+	}
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "identity provider error: " + providerErr})
+		return
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "state and code are required"})
+		return
+	}
+
+	identity, err := h.oidc.Exchange(c.Request.Context(), state, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrOIDCInvalidState):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, auth.ErrOIDCNoRoles):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("OIDCCallbackHandler exchange code", slog.String("error", err.Error()))
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "oidc authentication failed"})
+		}
+		return
+	}
+
+	// The provider replaces the password step only; the second factor is
+	// still required like in LoginHandler.
+	h.completeLogin(c, identity.Email, identity.Roles)
+}
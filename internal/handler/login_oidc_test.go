@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"opportunities/internal/auth"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+	"opportunities/internal/service"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOIDCLogin_WithProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	idp, err := auth.NewOIDCServerMock("opportunities-api", jwt.MapClaims{
+		"email":  "recruiter@company.com",
+		"groups": []string{"hr-recruiters"},
+	})
+	if err != nil {
+		t.Fatalf("failed starting oidc mock: %v", err)
+	}
+	defer idp.Close()
+
+	provider, err := auth.NewOIDCProvider(context.Background(), auth.OIDCConfig{
+		IssuerURL:   idp.URL(),
+		ClientID:    idp.ClientID,
+		RedirectURL: "http://localhost:8080/login/oidc/callback",
+		GroupRoles:  map[string]string{"hr-recruiters": auth.RoleRecruiter},
+	}, idp.Server.Client())
+	if err != nil {
+		t.Fatalf("failed creating oidc provider: %v", err)
+	}
+
+	newRouter := func(opts ...Option) *gin.Engine {
+		h := New(new(repository.OpeningRepositoryMock), nil, append([]Option{WithOIDC(provider)}, opts...)...)
+		r := gin.Default()
+		r.GET("/login/oidc", h.OIDCLoginHandler)
+		r.GET("/login/oidc/callback", h.OIDCCallbackHandler)
+		return r
+	}
+
+	login := func(t *testing.T, r *gin.Engine) *httptest.ResponseRecorder {
+		t.Helper()
+
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/login/oidc", nil)
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusFound, recorder.Code)
+
+		client := &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		resp, err := client.Get(recorder.Header().Get("Location"))
+		if err != nil {
+			t.Fatalf("authorize request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		callback, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil {
+			t.Fatalf("invalid callback url: %v", err)
+		}
+
+		recorder = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/login/oidc/callback?"+callback.RawQuery, nil)
+		r.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	r := newRouter()
+
+	t.Run("Should redirect to the provider and issue a token on callback", func(t *testing.T) {
+		recorder := login(t, r)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "token")
+	})
+
+	t.Run("Should return a challenge when the user enrolled 2FA", func(t *testing.T) {
+		secret, _ := auth.GenerateTOTPSecret()
+		repo := new(repository.TwoFactorRepositoryMock)
+		repo.On("Get", "recruiter@company.com").Return(schemas.TwoFactor{
+			Email:   "recruiter@company.com",
+			Secret:  secret,
+			Enabled: true,
+		}, nil)
+
+		recorder := login(t, newRouter(WithTwoFactor(service.NewTwoFactorService(repo, "Opportunities API"))))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		var challenge struct {
+			TwoFactorRequired bool   `json:"two_factor_required"`
+			ChallengeToken    string `json:"challenge_token"`
+			Token             string `json:"token"`
+		}
+		_ = json.Unmarshal(recorder.Body.Bytes(), &challenge)
+		assert.True(t, challenge.TwoFactorRequired)
+		assert.NotEmpty(t, challenge.ChallengeToken)
+		assert.Empty(t, challenge.Token)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Should return 400 for unknown state", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/login/oidc/callback?state=unknown&code=abc", nil)
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Should return 503 when oidc is not configured", func(t *testing.T) {
+		h := New(new(repository.OpeningRepositoryMock), nil)
+		r := gin.Default()
+		r.GET("/login/oidc", h.OIDCLoginHandler)
+
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/login/oidc", nil)
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"opportunities/internal/auth"
+	"opportunities/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginHandler_Throttling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := auth.NewLoginLimiter(auth.NewMemoryLoginAttemptStore(), auth.LoginLimiterConfig{
+		MaxAccountFailures: 2,
+		MaxIPFailures:      10,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Minute,
+		LockoutDuration:    time.Hour,
+		ResetAfter:         time.Hour,
+	})
+	h := New(new(repository.OpeningRepositoryMock), nil, WithLoginLimiter(limiter))
+
+	r := gin.Default()
+	r.POST("/login", h.LoginHandler)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		body := bytes.NewBufferString(`{"email":"admin@admin.com","password":"` + password + `"}`)
+		req, _ := http.NewRequest("POST", "/login", body)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("Should return 401 on the first wrong password", func(t *testing.T) {
+		recorder := login("wrong")
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("Should return 429 with Retry-After while backing off", func(t *testing.T) {
+		recorder := login("123456")
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
+	})
+}
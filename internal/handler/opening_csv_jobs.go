@@ -0,0 +1,333 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+	"opportunities/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// @BasePath /api/v1
+
+// ShowOpeningCSVJobHandler godoc
+// @Summary Show CSV import job
+// @Description Show the status of a CSV import
+// @Tags Opening
+// @Produce json
+// @Param request_id path string true "Import request identification"
+// @Success 200 {object} ShowImportJobResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/csv/{request_id} [get]
+func (h *OpeningHandler) ShowOpeningCSVJobHandler(c *gin.Context) {
+	job, ok := h.loadImportJob(c)
+	if !ok {
+		return
+	}
+
+	sendSuccess(c, "openingCsvJob", job)
+}
+
+// ShowOpeningCSVBatchHandler godoc
+// @Summary Show CSV import batch
+// @Description Show the jobs of the files of a compressed or multi-file upload
+// @Tags Opening
+// @Produce json
+// @Param batch_id path string true "Batch identification"
+// @Success 200 {object} OpeningCSVBatchResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/csv/batches/{batch_id} [get]
+func (h *OpeningHandler) ShowOpeningCSVBatchHandler(c *gin.Context) {
+	if h.csvService == nil {
+		sendError(c, http.StatusServiceUnavailable, "csv service unavailable")
+		return
+	}
+
+	batchID := c.Param("batch_id")
+
+	jobs, err := h.csvService.ListBatch(batchID)
+	if err != nil {
+		if errors.Is(err, service.ErrJobTrackingDisabled) {
+			sendError(c, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+
+		h.logger.Error("ShowOpeningCSVBatchHandler list batch", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error getting csv batch")
+		return
+	}
+
+	if len(jobs) == 0 {
+		sendError(c, http.StatusNotFound, fmt.Sprintf("csv batch %s not found", batchID))
+		return
+	}
+
+	// Every job of a batch belongs to the uploader.
+	claims, _ := middleware.Claims(c)
+	if claims == nil || (!claims.IsAdmin() && jobs[0].Owner != claims.Email) {
+		sendError(c, http.StatusForbidden, "only the uploader or an admin can access this csv batch")
+		return
+	}
+
+	sendSuccess(c, "openingCsvBatch", jobs)
+}
+
+// ListOpeningCSVJobsHandler godoc
+// @Summary List CSV import jobs
+// @Description List the caller's CSV imports, most recent first
+// @Tags Opening
+// @Produce json
+// @Param page query int false "Page (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} ListImportJobsResponse
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/csv [get]
+func (h *OpeningHandler) ListOpeningCSVJobsHandler(c *gin.Context) {
+	if h.csvService == nil {
+		sendError(c, http.StatusServiceUnavailable, "csv service unavailable")
+		return
+	}
+
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	claims, _ := middleware.Claims(c)
+
+	jobs, total, err := h.csvService.ListJobs(claims.Email, page, pageSize)
+	if err != nil {
+		if errors.Is(err, service.ErrJobTrackingDisabled) {
+			sendError(c, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+
+		h.logger.Error("ListOpeningCSVJobsHandler list jobs", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error getting csv jobs")
+		return
+	}
+
+	sendSuccess(c, "openingCsvJobs", importJobPage{
+		Items:    jobs,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	})
+}
+
+// CancelOpeningCSVJobHandler godoc
+// @Summary Cancel CSV import job
+// @Description Drop a queued CSV import or stop a running one, rolling back its rows
+// @Tags Opening
+// @Produce json
+// @Param request_id path string true "Import request identification"
+// @Success 202 {object} OpeningCSVCancelledResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/csv/{request_id} [delete]
+func (h *OpeningHandler) CancelOpeningCSVJobHandler(c *gin.Context) {
+	job, ok := h.loadImportJob(c)
+	if !ok {
+		return
+	}
+
+	running, err := h.csvService.Cancel(job.RequestID)
+	if err != nil {
+		if errors.Is(err, service.ErrCSVJobNotCancelable) {
+			sendError(c, http.StatusConflict, fmt.Sprintf("csv job %s is already %s", job.RequestID, job.Status))
+			return
+		}
+
+		h.logger.Error("CancelOpeningCSVJobHandler cancel job", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error cancelling csv job")
+		return
+	}
+
+	status := schemas.ImportJobCancelled
+	if running {
+		status = "cancelling"
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "openingCsvCancelled",
+		"data": openingCSVCancelledData{
+			RequestID: job.RequestID,
+			Status:    status,
+		},
+	})
+}
+
+// ShowOpeningCSVQueueHandler godoc
+// @Summary Show CSV import queue
+// @Description Show how many CSV imports are waiting and running, overall and for the caller
+// @Tags Opening
+// @Produce json
+// @Success 200 {object} OpeningCSVQueueResponse
+// @Failure 503 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/csv/queue [get]
+func (h *OpeningHandler) ShowOpeningCSVQueueHandler(c *gin.Context) {
+	if h.csvService == nil {
+		sendError(c, http.StatusServiceUnavailable, "csv service unavailable")
+		return
+	}
+
+	claims, _ := middleware.Claims(c)
+
+	total, mine := h.csvService.QueueStats(claims.Email)
+	sendSuccess(c, "openingCsvQueue", openingCSVQueueData{
+		QueueStats: total,
+		Mine: openingCSVQueueOwnerData{
+			Queued: mine.Queued,
+			Active: mine.Active,
+		},
+	})
+}
+
+// ListOpeningCSVJobErrorsHandler godoc
+// @Summary List CSV import row errors
+// @Description List every row error of a CSV import
+// @Tags Opening
+// @Produce json
+// @Param request_id path string true "Import request identification"
+// @Success 200 {object} ListImportRowErrorsResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/csv/{request_id}/errors [get]
+func (h *OpeningHandler) ListOpeningCSVJobErrorsHandler(c *gin.Context) {
+	job, ok := h.loadImportJob(c)
+	if !ok {
+		return
+	}
+
+	rowErrors, err := h.csvService.RowErrors(job.RequestID)
+	if err != nil {
+		h.logger.Error("ListOpeningCSVJobErrorsHandler list row errors", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error getting csv row errors")
+		return
+	}
+
+	sendSuccess(c, "openingCsvJobErrors", rowErrors)
+}
+
+// DownloadOpeningCSVJobErrorsHandler godoc
+// @Summary Download CSV import error report
+// @Description Download the uploaded CSV with an extra "error" column for each failing row
+// @Tags Opening
+// @Produce text/csv
+// @Param request_id path string true "Import request identification"
+// @Success 200 {file} file
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/csv/{request_id}/errors.csv [get]
+func (h *OpeningHandler) DownloadOpeningCSVJobErrorsHandler(c *gin.Context) {
+	job, ok := h.loadImportJob(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.csvService.ErrorReport(job.RequestID)
+	if err != nil {
+		if errors.Is(err, repository.ErrImportJobFileNotFound) {
+			sendError(c, http.StatusNotFound, fmt.Sprintf("csv job %s has no row errors", job.RequestID))
+			return
+		}
+		if errors.Is(err, service.ErrErrorReportFormat) {
+			sendError(c, http.StatusConflict, err.Error())
+			return
+		}
+
+		h.logger.Error("DownloadOpeningCSVJobErrorsHandler build report", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error building csv error report")
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-errors.csv"`, job.RequestID))
+	c.Status(http.StatusOK)
+
+	if err := report.WriteCSV(c.Writer); err != nil {
+		h.logger.Error("DownloadOpeningCSVJobErrorsHandler write report", slog.String("error", err.Error()))
+		if !c.Writer.Written() {
+			sendError(c, http.StatusInternalServerError, "error building csv error report")
+		}
+	}
+}
+
+// loadImportJob fetches the job in the request_id path param and checks that
+// the caller owns it (or is an admin). It writes the error response itself.
+func (h *OpeningHandler) loadImportJob(c *gin.Context) (schemas.ImportJob, bool) {
+	if h.csvService == nil {
+		sendError(c, http.StatusServiceUnavailable, "csv service unavailable")
+		return schemas.ImportJob{}, false
+	}
+
+	requestID := c.Param("request_id")
+
+	job, err := h.csvService.GetJob(requestID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrImportJobNotFound):
+			sendError(c, http.StatusNotFound, fmt.Sprintf("csv job %s not found", requestID))
+		case errors.Is(err, service.ErrJobTrackingDisabled):
+			sendError(c, http.StatusServiceUnavailable, err.Error())
+		default:
+			h.logger.Error("loadImportJob get job", slog.String("error", err.Error()))
+			sendError(c, http.StatusInternalServerError, "error getting csv job")
+		}
+		return schemas.ImportJob{}, false
+	}
+
+	claims, _ := middleware.Claims(c)
+	if claims == nil || (!claims.IsAdmin() && job.Owner != claims.Email) {
+		sendError(c, http.StatusForbidden, "only the uploader or an admin can access this csv job")
+		return schemas.ImportJob{}, false
+	}
+
+	return job, true
+}
+
+func parsePagination(c *gin.Context) (int, int, error) {
+	page := 1
+	pageSize := defaultPageSize
+
+	if raw := c.Query("page"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			return 0, 0, fmt.Errorf("page must be a positive integer")
+		}
+		page = value
+	}
+
+	if raw := c.Query("page_size"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			return 0, 0, fmt.Errorf("page_size must be a positive integer")
+		}
+		pageSize = min(value, maxPageSize)
+	}
+
+	return page, pageSize, nil
+}
@@ -0,0 +1,306 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"opportunities/internal/auth"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+	"opportunities/internal/service"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOpeningCSVJobHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	job := schemas.ImportJob{RequestID: "req-1", Owner: "owner@test.com", Status: schemas.ImportJobSucceeded}
+
+	tests := []struct {
+		name         string
+		path         string
+		email        string
+		roles        []string
+		mockBehavior func(m *repository.ImportJobRepositoryMock)
+		expectedCode int
+	}{
+		{
+			name:  "Owner sees the job",
+			path:  "/opening/csv/req-1",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "req-1").Return(job, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "Admin sees the job",
+			path:  "/opening/csv/req-1",
+			email: "admin@test.com",
+			roles: []string{auth.RoleAdmin},
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "req-1").Return(job, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "Other user is forbidden",
+			path:  "/opening/csv/req-1",
+			email: "other@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "req-1").Return(job, nil).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:  "Unknown job",
+			path:  "/opening/csv/missing",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "missing").Return(schemas.ImportJob{}, repository.ErrImportJobNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:  "List the caller's jobs",
+			path:  "/opening/csv?page=2&page_size=500",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("ListByOwner", "owner@test.com", 2, maxPageSize).Return([]schemas.ImportJob{job}, int64(101), nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "Owner lists the row errors",
+			path:  "/opening/csv/req-1/errors",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "req-1").Return(job, nil).Once()
+				m.On("ListRowErrors", "req-1").Return([]schemas.ImportRowError{{LineNumber: 2, Message: "role is required"}}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "Other user cannot download the report",
+			path:  "/opening/csv/req-1/errors.csv",
+			email: "other@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "req-1").Return(job, nil).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:  "Owner downloads the report",
+			path:  "/opening/csv/req-1/errors.csv",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "req-1").Return(job, nil).Twice()
+				m.On("GetFile", "req-1").Return(schemas.ImportJobFile{Content: []byte("role\n\n")}, nil).Once()
+				m.On("ListRowErrors", "req-1").Return([]schemas.ImportRowError{}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "Report of a job without row errors",
+			path:  "/opening/csv/req-1/errors.csv",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "req-1").Return(job, nil).Twice()
+				m.On("GetFile", "req-1").Return(schemas.ImportJobFile{}, repository.ErrImportJobFileNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:  "Report of a JSON import",
+			path:  "/opening/csv/req-json/errors.csv",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				jsonJob := schemas.ImportJob{RequestID: "req-json", Owner: "owner@test.com", Format: "ndjson", Status: schemas.ImportJobFailed}
+				m.On("Get", "req-json").Return(jsonJob, nil).Twice()
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:  "Owner sees the jobs of a batch",
+			path:  "/opening/csv/batches/batch-1",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("ListByBatch", "batch-1").Return([]schemas.ImportJob{job, job}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "Other user cannot see a batch",
+			path:  "/opening/csv/batches/batch-1",
+			email: "other@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("ListByBatch", "batch-1").Return([]schemas.ImportJob{job}, nil).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:  "Unknown batch",
+			path:  "/opening/csv/batches/missing",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("ListByBatch", "missing").Return([]schemas.ImportJob{}, nil).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Invalid page",
+			path:         "/opening/csv?page=0",
+			email:        "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := new(repository.ImportJobRepositoryMock)
+			tt.mockBehavior(jobs)
+			mockRepo := new(repository.OpeningRepositoryMock)
+			h := New(mockRepo, service.NewOpeningCSVService(mockRepo, nil, 1, service.WithImportJobs(jobs)))
+
+			r := gin.Default()
+			r.Use(middleware.Auth())
+			r.GET("/opening/csv", h.ListOpeningCSVJobsHandler)
+			r.GET("/opening/csv/batches/:batch_id", h.ShowOpeningCSVBatchHandler)
+			r.GET("/opening/csv/:request_id", h.ShowOpeningCSVJobHandler)
+			r.GET("/opening/csv/:request_id/errors", h.ListOpeningCSVJobErrorsHandler)
+			r.GET("/opening/csv/:request_id/errors.csv", h.DownloadOpeningCSVJobErrorsHandler)
+
+			token, _ := auth.GenerateToken(tt.email, tt.roles...)
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			jobs.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCancelOpeningCSVJobHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	queued := schemas.ImportJob{RequestID: "req-1", Owner: "owner@test.com", Status: schemas.ImportJobQueued}
+	finished := schemas.ImportJob{RequestID: "req-2", Owner: "owner@test.com", Status: schemas.ImportJobSucceeded}
+
+	tests := []struct {
+		name         string
+		path         string
+		email        string
+		roles        []string
+		mockBehavior func(m *repository.ImportJobRepositoryMock)
+		expectedCode int
+	}{
+		{
+			name:  "Owner cancels a queued job",
+			path:  "/opening/csv/req-1",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "req-1").Return(queued, nil)
+				m.On("Update", mock.MatchedBy(func(job *schemas.ImportJob) bool {
+					return job.Status == schemas.ImportJobCancelled
+				})).Return(nil).Once()
+			},
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:  "Admin cancels a queued job",
+			path:  "/opening/csv/req-1",
+			email: "admin@test.com",
+			roles: []string{auth.RoleAdmin},
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "req-1").Return(queued, nil)
+				m.On("Update", mock.Anything).Return(nil).Once()
+			},
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:  "Other user is forbidden",
+			path:  "/opening/csv/req-1",
+			email: "other@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "req-1").Return(queued, nil).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:  "Finished job",
+			path:  "/opening/csv/req-2",
+			email: "owner@test.com",
+			mockBehavior: func(m *repository.ImportJobRepositoryMock) {
+				m.On("Get", "req-2").Return(finished, nil).Once()
+			},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := new(repository.ImportJobRepositoryMock)
+			jobs.On("Create", mock.Anything).Return(nil).Once()
+			tt.mockBehavior(jobs)
+			mockRepo := new(repository.OpeningRepositoryMock)
+			csvService := service.NewOpeningCSVService(mockRepo, nil, 1,
+				service.WithImportJobs(jobs), service.WithSpoolDir(t.TempDir()))
+			assert.NoError(t, csvService.Enqueue(service.OpeningCSVJob{RequestID: "req-1", Owner: "owner@test.com"}))
+
+			r := gin.Default()
+			r.Use(middleware.Auth())
+			r.DELETE("/opening/csv/:request_id", New(mockRepo, csvService).CancelOpeningCSVJobHandler)
+
+			token, _ := auth.GenerateToken(tt.email, tt.roles...)
+			req, _ := http.NewRequest("DELETE", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			jobs.AssertExpectations(t)
+		})
+	}
+}
+
+func TestShowOpeningCSVQueueHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	jobs := new(repository.ImportJobRepositoryMock)
+	jobs.On("Create", mock.Anything).Return(nil)
+	mockRepo := new(repository.OpeningRepositoryMock)
+	csvService := service.NewOpeningCSVService(mockRepo, nil, 5,
+		service.WithImportJobs(jobs), service.WithWorkers(3), service.WithSpoolDir(t.TempDir()))
+
+	assert.NoError(t, csvService.Enqueue(service.OpeningCSVJob{RequestID: "req-1", Owner: "owner@test.com"}))
+	assert.NoError(t, csvService.Enqueue(service.OpeningCSVJob{RequestID: "req-2", Owner: "other@test.com"}))
+
+	r := gin.Default()
+	r.Use(middleware.Auth())
+	r.GET("/opening/csv/queue", New(mockRepo, csvService).ShowOpeningCSVQueueHandler)
+
+	token, _ := auth.GenerateToken("owner@test.com")
+	req, _ := http.NewRequest("GET", "/opening/csv/queue", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, req)
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	_ = json.Unmarshal(recorder.Body.Bytes(), &body)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"workers":3,"capacity":5,"queued":2,"active":0,"mine":{"queued":1,"active":0}}`, string(body.Data))
+}
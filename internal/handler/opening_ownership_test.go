@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"opportunities/internal/auth"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOpeningOwnership_UpdateAndDelete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	owned := schemas.Openings{Role: "Go Developer", Owner: "owner@test.com"}
+
+	tests := []struct {
+		name         string
+		method       string
+		email        string
+		roles        []string
+		mockBehavior func(m *repository.OpeningRepositoryMock)
+		expectedCode int
+	}{
+		{
+			name:   "Owner can update",
+			method: "PUT",
+			email:  "owner@test.com",
+			mockBehavior: func(m *repository.OpeningRepositoryMock) {
+				m.On("Get", "1").Return(owned, nil).Once()
+				m.On("Update", mock.AnythingOfType("*schemas.Openings")).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "Other user cannot update",
+			method: "PUT",
+			email:  "other@test.com",
+			mockBehavior: func(m *repository.OpeningRepositoryMock) {
+				m.On("Get", "1").Return(owned, nil).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "Admin can delete",
+			method: "DELETE",
+			email:  "admin@test.com",
+			roles:  []string{auth.RoleAdmin},
+			mockBehavior: func(m *repository.OpeningRepositoryMock) {
+				m.On("Get", "1").Return(owned, nil).Once()
+				m.On("Delete", "1").Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "Other user cannot delete",
+			method: "DELETE",
+			email:  "other@test.com",
+			mockBehavior: func(m *repository.OpeningRepositoryMock) {
+				m.On("Get", "1").Return(owned, nil).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "Openings without owner are admin only",
+			method: "DELETE",
+			email:  "other@test.com",
+			mockBehavior: func(m *repository.OpeningRepositoryMock) {
+				m.On("Get", "1").Return(schemas.Openings{Role: "Legacy"}, nil).Once()
+			},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.OpeningRepositoryMock)
+			tt.mockBehavior(mockRepo)
+			h := New(mockRepo, nil)
+
+			r := gin.Default()
+			r.Use(middleware.Auth())
+			r.PUT("/opening", h.UpdateOpeningHandler)
+			r.DELETE("/opening", h.DeleteOpeningHandler)
+
+			token, _ := auth.GenerateToken(tt.email, tt.roles...)
+			req, _ := http.NewRequest(tt.method, "/opening?id=1", bytes.NewBufferString(`{"role":"Senior Go Developer"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListOpeningHandler_Mine(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Should filter by the caller email", func(t *testing.T) {
+		mockRepo := new(repository.OpeningRepositoryMock)
+		mockRepo.On("List", repository.OpeningFilter{Owner: "owner@test.com"}).Return([]schemas.Openings{}, nil).Once()
+		h := New(mockRepo, nil)
+
+		r := gin.Default()
+		r.Use(middleware.OptionalAuth())
+		r.GET("/openings", h.ListOpeningHandler)
+
+		token, _ := auth.GenerateToken("owner@test.com")
+		req, _ := http.NewRequest("GET", "/openings?mine=true", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Should return 401 without token", func(t *testing.T) {
+		mockRepo := new(repository.OpeningRepositoryMock)
+		h := New(mockRepo, nil)
+
+		r := gin.Default()
+		r.Use(middleware.OptionalAuth())
+		r.GET("/openings", h.ListOpeningHandler)
+
+		req, _ := http.NewRequest("GET", "/openings?mine=true", nil)
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		mockRepo.AssertNotCalled(t, "List", mock.Anything)
+	})
+}
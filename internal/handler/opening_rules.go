@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"opportunities/internal/middleware"
+	"opportunities/internal/rules"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DryRunOpeningRulesRequest checks rows against the rules in effect or, when
+// Rules is set, against a draft of the rules file.
+type DryRunOpeningRulesRequest struct {
+	Rules *rules.File `json:"rules"`
+	// Rows hold field values, such as {"remote": "Home office"}, as they
+	// would appear in a CSV.
+	Rows []map[string]any `json:"rows"`
+}
+
+type openingRulesData struct {
+	Path     string     `json:"path"`
+	LoadedAt time.Time  `json:"loaded_at"`
+	Rules    rules.File `json:"rules"`
+}
+
+type openingRulesDryRunRow struct {
+	Index int `json:"index"`
+	rules.Result
+	Valid bool `json:"valid"`
+}
+
+// ShowOpeningRulesHandler godoc
+// @Summary Show opening rules
+// @Description Show the transform and validation rules in effect
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} OpeningRulesResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/opening-rules [get]
+func (h *OpeningHandler) ShowOpeningRulesHandler(c *gin.Context) {
+	if h.rules == nil {
+		sendError(c, http.StatusServiceUnavailable, "opening rules are not configured")
+		return
+	}
+
+	sendSuccess(c, "openingRules", h.rulesData())
+}
+
+// ReloadOpeningRulesHandler godoc
+// @Summary Reload opening rules
+// @Description Read the rules file again. An invalid file keeps the rules in effect.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} OpeningRulesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/opening-rules/reload [post]
+func (h *OpeningHandler) ReloadOpeningRulesHandler(c *gin.Context) {
+	if h.rules == nil {
+		sendError(c, http.StatusServiceUnavailable, "opening rules are not configured")
+		return
+	}
+
+	if _, err := h.rules.Reload(); err != nil {
+		h.logger.Error("ReloadOpeningRulesHandler reload", slog.String("error", err.Error()))
+		sendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.logger.Info("opening rules reloaded", slog.String("path", h.rules.Path()))
+	sendSuccess(c, "reloadOpeningRules", h.rulesData())
+}
+
+func (h *OpeningHandler) rulesData() openingRulesData {
+	return openingRulesData{
+		Path:     h.rules.Path(),
+		LoadedAt: h.rules.LoadedAt(),
+		Rules:    h.rules.Current().File(),
+	}
+}
+
+// DryRunOpeningRulesHandler godoc
+// @Summary Try opening rules
+// @Description Run the caller's rules, or a draft rules file, on sample rows. Nothing is written.
+// @Tags Opening
+// @Accept json
+// @Produce json
+// @Param request body DryRunOpeningRulesRequest true "Rows and optional draft rules"
+// @Success 200 {object} OpeningRulesDryRunResponse
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/rules/dry-run [post]
+func (h *OpeningHandler) DryRunOpeningRulesHandler(c *gin.Context) {
+	request := DryRunOpeningRulesRequest{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(request.Rows) == 0 {
+		sendError(c, http.StatusBadRequest, errParamIsRequired("rows", "array").Error())
+		return
+	}
+
+	owner := ""
+	if claims, ok := middleware.Claims(c); ok {
+		owner = claims.Email
+	}
+
+	set := h.rules.Current()
+	if request.Rules != nil {
+		draft, err := rules.Compile(*request.Rules)
+		if err != nil {
+			sendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		set = draft
+	}
+	set = set.For(owner)
+
+	rows := make([]openingRulesDryRunRow, 0, len(request.Rows))
+	for i, row := range request.Rows {
+		values := make(map[string]string, len(row))
+		for field, value := range row {
+			values[field] = rowValue(value)
+		}
+
+		result := set.Check(values)
+		rows = append(rows, openingRulesDryRunRow{Index: i, Result: result, Valid: len(result.Errors) == 0})
+	}
+
+	sendSuccess(c, "openingRulesDryRun", rows)
+}
+
+// rowValue writes a JSON value the way it would appear in a CSV cell.
+func rowValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"opportunities/internal/auth"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
+	"opportunities/internal/rules"
+	"opportunities/internal/schemas"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const handlerTestRules = `
+transforms:
+  - field: remote
+    when: { field: location, matches: "(?i)^home office$" }
+    set: "true"
+  - field: company
+    case: title
+validations:
+  - field: link
+    pattern: "^https://"
+    message: link must use https
+  - field: salary
+    min: 3000
+`
+
+func newRulesStore(t *testing.T, content string) (*rules.Store, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+
+	store, err := rules.NewStore(path)
+	if err != nil {
+		t.Fatalf("load rules: %v", err)
+	}
+
+	return store, path
+}
+
+func TestOpeningRules_CreateAndUpdate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, _ := newRulesStore(t, handlerTestRules)
+
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		mockBehavior func(m *repository.OpeningRepositoryMock)
+		expectedCode int
+		message      string
+	}{
+		{
+			name:   "Create fills remote and title-cases the company",
+			method: "POST",
+			body:   `{"role":"Go Dev","company":"acme corp","location":"Home office","link":"https://acme.com","salary":5000}`,
+			mockBehavior: func(m *repository.OpeningRepositoryMock) {
+				m.On("Create", mock.MatchedBy(func(o *schemas.Openings) bool {
+					return o.Remote && o.Company == "Acme Corp"
+				})).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Create rejects a link without https",
+			method:       "POST",
+			body:         `{"role":"Go Dev","company":"acme","location":"BR","remote":false,"link":"http://acme.com","salary":5000}`,
+			mockBehavior: func(m *repository.OpeningRepositoryMock) {},
+			expectedCode: http.StatusBadRequest,
+			message:      "link must use https",
+		},
+		{
+			name:   "Update checks the stored opening",
+			method: "PUT",
+			body:   `{"salary":1000}`,
+			mockBehavior: func(m *repository.OpeningRepositoryMock) {
+				m.On("Get", "1").Return(schemas.Openings{Role: "Go Dev", Company: "Acme", Location: "BR", Link: "https://acme.com", Salary: 5000, Owner: "owner@test.com"}, nil).Once()
+			},
+			expectedCode: http.StatusBadRequest,
+			message:      "salary must be at least 3000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.OpeningRepositoryMock)
+			tt.mockBehavior(mockRepo)
+			h := New(mockRepo, nil, WithRules(store))
+
+			r := gin.Default()
+			r.Use(middleware.Auth())
+			r.POST("/opening", h.CreateOpeningHandler)
+			r.PUT("/opening", h.UpdateOpeningHandler)
+
+			token, _ := auth.GenerateToken("owner@test.com")
+			req, _ := http.NewRequest(tt.method, "/opening?id=1", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			if tt.message != "" {
+				assert.Contains(t, recorder.Body.String(), tt.message)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOpeningRules_DryRunAndReload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, path := newRulesStore(t, handlerTestRules)
+
+	h := New(new(repository.OpeningRepositoryMock), nil, WithRules(store))
+	r := gin.Default()
+	r.Use(middleware.Auth())
+	r.POST("/opening/rules/dry-run", h.DryRunOpeningRulesHandler)
+	r.POST("/admin/opening-rules/reload", middleware.RequireRole(auth.RoleAdmin), h.ReloadOpeningRulesHandler)
+
+	token, _ := auth.GenerateToken("user@test.com")
+	adminToken, _ := auth.GenerateToken("admin@test.com", auth.RoleAdmin)
+
+	send := func(url, token, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	rows := `"rows":[{"role":"Go Dev","company":"acme","location":"home office","link":"https://acme.com","salary":5000},{"role":"QA","company":"acme","remote":false,"link":"http://acme.com","salary":"1000"}]`
+
+	t.Run("Should check rows against the rules in effect", func(t *testing.T) {
+		recorder := send("/opening/rules/dry-run", token, "{"+rows+"}")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		var response OpeningRulesDryRunResponse
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Len(t, response.Data, 2)
+		assert.True(t, response.Data[0].Valid)
+		assert.Equal(t, "true", response.Data[0].Values["remote"])
+		assert.Equal(t, "Acme", response.Data[0].Values["company"])
+		assert.Equal(t, []string{"link must use https", "salary must be at least 3000"}, response.Data[1].Errors)
+	})
+
+	t.Run("Should check rows against draft rules", func(t *testing.T) {
+		recorder := send("/opening/rules/dry-run", token, `{"rules":{"validations":[{"field":"salary","max":2000}]},`+rows+"}")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		var response OpeningRulesDryRunResponse
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, []string{"remote must be a boolean"}, response.Data[0].Errors)
+		assert.True(t, response.Data[1].Valid)
+
+		recorder = send("/opening/rules/dry-run", token, `{"rules":{"validations":[{"field":"salry","max":2000}]},`+rows+"}")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Should answer a malformed body with the error format", func(t *testing.T) {
+		recorder := send("/opening/rules/dry-run", token, `{"rows":`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, recorder.Body.String(), `"errorCode":400`)
+	})
+
+	t.Run("Should apply the shared rules without claims", func(t *testing.T) {
+		r := gin.Default()
+		r.POST("/opening/rules/dry-run", h.DryRunOpeningRulesHandler)
+
+		req, _ := http.NewRequest("POST", "/opening/rules/dry-run", bytes.NewBufferString("{"+rows+"}"))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("Should reload the rules file for admins", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte("validations:\n  - field: salary\n    min: 10000\n"), 0o644))
+
+		assert.Equal(t, http.StatusForbidden, send("/admin/opening-rules/reload", token, "").Code)
+		assert.Equal(t, http.StatusOK, send("/admin/opening-rules/reload", adminToken, "").Code)
+		assert.Len(t, store.Current().File().Validations, 1)
+		assert.Empty(t, store.Current().File().Transforms)
+
+		assert.NoError(t, os.WriteFile(path, []byte("validations: ["), 0o644))
+		assert.Equal(t, http.StatusBadRequest, send("/admin/opening-rules/reload", adminToken, "").Code)
+		assert.Len(t, store.Current().File().Validations, 1)
+	})
+}
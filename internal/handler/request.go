@@ -2,6 +2,7 @@ package handler
 
 import (
 	"fmt"
+	"strconv"
 )
 
 type CreateOpeningRequest struct {
@@ -41,6 +42,55 @@ func (req *CreateOpeningRequest) Validate() error {
 	return nil
 }
 
+// values returns the request as the strings the opening rules work on. A
+// missing remote or salary is empty.
+func (req *CreateOpeningRequest) values() map[string]string {
+	values := map[string]string{
+		"role":     req.Role,
+		"company":  req.Company,
+		"location": req.Location,
+		"remote":   "",
+		"link":     req.Link,
+		"salary":   "",
+	}
+	if req.Remote != nil {
+		values["remote"] = strconv.FormatBool(*req.Remote)
+	}
+	if req.Salary != 0 {
+		values["salary"] = strconv.FormatInt(req.Salary, 10)
+	}
+
+	return values
+}
+
+// setValues stores values rewritten by the opening rules in the request.
+func (req *CreateOpeningRequest) setValues(values map[string]string) error {
+	req.Role = values["role"]
+	req.Company = values["company"]
+	req.Location = values["location"]
+	req.Link = values["link"]
+
+	req.Remote = nil
+	if values["remote"] != "" {
+		remote, err := strconv.ParseBool(values["remote"])
+		if err != nil {
+			return fmt.Errorf("remote must be a boolean")
+		}
+		req.Remote = &remote
+	}
+
+	req.Salary = 0
+	if values["salary"] != "" {
+		salary, err := strconv.ParseInt(values["salary"], 10, 64)
+		if err != nil {
+			return fmt.Errorf("salary must be an integer")
+		}
+		req.Salary = salary
+	}
+
+	return nil
+}
+
 func errParamIsRequired(name, typ string) error {
 	return fmt.Errorf("param: %s (type: %s) is required", name, typ)
 }
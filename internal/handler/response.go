@@ -2,6 +2,8 @@ package handler
 
 import (
 	"fmt"
+	"opportunities/internal/schemas"
+	"opportunities/internal/service"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -29,16 +31,20 @@ type ErrorResponse struct {
 }
 
 type openingResponse struct {
-	ID        uint      `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	DeletedAt time.Time `json:"deleted_at,omitempty"`
-	Role      string    `json:"role"`
-	Company   string    `json:"company"`
-	Location  string    `json:"location"`
-	Remote    bool      `json:"remote"`
-	Link      string    `json:"link"`
-	Salary    int64     `json:"salary"`
+	ID         uint       `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  time.Time  `json:"deleted_at,omitempty"`
+	Role       string     `json:"role"`
+	Company    string     `json:"company"`
+	Location   string     `json:"location"`
+	Remote     bool       `json:"remote"`
+	Link       string     `json:"link"`
+	Salary     int64      `json:"salary"`
+	Owner      string     `json:"owner"`
+	ExternalID string     `json:"external_id,omitempty"`
+	Source     string     `json:"source,omitempty"`
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
 }
 
 type CreateOpeningResponse struct {
@@ -66,9 +72,160 @@ type UpdateOpeningResponse struct {
 type openingCSVAcceptedData struct {
 	RequestID string `json:"request_id"`
 	Status    string `json:"status"`
+	Format    string `json:"format"`
+	Mode      string `json:"mode"`
 }
 
 type OpeningCSVAcceptedResponse struct {
 	Message string                 `json:"message"`
 	Data    openingCSVAcceptedData `json:"data"`
 }
+
+type meData struct {
+	Email     string    `json:"email"`
+	Roles     []string  `json:"roles"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SessionID string    `json:"session_id,omitempty"`
+}
+
+type MeResponse struct {
+	Message string `json:"message"`
+	Data    meData `json:"data"`
+}
+
+type ListSessionsResponse struct {
+	Message string                    `json:"message"`
+	Data    []schemas.SessionResponse `json:"data"`
+}
+
+type RevokeSessionResponse struct {
+	Message string `json:"message"`
+	Data    string `json:"data"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Message string                      `json:"message"`
+	Data    service.TwoFactorEnrollment `json:"data"`
+}
+
+type twoFactorRecoveryCodesData struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorRecoveryCodesResponse struct {
+	Message string                     `json:"message"`
+	Data    twoFactorRecoveryCodesData `json:"data"`
+}
+
+type TwoFactorPoliciesResponse struct {
+	Message string                    `json:"message"`
+	Data    []schemas.TwoFactorPolicy `json:"data"`
+}
+
+type TwoFactorPolicyResponse struct {
+	Message string                  `json:"message"`
+	Data    schemas.TwoFactorPolicy `json:"data"`
+}
+
+type importJobPage struct {
+	Items    []schemas.ImportJob `json:"items"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+	Total    int64               `json:"total"`
+}
+
+type ShowImportJobResponse struct {
+	Message string            `json:"message"`
+	Data    schemas.ImportJob `json:"data"`
+}
+
+type ValidateOpeningCSVResponse struct {
+	Message string                   `json:"message"`
+	Data    service.ValidationReport `json:"data"`
+}
+
+type openingCSVCancelledData struct {
+	RequestID string `json:"request_id"`
+	Status    string `json:"status"`
+}
+
+type OpeningCSVCancelledResponse struct {
+	Message string                  `json:"message"`
+	Data    openingCSVCancelledData `json:"data"`
+}
+
+type openingCSVQueueOwnerData struct {
+	Queued int `json:"queued"`
+	Active int `json:"active"`
+}
+
+type openingCSVQueueData struct {
+	service.QueueStats
+	Mine openingCSVQueueOwnerData `json:"mine"`
+}
+
+type OpeningCSVQueueResponse struct {
+	Message string              `json:"message"`
+	Data    openingCSVQueueData `json:"data"`
+}
+
+type ListImportRowErrorsResponse struct {
+	Message string                   `json:"message"`
+	Data    []schemas.ImportRowError `json:"data"`
+}
+
+type CSVMappingResponse struct {
+	Message string                   `json:"message"`
+	Data    schemas.CSVMappingPreset `json:"data"`
+}
+
+type ListCSVMappingsResponse struct {
+	Message string                     `json:"message"`
+	Data    []schemas.CSVMappingPreset `json:"data"`
+}
+
+type ListImportJobsResponse struct {
+	Message string        `json:"message"`
+	Data    importJobPage `json:"data"`
+}
+
+type ExportJobResponse struct {
+	Message string            `json:"message"`
+	Data    schemas.ExportJob `json:"data"`
+}
+
+type openingCSVBatchItem struct {
+	RequestID string `json:"request_id,omitempty"`
+	FileName  string `json:"file_name"`
+	// Status is accepted, duplicate (RequestID is then the original job) or
+	// rejected, with Error.
+	Status string `json:"status"`
+	Format string `json:"format"`
+	Mode   string `json:"mode"`
+	Error  string `json:"error,omitempty"`
+}
+
+type openingCSVBatchData struct {
+	BatchID string                `json:"batch_id"`
+	Jobs    []openingCSVBatchItem `json:"jobs"`
+}
+
+type OpeningCSVBatchAcceptedResponse struct {
+	Message string              `json:"message"`
+	Data    openingCSVBatchData `json:"data"`
+}
+
+type OpeningCSVBatchResponse struct {
+	Message string              `json:"message"`
+	Data    []schemas.ImportJob `json:"data"`
+}
+
+type OpeningRulesResponse struct {
+	Message string           `json:"message"`
+	Data    openingRulesData `json:"data"`
+}
+
+type OpeningRulesDryRunResponse struct {
+	Message string                  `json:"message"`
+	Data    []openingRulesDryRunRow `json:"data"`
+}
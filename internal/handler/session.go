@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"opportunities/internal/auth"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// issueSession creates a refresh session for a successful login and returns
+// the login response body. Without a session repository it falls back to a
+// plain long-lived token.
+func (h *OpeningHandler) issueSession(c *gin.Context, email string, roles []string) (gin.H, error) {
+	if h.sessions == nil {
+		token, err := auth.GenerateToken(email, roles...)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"token": token}, nil
+	}
+
+	refreshToken, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := schemas.Session{
+		ID:         uuid.NewString(),
+		Email:      email,
+		Roles:      strings.Join(roles, ","),
+		TokenHash:  hash,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		ExpiresAt:  now.Add(auth.RefreshTokenTTL),
+		LastUsedAt: now,
+	}
+
+	if err := h.sessions.Create(&session); err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := auth.GenerateSessionToken(session.ID, email, roles...)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_at":    expiresAt,
+	}, nil
+}
+
+// RefreshTokenHandler godoc
+// @Summary Refresh token
+// @Description Exchange a refresh token for a new access token. The refresh token is rotated.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /login/refresh [post]
+func (h *OpeningHandler) RefreshTokenHandler(c *gin.Context) {
+	if h.sessions == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "sessions are not configured"})
+		return
+	}
+
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	session, err := h.sessions.GetByTokenHash(auth.HashRefreshToken(req.RefreshToken))
+	now := time.Now()
+	if err != nil || session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired refresh token"})
+		return
+	}
+
+	refreshToken, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		return
+	}
+
+	previousHash := session.TokenHash
+	session.TokenHash = hash
+	session.LastUsedAt = now
+	session.IP = c.ClientIP()
+	session.UserAgent = c.Request.UserAgent()
+
+	// Two requests with the same refresh token cannot both rotate it; the
+	// loser is treated as a stolen token and ends the session.
+	err = h.sessions.Rotate(&session, previousHash)
+	if errors.Is(err, repository.ErrRefreshTokenReused) {
+		if err := h.sessions.Revoke(session.ID, session.Email, now); err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+			h.logger.Error("RefreshTokenHandler revoke reused session", slog.String("error", err.Error()))
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("RefreshTokenHandler update session", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not refresh session"})
+		return
+	}
+
+	token, expiresAt, err := auth.GenerateSessionToken(session.ID, session.Email, sessionRoles(session)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_at":    expiresAt,
+	})
+}
+
+// MeHandler godoc
+// @Summary Current user
+// @Description Return the authenticated user and token expiry
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} MeResponse
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /me [get]
+func (h *OpeningHandler) MeHandler(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		sendError(c, http.StatusUnauthorized, "authorization header is required")
+		return
+	}
+
+	data := meData{
+		Email:     claims.Email,
+		Roles:     claims.Roles,
+		SessionID: claims.SessionID,
+	}
+	if claims.ExpiresAt != nil {
+		data.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if data.Roles == nil {
+		data.Roles = []string{}
+	}
+
+	sendSuccess(c, "me", data)
+}
+
+// ListSessionsHandler godoc
+// @Summary List sessions
+// @Description List the active refresh sessions of the authenticated user
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} ListSessionsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /me/sessions [get]
+func (h *OpeningHandler) ListSessionsHandler(c *gin.Context) {
+	if h.sessions == nil {
+		sendError(c, http.StatusServiceUnavailable, "sessions are not configured")
+		return
+	}
+
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		sendError(c, http.StatusUnauthorized, "authorization header is required")
+		return
+	}
+
+	sessions, err := h.sessions.ListActive(claims.Email, time.Now())
+	if err != nil {
+		h.logger.Error("ListSessionsHandler list sessions", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error getting sessions")
+		return
+	}
+
+	response := make([]schemas.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, schemas.SessionResponse{
+			ID:         session.ID,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			Current:    session.ID == claims.SessionID,
+		})
+	}
+
+	sendSuccess(c, "sessions", response)
+}
+
+// RevokeSessionHandler godoc
+// @Summary Revoke session
+// @Description Revoke one of the authenticated user's refresh sessions and the access tokens issued for it
+// @Tags Auth
+// @Produce json
+// @Param id path string true "Session identification"
+// @Success 200 {object} RevokeSessionResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /me/sessions/{id} [delete]
+func (h *OpeningHandler) RevokeSessionHandler(c *gin.Context) {
+	if h.sessions == nil {
+		sendError(c, http.StatusServiceUnavailable, "sessions are not configured")
+		return
+	}
+
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		sendError(c, http.StatusUnauthorized, "authorization header is required")
+		return
+	}
+
+	id := c.Param("id")
+	if err := h.sessions.Revoke(id, claims.Email, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			sendError(c, http.StatusNotFound, "session not found")
+			return
+		}
+
+		h.logger.Error("RevokeSessionHandler revoke session", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error revoking session")
+		return
+	}
+
+	sendSuccess(c, "revokeSession", id)
+}
+
+func sessionRoles(session schemas.Session) []string {
+	if session.Roles == "" {
+		return nil
+	}
+
+	return strings.Split(session.Roles, ",")
+}
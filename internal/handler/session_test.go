@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"opportunities/internal/auth"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := New(new(repository.OpeningRepositoryMock), nil)
+	r := gin.Default()
+	r.Use(middleware.Auth())
+	r.GET("/me", h.MeHandler)
+
+	token, expiresAt, _ := auth.GenerateSessionToken("session-1", "recruiter@test.com", auth.RoleRecruiter)
+	req, _ := http.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, req)
+
+	var body MeResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &body)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "recruiter@test.com", body.Data.Email)
+	assert.Equal(t, []string{auth.RoleRecruiter}, body.Data.Roles)
+	assert.Equal(t, "session-1", body.Data.SessionID)
+	assert.WithinDuration(t, expiresAt, body.Data.ExpiresAt, time.Second)
+}
+
+func TestSessionHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(sessions *repository.SessionRepositoryMock) *gin.Engine {
+		h := New(new(repository.OpeningRepositoryMock), nil, WithSessions(sessions))
+		r := gin.Default()
+		r.POST("/login", h.LoginHandler)
+		r.POST("/login/refresh", h.RefreshTokenHandler)
+
+		protected := r.Group("")
+		protected.Use(middleware.Auth())
+		protected.GET("/me/sessions", h.ListSessionsHandler)
+		protected.DELETE("/me/sessions/:id", h.RevokeSessionHandler)
+		return r
+	}
+
+	t.Run("Should create a session on login", func(t *testing.T) {
+		sessions := new(repository.SessionRepositoryMock)
+		sessions.On("Create", mock.AnythingOfType("*schemas.Session")).Return(nil).Once()
+		r := newRouter(sessions)
+
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"email":"admin@admin.com","password":"123456"}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "refresh_token")
+		sessions.AssertExpectations(t)
+	})
+
+	t.Run("Should rotate the refresh token", func(t *testing.T) {
+		refreshToken, hash, _ := auth.NewRefreshToken()
+		sessions := new(repository.SessionRepositoryMock)
+		sessions.On("GetByTokenHash", hash).Return(schemas.Session{
+			ID:        "session-1",
+			Email:     "admin@admin.com",
+			Roles:     auth.RoleAdmin,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}, nil).Once()
+		sessions.On("Rotate", mock.MatchedBy(func(s *schemas.Session) bool {
+			return s.TokenHash != hash
+		}), hash).Return(nil).Once()
+		r := newRouter(sessions)
+
+		req, _ := http.NewRequest("POST", "/login/refresh", bytes.NewBufferString(`{"refresh_token":"`+refreshToken+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		sessions.AssertExpectations(t)
+	})
+
+	t.Run("Should revoke the session when the refresh token is reused", func(t *testing.T) {
+		refreshToken, hash, _ := auth.NewRefreshToken()
+		sessions := new(repository.SessionRepositoryMock)
+		sessions.On("GetByTokenHash", hash).Return(schemas.Session{
+			ID:        "session-1",
+			Email:     "admin@admin.com",
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}, nil).Once()
+		sessions.On("Rotate", mock.AnythingOfType("*schemas.Session"), hash).Return(repository.ErrRefreshTokenReused).Once()
+		sessions.On("Revoke", "session-1", "admin@admin.com", mock.AnythingOfType("time.Time")).Return(nil).Once()
+		r := newRouter(sessions)
+
+		req, _ := http.NewRequest("POST", "/login/refresh", bytes.NewBufferString(`{"refresh_token":"`+refreshToken+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "refresh token was already used")
+		sessions.AssertExpectations(t)
+	})
+
+	t.Run("Should reject a revoked session", func(t *testing.T) {
+		refreshToken, hash, _ := auth.NewRefreshToken()
+		revokedAt := time.Now()
+		sessions := new(repository.SessionRepositoryMock)
+		sessions.On("GetByTokenHash", hash).Return(schemas.Session{
+			ID:        "session-1",
+			ExpiresAt: time.Now().Add(time.Hour),
+			RevokedAt: &revokedAt,
+		}, nil).Once()
+		r := newRouter(sessions)
+
+		req, _ := http.NewRequest("POST", "/login/refresh", bytes.NewBufferString(`{"refresh_token":"`+refreshToken+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("Should list sessions and flag the current one", func(t *testing.T) {
+		sessions := new(repository.SessionRepositoryMock)
+		sessions.On("ListActive", "admin@admin.com", mock.AnythingOfType("time.Time")).Return([]schemas.Session{
+			{ID: "session-1"},
+			{ID: "session-2"},
+		}, nil).Once()
+		r := newRouter(sessions)
+
+		token, _, _ := auth.GenerateSessionToken("session-2", "admin@admin.com", auth.RoleAdmin)
+		req, _ := http.NewRequest("GET", "/me/sessions", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		var body ListSessionsResponse
+		_ = json.Unmarshal(recorder.Body.Bytes(), &body)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Len(t, body.Data, 2)
+		assert.False(t, body.Data[0].Current)
+		assert.True(t, body.Data[1].Current)
+	})
+
+	t.Run("Should reject access tokens of a revoked session", func(t *testing.T) {
+		sessions := new(repository.SessionRepositoryMock)
+		sessions.On("Revoke", "session-2", "admin@admin.com", mock.AnythingOfType("time.Time")).Return(nil).Once()
+		sessions.On("IsActive", "session-2", mock.AnythingOfType("time.Time")).Return(true, nil).Once()
+		sessions.On("IsActive", "session-2", mock.AnythingOfType("time.Time")).Return(false, nil).Once()
+
+		h := New(new(repository.OpeningRepositoryMock), nil, WithSessions(sessions))
+		r := gin.Default()
+		r.Use(middleware.Auth(middleware.WithSessions(sessions)))
+		r.GET("/me", h.MeHandler)
+		r.DELETE("/me/sessions/:id", h.RevokeSessionHandler)
+
+		token, _, _ := auth.GenerateSessionToken("session-2", "admin@admin.com", auth.RoleAdmin)
+		send := func(method, path string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest(method, path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+			return recorder
+		}
+
+		assert.Equal(t, http.StatusOK, send("DELETE", "/me/sessions/session-2").Code)
+
+		recorder := send("GET", "/me")
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "session has been revoked")
+		sessions.AssertExpectations(t)
+	})
+
+	t.Run("Should return 404 when revoking an unknown session", func(t *testing.T) {
+		sessions := new(repository.SessionRepositoryMock)
+		sessions.On("Revoke", "other", "admin@admin.com", mock.AnythingOfType("time.Time")).Return(repository.ErrSessionNotFound).Once()
+		r := newRouter(sessions)
+
+		token, _, _ := auth.GenerateSessionToken("session-2", "admin@admin.com", auth.RoleAdmin)
+		req, _ := http.NewRequest("DELETE", "/me/sessions/other", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		sessions.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,348 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"opportunities/internal/auth"
+	"opportunities/internal/middleware"
+	"opportunities/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type TwoFactorEnrollRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorPolicyRequest struct {
+	Required *bool `json:"required"`
+}
+
+// TwoFactorLoginHandler godoc
+// @Summary Login second step
+// @Description Exchange the login challenge token and a TOTP or recovery code for a session.
+// @Description For enrollment challenges the code confirms the enrollment and recovery codes are returned.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorLoginRequest true "Challenge and code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /login/2fa [post]
+func (h *OpeningHandler) TwoFactorLoginHandler(c *gin.Context) {
+	if h.twoFactor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "two-factor authentication is not configured"})
+		return
+	}
+
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "challenge_token and code are required"})
+		return
+	}
+
+	claims, err := auth.ValidateChallengeToken(req.ChallengeToken, auth.PurposeTwoFactor, auth.PurposeTwoFactorEnroll)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+
+	// As in LoginHandler, the attempt counts as failed until the code is
+	// accepted.
+	var reservation *auth.LoginReservation
+	if h.loginLimiter != nil {
+		var retryAfter time.Duration
+		reservation, retryAfter, err = h.loginLimiter.Reserve(ctx, ip, claims.Email)
+		if err != nil {
+			h.logger.Error("TwoFactorLoginHandler check attempts", slog.String("error", err.Error()))
+		} else if retryAfter > 0 {
+			sendTooManyAttempts(c, retryAfter)
+			return
+		}
+	}
+
+	var recoveryCodes []string
+	if claims.Purpose == auth.PurposeTwoFactorEnroll {
+		recoveryCodes, err = h.twoFactor.Confirm(claims.Email, req.Code)
+	} else {
+		err = h.twoFactor.Verify(claims.Email, req.Code)
+	}
+
+	// Only a wrong code is a guess; anything else gives the attempt back.
+	if h.loginLimiter != nil && !errors.Is(err, service.ErrInvalidTwoFactorCode) {
+		if err := h.loginLimiter.Refund(ctx, reservation); err != nil {
+			h.logger.Error("TwoFactorLoginHandler reset attempts", slog.String("error", err.Error()))
+		}
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTwoFactorCode):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrTwoFactorNotEnrolled), errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("TwoFactorLoginHandler verify code", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify code"})
+		}
+		return
+	}
+
+	// A challenge is exchanged once; a replay within its lifetime fails even
+	// with another valid code.
+	if !h.challenges.Consume(claims) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "challenge token was already used"})
+		return
+	}
+
+	response, err := h.issueSession(c, claims.Email, claims.Roles)
+	if err != nil {
+		h.logger.Error("TwoFactorLoginHandler issue session", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		return
+	}
+
+	if recoveryCodes != nil {
+		response["recovery_codes"] = recoveryCodes
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// TwoFactorLoginEnrollHandler godoc
+// @Summary Enroll during login
+// @Description Start the mandatory 2FA enrollment using an enrollment challenge token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorEnrollRequest true "Enrollment challenge"
+// @Success 200 {object} service.TwoFactorEnrollment
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /login/2fa/enroll [post]
+func (h *OpeningHandler) TwoFactorLoginEnrollHandler(c *gin.Context) {
+	if h.twoFactor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "two-factor authentication is not configured"})
+		return
+	}
+
+	var req TwoFactorEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ChallengeToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "challenge_token is required"})
+		return
+	}
+
+	claims, err := auth.ValidateChallengeToken(req.ChallengeToken, auth.PurposeTwoFactorEnroll)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := h.twoFactor.Enroll(claims.Email)
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorAlreadyEnabled) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		h.logger.Error("TwoFactorLoginEnrollHandler enroll", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not start enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// EnrollTwoFactorHandler godoc
+// @Summary Enroll 2FA
+// @Description Generate a TOTP secret and provisioning URI (for QR codes). Confirm it at /me/2fa/confirm.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} TwoFactorEnrollmentResponse
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /me/2fa [post]
+func (h *OpeningHandler) EnrollTwoFactorHandler(c *gin.Context) {
+	if h.twoFactor == nil {
+		sendError(c, http.StatusServiceUnavailable, "two-factor authentication is not configured")
+		return
+	}
+
+	claims, _ := middleware.Claims(c)
+
+	enrollment, err := h.twoFactor.Enroll(claims.Email)
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorAlreadyEnabled) {
+			sendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		h.logger.Error("EnrollTwoFactorHandler enroll", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "could not start enrollment")
+		return
+	}
+
+	sendSuccess(c, "enrollTwoFactor", enrollment)
+}
+
+// ConfirmTwoFactorHandler godoc
+// @Summary Confirm 2FA
+// @Description Enable 2FA with the first valid code and return single-use recovery codes
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} TwoFactorRecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /me/2fa/confirm [post]
+func (h *OpeningHandler) ConfirmTwoFactorHandler(c *gin.Context) {
+	if h.twoFactor == nil {
+		sendError(c, http.StatusServiceUnavailable, "two-factor authentication is not configured")
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		sendError(c, http.StatusBadRequest, errParamIsRequired("code", "string").Error())
+		return
+	}
+
+	claims, _ := middleware.Claims(c)
+
+	codes, err := h.twoFactor.Confirm(claims.Email, req.Code)
+	if err != nil {
+		h.sendTwoFactorError(c, "ConfirmTwoFactorHandler", err)
+		return
+	}
+
+	sendSuccess(c, "confirmTwoFactor", gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactorHandler godoc
+// @Summary Disable 2FA
+// @Description Disable 2FA after checking a valid code. Not allowed when the user's role requires 2FA.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "TOTP or recovery code"
+// @Success 200 {object} ErrorResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /me/2fa [delete]
+func (h *OpeningHandler) DisableTwoFactorHandler(c *gin.Context) {
+	if h.twoFactor == nil {
+		sendError(c, http.StatusServiceUnavailable, "two-factor authentication is not configured")
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		sendError(c, http.StatusBadRequest, errParamIsRequired("code", "string").Error())
+		return
+	}
+
+	claims, _ := middleware.Claims(c)
+
+	if err := h.twoFactor.Disable(claims.Email, req.Code, claims.Roles); err != nil {
+		h.sendTwoFactorError(c, "DisableTwoFactorHandler", err)
+		return
+	}
+
+	sendSuccess(c, "disableTwoFactor", claims.Email)
+}
+
+// ListTwoFactorPoliciesHandler godoc
+// @Summary List 2FA policies
+// @Description List the per-role "2FA required" policies
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} TwoFactorPoliciesResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/2fa-policies [get]
+func (h *OpeningHandler) ListTwoFactorPoliciesHandler(c *gin.Context) {
+	if h.twoFactor == nil {
+		sendError(c, http.StatusServiceUnavailable, "two-factor authentication is not configured")
+		return
+	}
+
+	policies, err := h.twoFactor.ListPolicies()
+	if err != nil {
+		h.logger.Error("ListTwoFactorPoliciesHandler list policies", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error getting policies")
+		return
+	}
+
+	sendSuccess(c, "twoFactorPolicies", policies)
+}
+
+// SetTwoFactorPolicyHandler godoc
+// @Summary Set 2FA policy
+// @Description Require (or stop requiring) 2FA for every account with the role
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param role path string true "Role"
+// @Param request body TwoFactorPolicyRequest true "Policy"
+// @Success 200 {object} TwoFactorPolicyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/2fa-policies/{role} [put]
+func (h *OpeningHandler) SetTwoFactorPolicyHandler(c *gin.Context) {
+	if h.twoFactor == nil {
+		sendError(c, http.StatusServiceUnavailable, "two-factor authentication is not configured")
+		return
+	}
+
+	var req TwoFactorPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Required == nil {
+		sendError(c, http.StatusBadRequest, errParamIsRequired("required", "bool").Error())
+		return
+	}
+
+	claims, _ := middleware.Claims(c)
+
+	policy, err := h.twoFactor.SetPolicy(c.Param("role"), *req.Required, claims.Email)
+	if err != nil {
+		h.logger.Error("SetTwoFactorPolicyHandler save policy", slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "error saving policy")
+		return
+	}
+
+	sendSuccess(c, "setTwoFactorPolicy", policy)
+}
+
+func (h *OpeningHandler) sendTwoFactorError(c *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		sendError(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrTwoFactorRequired):
+		sendError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrTwoFactorNotEnrolled), errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		sendError(c, http.StatusBadRequest, err.Error())
+	default:
+		h.logger.Error(op, slog.String("error", err.Error()))
+		sendError(c, http.StatusInternalServerError, "two-factor operation failed")
+	}
+}
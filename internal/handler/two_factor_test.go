@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"opportunities/internal/auth"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+	"opportunities/internal/service"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLoginHandler_TwoFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secret, _ := auth.GenerateTOTPSecret()
+
+	newRouter := func(repo *repository.TwoFactorRepositoryMock) *gin.Engine {
+		h := New(new(repository.OpeningRepositoryMock), nil,
+			WithTwoFactor(service.NewTwoFactorService(repo, "Opportunities API")))
+		r := gin.Default()
+		r.POST("/login", h.LoginHandler)
+		r.POST("/login/2fa", h.TwoFactorLoginHandler)
+		return r
+	}
+
+	post := func(r *gin.Engine, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("Should return a challenge and exchange it for a token", func(t *testing.T) {
+		repo := new(repository.TwoFactorRepositoryMock)
+		repo.On("Get", "admin@admin.com").Return(schemas.TwoFactor{
+			Email:   "admin@admin.com",
+			Secret:  secret,
+			Enabled: true,
+		}, nil)
+		repo.On("Save", mock.AnythingOfType("*schemas.TwoFactor")).Return(nil).Once()
+		r := newRouter(repo)
+
+		recorder := post(r, "/login", `{"email":"admin@admin.com","password":"123456"}`)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		var challenge struct {
+			TwoFactorRequired bool   `json:"two_factor_required"`
+			ChallengeToken    string `json:"challenge_token"`
+			Token             string `json:"token"`
+		}
+		_ = json.Unmarshal(recorder.Body.Bytes(), &challenge)
+		assert.True(t, challenge.TwoFactorRequired)
+		assert.Empty(t, challenge.Token)
+
+		_, err := auth.ValidateToken(challenge.ChallengeToken)
+		assert.Error(t, err, "challenge token must not be accepted as access token")
+
+		code, _ := auth.TOTPCode(secret, time.Now())
+		recorder = post(r, "/login/2fa", `{"challenge_token":"`+challenge.ChallengeToken+`","code":"`+code+`"}`)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "token")
+		repo.AssertExpectations(t)
+
+		// The challenge cannot be exchanged again, not even with a new code.
+		repo.On("Save", mock.AnythingOfType("*schemas.TwoFactor")).Return(nil).Once()
+		next, _ := auth.TOTPCode(secret, time.Now().Add(30*time.Second))
+		recorder = post(r, "/login/2fa", `{"challenge_token":"`+challenge.ChallengeToken+`","code":"`+next+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "already used")
+	})
+
+	t.Run("Should reject an invalid code", func(t *testing.T) {
+		repo := new(repository.TwoFactorRepositoryMock)
+		repo.On("Get", "admin@admin.com").Return(schemas.TwoFactor{
+			Email:   "admin@admin.com",
+			Secret:  secret,
+			Enabled: true,
+		}, nil)
+		r := newRouter(repo)
+
+		challenge, _, _ := auth.GenerateChallengeToken(auth.PurposeTwoFactor, "admin@admin.com", auth.RoleAdmin)
+		recorder := post(r, "/login/2fa", `{"challenge_token":"`+challenge+`","code":"not-a-code"}`)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		repo.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("Should require enrollment when the role policy demands 2FA", func(t *testing.T) {
+		repo := new(repository.TwoFactorRepositoryMock)
+		repo.On("Get", "admin@admin.com").Return(schemas.TwoFactor{}, repository.ErrTwoFactorNotFound)
+		repo.On("ListPolicies").Return([]schemas.TwoFactorPolicy{{Role: auth.RoleAdmin, Required: true}}, nil)
+		r := newRouter(repo)
+
+		recorder := post(r, "/login", `{"email":"admin@admin.com","password":"123456"}`)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"enrollment_required":true`)
+	})
+}
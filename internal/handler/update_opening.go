@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"opportunities/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +21,7 @@ import (
 // @Param opening body UpdateOpeningRequest true "Opening data to Update"
 // @Success 200 {object} UpdateOpeningResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security BearerAuth
@@ -52,6 +54,12 @@ func (h *OpeningHandler) UpdateOpeningHandler(c *gin.Context) {
 		return
 	}
 
+	claims, _ := middleware.Claims(c)
+	if !canModifyOpening(claims, opening) {
+		sendError(c, http.StatusForbidden, "only the opening owner or an admin can update it")
+		return
+	}
+
 	if request.Role != "" {
 		opening.Role = request.Role
 	}
@@ -76,9 +84,17 @@ func (h *OpeningHandler) UpdateOpeningHandler(c *gin.Context) {
 		opening.Salary = request.Salary
 	}
 
+	// The rules check the opening as it will be stored, not only the
+	// fields of the request.
+	if err := h.rules.For(opening.Owner).Apply(&opening); err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	if err := h.repo.Update(&opening); err != nil {
 		h.logger.Error("UpdateOpeningHandler save opening", slog.String("error", err.Error()))
 		sendError(c, http.StatusInternalServerError, err.Error())
+		return
 	}
 
 	sendSuccess(c, "updateOpening", opening)
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"opportunities/internal/middleware"
+	"opportunities/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @BasePath /api/v1
+
+// ValidateOpeningCSVHandler godoc
+// @Summary Validate a CSV without importing it
+// @Description Parse and check a CSV synchronously and return a preview. Nothing is written.
+// @Tags Opening
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV file"
+// @Param upsert formData bool false "Check the rows as an upsert import"
+// @Param mapping formData string false "JSON object mapping source columns to fields"
+// @Param mapping_preset formData string false "Name of a saved mapping preset"
+// @Param reject_unknown_columns formData bool false "Fail on columns that are not mapped to a field"
+// @Param delimiter formData string false "CSV delimiter: , ; | or tab (default: detected)"
+// @Param encoding formData string false "CSV encoding: utf-8, windows-1252 or iso-8859-1 (default: detected)"
+// @Param locale formData string false "How booleans and amounts are written: pt-BR (default) or en-US"
+// @Param preview formData int false "Number of parsed openings to return (default 10, max 100)"
+// @Success 200 {object} ValidateOpeningCSVResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /opening/csv/validate [post]
+func (h *OpeningHandler) ValidateOpeningCSVHandler(c *gin.Context) {
+	if h.csvService == nil {
+		sendError(c, http.StatusServiceUnavailable, "csv service unavailable")
+		return
+	}
+
+	upsert, err := parseFormBool(c, "upsert")
+	if err != nil {
+		sendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	previewSize := 0
+	if raw := c.PostForm("preview"); raw != "" {
+		previewSize, err = strconv.Atoi(raw)
+		if err != nil || previewSize < 0 {
+			sendError(c, http.StatusBadRequest, "preview must be a non-negative integer")
+			return
+		}
+	}
+
+	columns, ok := h.csvParseOptions(c)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		sendError(c, http.StatusBadRequest, "file is required")
+		return
+	}
+
+	maxBytes := h.csvService.ValidateMaxBytes()
+	if fileHeader.Size > maxBytes {
+		sendError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("csv file must be at most %d bytes to validate", maxBytes))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		h.logger.Error("ValidateOpeningCSVHandler open file", slog.String("error", err.Error()))
+		sendError(c, http.StatusBadRequest, "invalid file")
+		return
+	}
+	defer file.Close()
+
+	opts := service.ValidateOptions{
+		Upsert:      upsert,
+		Columns:     columns,
+		PreviewSize: previewSize,
+	}
+	if claims, ok := middleware.Claims(c); ok {
+		opts.Owner = claims.Email
+	}
+
+	report, err := h.csvService.Validate(file, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrCSVTooLarge):
+			sendError(c, http.StatusRequestEntityTooLarge, err.Error())
+		case errors.Is(err, service.ErrInvalidCSV):
+			sendError(c, http.StatusBadRequest, err.Error())
+		default:
+			h.logger.Error("ValidateOpeningCSVHandler validate", slog.String("error", err.Error()))
+			sendError(c, http.StatusInternalServerError, "error validating csv")
+		}
+		return
+	}
+
+	sendSuccess(c, "openingCsvValidation", report)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"opportunities/internal/auth"
+	"opportunities/internal/middleware"
+	"opportunities/internal/repository"
+	"opportunities/internal/service"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOpeningCSVHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		content      string
+		maxBytes     int64
+		expectedCode int
+	}{
+		{
+			name:         "Reports row errors",
+			content:      "role,company,location,remote,link,salary\nGo Dev,Acme,BR,maybe,https://acme.com,1000\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Invalid header",
+			content:      "role,company,location,link,salary\nGo Dev,Acme,BR,https://acme.com,1000\n",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "File too large",
+			content:      "role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,1000\n",
+			maxBytes:     16,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.OpeningRepositoryMock)
+			csvService := service.NewOpeningCSVService(mockRepo, nil, 1, service.WithValidateMaxBytes(tt.maxBytes))
+
+			r := gin.Default()
+			r.Use(middleware.Auth())
+			r.POST("/opening/csv/validate", New(mockRepo, csvService).ValidateOpeningCSVHandler)
+
+			token, _ := auth.GenerateToken("test@test.com")
+			body, contentType := newCSVMultipartBody(t, "file", "openings.csv", tt.content)
+			req, _ := http.NewRequest("POST", "/opening/csv/validate", body)
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			mockRepo.AssertExpectations(t)
+
+			if tt.expectedCode == http.StatusOK {
+				var response ValidateOpeningCSVResponse
+				_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+
+				assert.Equal(t, 1, response.Data.TotalRows)
+				assert.Equal(t, []service.ValidationIssue{{LineNumber: 2, Message: "remote must be a boolean"}}, response.Data.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Package importer defines the parsers of bulk opening uploads. Every format
+// yields the same validated rows, so the import pipeline does not depend on
+// the file format.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	csvutil "opportunities/internal/csv"
+)
+
+// Supported formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+)
+
+var ErrUnsupportedFormat = fmt.Errorf("format must be %q, %q or %q", FormatCSV, FormatNDJSON, FormatJSON)
+
+// Reader returns validated openings one chunk at a time and io.EOF once
+// every record was read. csvutil.OpeningStream is a Reader.
+type Reader interface {
+	ReadChunk(size, workers int) ([]csvutil.ParsedOpening, []csvutil.RowError, error)
+}
+
+// DialectReader is implemented by the readers of delimited text. The dialect
+// is reported in the job result.
+type DialectReader interface {
+	Dialect() csvutil.Dialect
+}
+
+// Parser opens uploads of one format. Open fails when the upload cannot be
+// read at all, for example on an invalid CSV header.
+type Parser interface {
+	Open(r io.Reader, opts csvutil.ParseOptions) (Reader, error)
+}
+
+// Parsers returns the built-in parser of every supported format.
+func Parsers() map[string]Parser {
+	return map[string]Parser{
+		FormatCSV:    csvParser{},
+		FormatNDJSON: jsonParser{},
+		FormatJSON:   jsonParser{},
+	}
+}
+
+// ParseFormat validates the format chosen for an upload. An empty value is
+// guessed from the file name, falling back to CSV.
+func ParseFormat(raw, fileName string) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(raw))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(fileName)) {
+		case ".ndjson", ".jsonl":
+			return FormatNDJSON, nil
+		case ".json":
+			return FormatJSON, nil
+		default:
+			return FormatCSV, nil
+		}
+	}
+
+	switch format {
+	case FormatCSV, FormatNDJSON, FormatJSON:
+		return format, nil
+	case "jsonl":
+		return FormatNDJSON, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+type csvParser struct{}
+
+func (csvParser) Open(r io.Reader, opts csvutil.ParseOptions) (Reader, error) {
+	stream, err := csvutil.NewOpeningStream(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return stream, nil
+}
+
+var errEmptyJSON = errors.New("json file is empty")
@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	csvutil "opportunities/internal/csv"
+)
+
+func readAll(t *testing.T, format, content string, opts csvutil.ParseOptions) ([]csvutil.ParsedOpening, []csvutil.RowError) {
+	t.Helper()
+
+	reader, err := Parsers()[format].Open(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+
+	parsed := make([]csvutil.ParsedOpening, 0)
+	rowErrors := make([]csvutil.RowError, 0)
+	for {
+		chunk, chunkErrors, err := reader.ReadChunk(2, 2)
+		if errors.Is(err, io.EOF) {
+			return parsed, rowErrors
+		}
+		if err != nil {
+			t.Fatalf("unexpected read error: %v", err)
+		}
+
+		parsed = append(parsed, chunk...)
+		rowErrors = append(rowErrors, chunkErrors...)
+	}
+}
+
+func TestJSONParser_NDJSON(t *testing.T) {
+	content := "\n" +
+		`{"role":"Go Dev","company":"Acme","location":"BR","remote":true,"link":"https://acme.com/1","salary":2000,"external_id":42}` + "\n" +
+		`{"Job Title":"Go Dev","company":"Acme","location":"BR","remote":false,"link":"https://acme.com/2","salary":{"amount":3000,"currency":"BRL"}}` + "\n" +
+		"\n" +
+		`{"role":"Go Dev","company":"Acme","location":"BR","remote":"true","link":"https://acme.com/3","salary":2000}` + "\n" +
+		`{"role":"Go Dev",` + "\n" +
+		`{"role":"Go Dev","company":"Acme","location":"BR","remote":true,"link":"https://acme.com/5","salary":20.5}`
+
+	parsed, rowErrors := readAll(t, FormatNDJSON, content, csvutil.ParseOptions{Mapping: csvutil.ColumnMapping{"job title": "role"}})
+
+	if len(parsed) != 2 {
+		t.Fatalf("expected 2 parsed openings, got %d", len(parsed))
+	}
+	if parsed[0].LineNumber != 2 || parsed[0].Opening.ExternalID != "42" || !parsed[0].Opening.Remote {
+		t.Fatalf("unexpected first opening: %+v", parsed[0])
+	}
+	if parsed[1].LineNumber != 3 || parsed[1].Opening.Role != "Go Dev" || parsed[1].Opening.Salary != 3000 {
+		t.Fatalf("expected mapped role and nested salary, got %+v", parsed[1])
+	}
+
+	expected := []csvutil.RowError{
+		{LineNumber: 5, Message: "remote must be a boolean"},
+		{LineNumber: 6, Message: "invalid json object"},
+		{LineNumber: 7, Message: "salary must be an integer"},
+	}
+	if len(rowErrors) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, rowErrors)
+	}
+	for i := range expected {
+		if rowErrors[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, rowErrors)
+		}
+	}
+}
+
+func TestJSONParser_NDJSONOversizedLine(t *testing.T) {
+	content := `{"role":"` + strings.Repeat("a", maxJSONLineSize) + `"}` + "\n" +
+		`{"role":"Go Dev","company":"Acme","location":"BR","remote":true,"link":"https://acme.com/1","salary":2000}`
+
+	parsed, rowErrors := readAll(t, FormatNDJSON, content, csvutil.ParseOptions{})
+
+	if len(parsed) != 1 || parsed[0].LineNumber != 2 {
+		t.Fatalf("expected the line after the oversized one to be read, got %+v", parsed)
+	}
+	expected := csvutil.RowError{LineNumber: 1, Message: fmt.Sprintf("line exceeds %d bytes", maxJSONLineSize)}
+	if len(rowErrors) != 1 || rowErrors[0] != expected {
+		t.Fatalf("expected %v, got %v", expected, rowErrors)
+	}
+}
+
+func TestJSONParser_Array(t *testing.T) {
+	content := "\xef\xbb\xbf[\n" +
+		`{"role":"Go Dev","company":"Acme","location":"BR","remote":true,"link":"https://acme.com/1","salary":2000},` + "\n" +
+		`{"role":"","company":"Acme","location":"BR","remote":true,"link":"https://acme.com/2","salary":2000}` + "\n" +
+		"]"
+
+	parsed, rowErrors := readAll(t, FormatJSON, content, csvutil.ParseOptions{})
+
+	if len(parsed) != 1 || parsed[0].LineNumber != 1 {
+		t.Fatalf("expected the first element to be parsed, got %+v", parsed)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].LineNumber != 2 || rowErrors[0].Message != "role is required" {
+		t.Fatalf("expected role error on element 2, got %+v", rowErrors)
+	}
+}
+
+func TestJSONParser_RejectsInvalidInput(t *testing.T) {
+	for name, content := range map[string]string{
+		"empty":  "  \n",
+		"scalar": "42",
+	} {
+		if _, err := Parsers()[FormatJSON].Open(strings.NewReader(content), csvutil.ParseOptions{}); err == nil {
+			t.Fatalf("%s: expected open error", name)
+		}
+	}
+
+	reader, err := Parsers()[FormatJSON].Open(strings.NewReader(`[{"role":"Go Dev"} {"role"`), csvutil.ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+	if _, _, err := reader.ReadChunk(10, 1); err == nil || errors.Is(err, io.EOF) {
+		t.Fatalf("expected malformed array to stop the read, got %v", err)
+	}
+
+	reader, _ = Parsers()[FormatNDJSON].Open(strings.NewReader(`{"role":"Go Dev","extra":1}`), csvutil.ParseOptions{RejectUnknownColumns: true})
+	_, rowErrors, _ := reader.ReadChunk(10, 1)
+	if len(rowErrors) != 1 || rowErrors[0].Message != `unknown field "extra"` {
+		t.Fatalf("expected unknown field error, got %+v", rowErrors)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, tt := range []struct {
+		raw, fileName, expected string
+	}{
+		{"", "openings.csv", FormatCSV},
+		{"", "openings.jsonl", FormatNDJSON},
+		{"", "openings.JSON", FormatJSON},
+		{"", "openings", FormatCSV},
+		{"NDJSON", "openings.csv", FormatNDJSON},
+	} {
+		format, err := ParseFormat(tt.raw, tt.fileName)
+		if err != nil || format != tt.expected {
+			t.Fatalf("ParseFormat(%q, %q) = %q, %v", tt.raw, tt.fileName, format, err)
+		}
+	}
+
+	if _, err := ParseFormat("xml", "openings.xml"); err != ErrUnsupportedFormat {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
@@ -0,0 +1,389 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	csvutil "opportunities/internal/csv"
+	"opportunities/internal/schemas"
+)
+
+// maxJSONLineSize bounds one NDJSON line, so a file without line breaks is
+// not read into memory at once.
+const maxJSONLineSize = 1 << 20
+
+// jsonParser reads either a JSON array of openings or one opening object per
+// line (NDJSON). Row numbers are the line in NDJSON and the position of the
+// element in an array.
+type jsonParser struct{}
+
+func (jsonParser) Open(r io.Reader, opts csvutil.ParseOptions) (Reader, error) {
+	input := bufio.NewReader(r)
+
+	first, skipped, err := firstNonSpace(input)
+	if errors.Is(err, io.EOF) {
+		return nil, errEmptyJSON
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid json format: %w", err)
+	}
+
+	reader := &jsonReader{opts: opts, maxLine: maxJSONLineSize}
+
+	switch first {
+	case '[':
+		reader.decoder = json.NewDecoder(input)
+		if _, err := reader.decoder.Token(); err != nil {
+			return nil, fmt.Errorf("invalid json format: %w", err)
+		}
+	case '{':
+		reader.lines = input
+		reader.line = skipped
+	default:
+		return nil, fmt.Errorf("invalid json format: expected an array or one object per line")
+	}
+
+	return reader, nil
+}
+
+// firstNonSpace skips a UTF-8 BOM and leading blank space, and returns the
+// first byte without consuming it along with the number of skipped lines.
+func firstNonSpace(r *bufio.Reader) (byte, int, error) {
+	if bom, _ := r.Peek(3); bytes.Equal(bom, []byte{0xef, 0xbb, 0xbf}) {
+		r.Discard(3)
+	}
+
+	lines := 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, lines, err
+		}
+
+		switch b {
+		case '\n':
+			lines++
+		case ' ', '\t', '\r':
+		default:
+			return b, lines, r.UnreadByte()
+		}
+	}
+}
+
+type jsonRecord struct {
+	number int
+	raw    []byte
+	// err is set for a line that could not be read, such as one over the
+	// size limit.
+	err error
+}
+
+// jsonReader is an array decoder or an NDJSON line reader.
+type jsonReader struct {
+	decoder *json.Decoder
+	lines   *bufio.Reader
+	line    int
+	maxLine int
+	opts    csvutil.ParseOptions
+}
+
+func (r *jsonReader) ReadChunk(size, workers int) ([]csvutil.ParsedOpening, []csvutil.RowError, error) {
+	records := make([]jsonRecord, 0, size)
+	for len(records) < size {
+		record, err := r.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	if len(records) == 0 {
+		return nil, nil, io.EOF
+	}
+
+	results := make([]chunkResult, len(records))
+	workers = max(1, min(workers, len(records)))
+
+	wg := sync.WaitGroup{}
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			for i := offset; i < len(records); i += workers {
+				if records[i].err != nil {
+					results[i].err = records[i].err
+					continue
+				}
+				results[i].opening, results[i].err = parseJSONOpening(records[i].raw, r.opts)
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	parsed := make([]csvutil.ParsedOpening, 0, len(records))
+	rowErrors := make([]csvutil.RowError, 0)
+	for i, result := range results {
+		if result.err != nil {
+			rowErrors = append(rowErrors, csvutil.RowError{LineNumber: records[i].number, Message: result.err.Error()})
+			continue
+		}
+
+		parsed = append(parsed, csvutil.ParsedOpening{LineNumber: records[i].number, Opening: result.opening})
+	}
+
+	return parsed, rowErrors, nil
+}
+
+type chunkResult struct {
+	opening schemas.Openings
+	err     error
+}
+
+// next returns the next raw object. A malformed array cannot be resumed, so
+// its errors end the read; a malformed or oversized NDJSON line is only a row
+// error.
+func (r *jsonReader) next() (jsonRecord, error) {
+	if r.decoder != nil {
+		if !r.decoder.More() {
+			return jsonRecord{}, io.EOF
+		}
+
+		var raw json.RawMessage
+		if err := r.decoder.Decode(&raw); err != nil {
+			return jsonRecord{}, fmt.Errorf("invalid json format: %w", err)
+		}
+
+		r.line++
+		return jsonRecord{number: r.line, raw: raw}, nil
+	}
+
+	for {
+		line, tooLong, err := r.readLine()
+		if len(line) == 0 && !tooLong && errors.Is(err, io.EOF) {
+			return jsonRecord{}, io.EOF
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return jsonRecord{}, fmt.Errorf("invalid json format: %w", err)
+		}
+
+		r.line++
+		if tooLong {
+			return jsonRecord{number: r.line, err: fmt.Errorf("line exceeds %d bytes", r.maxLine)}, nil
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			return jsonRecord{number: r.line, raw: line}, nil
+		}
+	}
+}
+
+// readLine reads up to the next line break. Past maxLine bytes the rest of
+// the line is discarded and tooLong is reported instead.
+func (r *jsonReader) readLine() ([]byte, bool, error) {
+	var line []byte
+	tooLong := false
+	for {
+		fragment, err := r.lines.ReadSlice('\n')
+		if !tooLong {
+			if len(line)+len(fragment) > r.maxLine {
+				line, tooLong = nil, true
+			} else {
+				line = append(line, fragment...)
+			}
+		}
+
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, tooLong, err
+		}
+	}
+}
+
+// jsonSalary accepts a plain number or an object such as
+// {"amount": 5000, "currency": "BRL"}.
+type jsonSalary struct {
+	Amount json.Number `json:"amount"`
+}
+
+func parseJSONOpening(raw []byte, opts csvutil.ParseOptions) (schemas.Openings, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return schemas.Openings{}, fmt.Errorf("invalid json object")
+	}
+
+	fields := make(map[string]json.RawMessage, len(object))
+	for key, value := range object {
+		field, ok := opts.Field(key)
+		if !ok {
+			if opts.RejectUnknownColumns {
+				return schemas.Openings{}, fmt.Errorf("unknown field %q", key)
+			}
+			continue
+		}
+
+		if _, duplicated := fields[field]; duplicated {
+			return schemas.Openings{}, fmt.Errorf("field %q appears more than once", field)
+		}
+		fields[field] = value
+	}
+
+	if opts.Rules != nil {
+		applyJSONTransforms(fields, opts.Rules)
+	}
+
+	var opening schemas.Openings
+	for _, text := range []struct {
+		field  string
+		target *string
+	}{
+		{"role", &opening.Role},
+		{"company", &opening.Company},
+		{"location", &opening.Location},
+		{"link", &opening.Link},
+	} {
+		value, err := jsonString(fields[text.field])
+		if err != nil {
+			return schemas.Openings{}, fmt.Errorf("%s must be a string", text.field)
+		}
+		if value == "" {
+			return schemas.Openings{}, fmt.Errorf("%s is required", text.field)
+		}
+		*text.target = value
+	}
+
+	if raw, ok := fields["external_id"]; ok {
+		var number json.Number
+		if err := json.Unmarshal(raw, &number); err == nil {
+			opening.ExternalID = number.String()
+		} else if opening.ExternalID, err = jsonString(raw); err != nil {
+			return schemas.Openings{}, fmt.Errorf("external_id must be a string or a number")
+		}
+	}
+
+	if err := json.Unmarshal(fields["remote"], &opening.Remote); err != nil || fields["remote"] == nil {
+		return schemas.Openings{}, fmt.Errorf("remote must be a boolean")
+	}
+
+	salary, err := jsonInteger(fields["salary"])
+	if err != nil {
+		return schemas.Openings{}, fmt.Errorf("salary must be an integer")
+	}
+	if salary <= 0 {
+		return schemas.Openings{}, fmt.Errorf("salary must be greater than zero")
+	}
+	opening.Salary = salary
+
+	if opts.Rules != nil {
+		if err := opts.Rules.Validate(opening); err != nil {
+			return schemas.Openings{}, err
+		}
+	}
+
+	return opening, nil
+}
+
+// applyJSONTransforms runs the transforms of the rules on the fields as the
+// text a CSV would hold. Rewritten fields are encoded again, so the typed
+// parsing that follows reads them like any other value.
+func applyJSONTransforms(fields map[string]json.RawMessage, rules csvutil.RowRules) {
+	original := make(map[string]string, len(csvutil.Header()))
+	for _, field := range csvutil.Header() {
+		original[field] = jsonText(fields[field])
+	}
+	if salary, err := jsonInteger(fields["salary"]); err == nil {
+		original["salary"] = strconv.FormatInt(salary, 10)
+	}
+
+	values := make(map[string]string, len(original))
+	for field, value := range original {
+		values[field] = value
+	}
+	rules.Transform(values)
+
+	for field, value := range values {
+		if value == original[field] {
+			continue
+		}
+
+		encoded, _ := json.Marshal(value)
+		switch field {
+		case "remote":
+			if remote, err := strconv.ParseBool(value); err == nil {
+				encoded = []byte(strconv.FormatBool(remote))
+			}
+		case "salary":
+			if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+				encoded = []byte(value)
+			}
+		}
+		fields[field] = encoded
+	}
+}
+
+// jsonText returns a JSON scalar as text: strings as they are, numbers and
+// booleans as written. Missing and null values are empty.
+func jsonText(raw json.RawMessage) string {
+	if raw == nil {
+		return ""
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return string(raw)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return string(raw)
+	}
+}
+
+func jsonString(raw json.RawMessage) (string, error) {
+	if raw == nil || string(raw) == "null" {
+		return "", nil
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(value), nil
+}
+
+func jsonInteger(raw json.RawMessage) (int64, error) {
+	if raw == nil {
+		return 0, errors.New("missing")
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err != nil {
+		var nested jsonSalary
+		if err := json.Unmarshal(raw, &nested); err != nil || nested.Amount == "" {
+			return 0, errors.New("not a number")
+		}
+		number = nested.Amount
+	}
+
+	return strconv.ParseInt(number.String(), 10, 64)
+}
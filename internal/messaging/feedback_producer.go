@@ -3,18 +3,41 @@ package messaging
 import (
 	"context"
 	"time"
+
+	"opportunities/internal/schemas"
 )
 
 type OpeningCSVFeedback struct {
 	RequestID      string    `json:"request_id"`
+	Format         string    `json:"format,omitempty"`
 	Status         string    `json:"status"`
 	TotalRows      int       `json:"total_rows"`
 	ProcessedRows  int       `json:"processed_rows"`
+	InsertedRows   int       `json:"inserted_rows"`
+	UpdatedRows    int       `json:"updated_rows"`
+	UnchangedRows  int       `json:"unchanged_rows"`
+	ClosedRows     int       `json:"closed_rows"`
+	SkippedRows    int       `json:"skipped_rows"`
 	DurationMS     int64     `json:"duration_ms"`
 	ErrorCount     int       `json:"error_count"`
 	FirstErrorLine int       `json:"first_error_line"`
 	Message        string    `json:"message"`
 	Timestamp      time.Time `json:"timestamp"`
+
+	// Delimiter, Encoding and BOM describe how a CSV upload was read.
+	Delimiter string `json:"delimiter,omitempty"`
+	Encoding  string `json:"encoding,omitempty"`
+	BOM       bool   `json:"bom,omitempty"`
+
+	// NormalizedValues and Normalizations report the values the locale rules
+	// rewrote, such as "sim" read as true.
+	NormalizedValues int                           `json:"normalized_values,omitempty"`
+	Normalizations   []schemas.ImportNormalization `json:"normalizations,omitempty"`
+
+	// Phase and ETAMS are only set on "running" progress feedback. ETAMS is
+	// zero while the remaining time cannot be estimated.
+	Phase string `json:"phase,omitempty"`
+	ETAMS int64  `json:"eta_ms,omitempty"`
 }
 
 type FeedbackProducer interface {
@@ -92,6 +92,11 @@ func (p *KafkaFeedbackProducer) PublishOpeningCSVFeedback(ctx context.Context, f
 	return lastErr
 }
 
+// Close flushes the pending messages and closes the writer.
+func (p *KafkaFeedbackProducer) Close() error {
+	return p.writer.Close()
+}
+
 func (p *KafkaFeedbackProducer) ensureTopic(ctx context.Context, partitions int, replicationFactor int) error {
 	if len(p.brokers) == 0 {
 		return fmt.Errorf("no kafka brokers configured")
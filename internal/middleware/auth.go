@@ -3,11 +3,55 @@ package middleware
 import (
 	"net/http"
 	"opportunities/internal/auth"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Auth() gin.HandlerFunc {
+const claimsKey = "auth_claims"
+
+// SessionStore tells whether the refresh session behind an access token is
+// still active.
+type SessionStore interface {
+	IsActive(id string, now time.Time) (bool, error)
+}
+
+type authConfig struct {
+	sessions SessionStore
+}
+
+type AuthOption func(*authConfig)
+
+// WithSessions rejects access tokens whose session was revoked or expired,
+// instead of accepting them until they expire on their own.
+func WithSessions(sessions SessionStore) AuthOption {
+	return func(cfg *authConfig) {
+		cfg.sessions = sessions
+	}
+}
+
+func newAuthConfig(opts []AuthOption) authConfig {
+	cfg := authConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// activeSession checks the session of tokens bound to one. Tokens without a
+// session, and every token when no store is set, pass.
+func (cfg authConfig) activeSession(claims *auth.Claims) (bool, error) {
+	if cfg.sessions == nil || claims.SessionID == "" {
+		return true, nil
+	}
+
+	return cfg.sessions.IsActive(claims.SessionID, time.Now())
+}
+
+func Auth(opts ...AuthOption) gin.HandlerFunc {
+	cfg := newAuthConfig(opts)
+
 	return func(c *gin.Context) {
 		header := c.GetHeader("Authorization")
 
@@ -16,11 +60,66 @@ func Auth() gin.HandlerFunc {
 			return
 		}
 
-		if err := auth.ValidateToken(header); err != nil {
+		claims, err := auth.ValidateToken(header)
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
 
+		active, err := cfg.activeSession(claims)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not check session"})
+			return
+		}
+		if !active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+			return
+		}
+
+		c.Set(claimsKey, claims)
+		c.Next()
+	}
+}
+
+// OptionalAuth stores the caller claims when a valid token is sent, but lets
+// anonymous requests through. Used on public routes that behave differently
+// for authenticated callers.
+func OptionalAuth(opts ...AuthOption) gin.HandlerFunc {
+	cfg := newAuthConfig(opts)
+
+	return func(c *gin.Context) {
+		if header := c.GetHeader("Authorization"); header != "" {
+			if claims, err := auth.ValidateToken(header); err == nil {
+				if active, err := cfg.activeSession(claims); err == nil && active {
+					c.Set(claimsKey, claims)
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// Claims returns the claims stored by Auth or OptionalAuth.
+func Claims(c *gin.Context) (*auth.Claims, bool) {
+	value, ok := c.Get(claimsKey)
+	if !ok {
+		return nil, false
+	}
+
+	claims, ok := value.(*auth.Claims)
+	return claims, ok
+}
+
+// RequireRole must run after Auth and rejects callers without the role.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := Claims(c)
+		if !ok || !claims.HasRole(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"opportunities/internal/schemas"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type CSVMappingRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *CSVMappingRepositoryMock) Get(owner, name string) (schemas.CSVMappingPreset, error) {
+	args := m.Called(owner, name)
+	return args.Get(0).(schemas.CSVMappingPreset), args.Error(1)
+}
+
+func (m *CSVMappingRepositoryMock) List(owner string) ([]schemas.CSVMappingPreset, error) {
+	args := m.Called(owner)
+	return args.Get(0).([]schemas.CSVMappingPreset), args.Error(1)
+}
+
+func (m *CSVMappingRepositoryMock) Save(preset *schemas.CSVMappingPreset) error {
+	args := m.Called(preset)
+	return args.Error(0)
+}
+
+func (m *CSVMappingRepositoryMock) Delete(owner, name string) error {
+	args := m.Called(owner, name)
+	return args.Error(0)
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+
+	"opportunities/internal/schemas"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrMappingPresetNotFound = errors.New("csv mapping preset not found")
+
+type CSVMappingRepository interface {
+	Get(owner, name string) (schemas.CSVMappingPreset, error)
+	List(owner string) ([]schemas.CSVMappingPreset, error)
+	Save(preset *schemas.CSVMappingPreset) error
+	Delete(owner, name string) error
+}
+
+type sqliteCSVMappingRepository struct {
+	db *gorm.DB
+}
+
+func NewCSVMappingRepository(db *gorm.DB) CSVMappingRepository {
+	return &sqliteCSVMappingRepository{db: db}
+}
+
+func (r *sqliteCSVMappingRepository) Get(owner, name string) (schemas.CSVMappingPreset, error) {
+	var preset schemas.CSVMappingPreset
+	err := r.db.Where("owner = ? AND name = ?", owner, name).First(&preset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return schemas.CSVMappingPreset{}, ErrMappingPresetNotFound
+	}
+
+	return preset, err
+}
+
+func (r *sqliteCSVMappingRepository) List(owner string) ([]schemas.CSVMappingPreset, error) {
+	var presets []schemas.CSVMappingPreset
+	if err := r.db.Where("owner = ?", owner).Order("name").Find(&presets).Error; err != nil {
+		return nil, err
+	}
+
+	return presets, nil
+}
+
+// Save creates the preset or replaces the mapping of the owner's preset with
+// the same name.
+func (r *sqliteCSVMappingRepository) Save(preset *schemas.CSVMappingPreset) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "owner"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mapping", "updated_at"}),
+	}).Create(preset).Error
+}
+
+func (r *sqliteCSVMappingRepository) Delete(owner, name string) error {
+	result := r.db.Where("owner = ? AND name = ?", owner, name).Delete(&schemas.CSVMappingPreset{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMappingPresetNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"time"
+
+	"opportunities/internal/schemas"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type ExportJobRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ExportJobRepositoryMock) Create(job *schemas.ExportJob) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *ExportJobRepositoryMock) Get(id string) (schemas.ExportJob, error) {
+	args := m.Called(id)
+	return args.Get(0).(schemas.ExportJob), args.Error(1)
+}
+
+func (m *ExportJobRepositoryMock) Update(job *schemas.ExportJob) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *ExportJobRepositoryMock) ListUnfinished() ([]schemas.ExportJob, error) {
+	args := m.Called()
+	return args.Get(0).([]schemas.ExportJob), args.Error(1)
+}
+
+func (m *ExportJobRepositoryMock) ListFilesFinishedBefore(before time.Time) ([]schemas.ExportJob, error) {
+	args := m.Called(before)
+	return args.Get(0).([]schemas.ExportJob), args.Error(1)
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"opportunities/internal/schemas"
+
+	"gorm.io/gorm"
+)
+
+var ErrExportJobNotFound = errors.New("export job not found")
+
+type ExportJobRepository interface {
+	Create(job *schemas.ExportJob) error
+	Get(id string) (schemas.ExportJob, error)
+	Update(job *schemas.ExportJob) error
+	ListUnfinished() ([]schemas.ExportJob, error)
+	ListFilesFinishedBefore(before time.Time) ([]schemas.ExportJob, error)
+}
+
+type sqliteExportJobRepository struct {
+	db *gorm.DB
+}
+
+func NewExportJobRepository(db *gorm.DB) ExportJobRepository {
+	return &sqliteExportJobRepository{db: db}
+}
+
+func (r *sqliteExportJobRepository) Create(job *schemas.ExportJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *sqliteExportJobRepository) Get(id string) (schemas.ExportJob, error) {
+	var job schemas.ExportJob
+	err := r.db.Where("id = ?", id).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return schemas.ExportJob{}, ErrExportJobNotFound
+	}
+
+	return job, err
+}
+
+func (r *sqliteExportJobRepository) Update(job *schemas.ExportJob) error {
+	return r.db.Save(job).Error
+}
+
+// ListUnfinished returns the queued and running exports, oldest first.
+func (r *sqliteExportJobRepository) ListUnfinished() ([]schemas.ExportJob, error) {
+	var jobs []schemas.ExportJob
+	err := r.db.
+		Where("status IN ?", []string{schemas.ImportJobQueued, schemas.ImportJobRunning}).
+		Order("created_at, id").
+		Find(&jobs).Error
+
+	return jobs, err
+}
+
+// ListFilesFinishedBefore returns the exports that still have a file and
+// finished before the given time.
+func (r *sqliteExportJobRepository) ListFilesFinishedBefore(before time.Time) ([]schemas.ExportJob, error) {
+	var jobs []schemas.ExportJob
+	err := r.db.
+		Where("path <> '' AND finished_at < ?", before).
+		Order("finished_at, id").
+		Find(&jobs).Error
+
+	return jobs, err
+}
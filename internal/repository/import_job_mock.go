@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"time"
+
+	"opportunities/internal/schemas"
+
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type ImportJobRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ImportJobRepositoryMock) Create(job *schemas.ImportJob) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *ImportJobRepositoryMock) Get(requestID string) (schemas.ImportJob, error) {
+	args := m.Called(requestID)
+	return args.Get(0).(schemas.ImportJob), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) Update(job *schemas.ImportJob) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *ImportJobRepositoryMock) UpdateWithTx(tx *gorm.DB, job *schemas.ImportJob) error {
+	args := m.Called(tx, job)
+	return args.Error(0)
+}
+
+func (m *ImportJobRepositoryMock) ListUnfinished() ([]schemas.ImportJob, error) {
+	args := m.Called()
+	return args.Get(0).([]schemas.ImportJob), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) FindByIdempotencyKey(owner, key string, since time.Time) (schemas.ImportJob, error) {
+	args := m.Called(owner, key, since)
+	return args.Get(0).(schemas.ImportJob), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) FindByContentHash(owner, hash string, since time.Time) (schemas.ImportJob, error) {
+	args := m.Called(owner, hash, since)
+	return args.Get(0).(schemas.ImportJob), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) ListByOwner(owner string, page, pageSize int) ([]schemas.ImportJob, int64, error) {
+	args := m.Called(owner, page, pageSize)
+	return args.Get(0).([]schemas.ImportJob), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *ImportJobRepositoryMock) ListByBatch(batchID string) ([]schemas.ImportJob, error) {
+	args := m.Called(batchID)
+	return args.Get(0).([]schemas.ImportJob), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) SaveFailure(file *schemas.ImportJobFile, rowErrors []schemas.ImportRowError) error {
+	args := m.Called(file, rowErrors)
+	return args.Error(0)
+}
+
+func (m *ImportJobRepositoryMock) AppendRowErrors(tx *gorm.DB, rowErrors []schemas.ImportRowError) error {
+	args := m.Called(tx, rowErrors)
+	return args.Error(0)
+}
+
+func (m *ImportJobRepositoryMock) DeleteRowErrors(requestID string) error {
+	args := m.Called(requestID)
+	return args.Error(0)
+}
+
+func (m *ImportJobRepositoryMock) ListRowErrors(requestID string) ([]schemas.ImportRowError, error) {
+	args := m.Called(requestID)
+	return args.Get(0).([]schemas.ImportRowError), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) GetFile(requestID string) (schemas.ImportJobFile, error) {
+	args := m.Called(requestID)
+	return args.Get(0).(schemas.ImportJobFile), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) ListFilesFinishedBefore(before time.Time) ([]schemas.ImportJobFile, error) {
+	args := m.Called(before)
+	return args.Get(0).([]schemas.ImportJobFile), args.Error(1)
+}
+
+func (m *ImportJobRepositoryMock) DeleteFile(requestID string) error {
+	args := m.Called(requestID)
+	return args.Error(0)
+}
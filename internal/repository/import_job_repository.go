@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"opportunities/internal/schemas"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrImportJobNotFound     = errors.New("import job not found")
+	ErrImportJobFileNotFound = errors.New("import job file not found")
+)
+
+type ImportJobRepository interface {
+	Create(job *schemas.ImportJob) error
+	Get(requestID string) (schemas.ImportJob, error)
+	Update(job *schemas.ImportJob) error
+	UpdateWithTx(tx *gorm.DB, job *schemas.ImportJob) error
+	ListUnfinished() ([]schemas.ImportJob, error)
+	FindByIdempotencyKey(owner, key string, since time.Time) (schemas.ImportJob, error)
+	FindByContentHash(owner, hash string, since time.Time) (schemas.ImportJob, error)
+	ListByOwner(owner string, page, pageSize int) ([]schemas.ImportJob, int64, error)
+	ListByBatch(batchID string) ([]schemas.ImportJob, error)
+	SaveFailure(file *schemas.ImportJobFile, rowErrors []schemas.ImportRowError) error
+	AppendRowErrors(tx *gorm.DB, rowErrors []schemas.ImportRowError) error
+	DeleteRowErrors(requestID string) error
+	ListRowErrors(requestID string) ([]schemas.ImportRowError, error)
+	GetFile(requestID string) (schemas.ImportJobFile, error)
+	ListFilesFinishedBefore(before time.Time) ([]schemas.ImportJobFile, error)
+	DeleteFile(requestID string) error
+}
+
+type sqliteImportJobRepository struct {
+	db *gorm.DB
+}
+
+func NewImportJobRepository(db *gorm.DB) ImportJobRepository {
+	return &sqliteImportJobRepository{db: db}
+}
+
+func (r *sqliteImportJobRepository) Create(job *schemas.ImportJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *sqliteImportJobRepository) Get(requestID string) (schemas.ImportJob, error) {
+	var job schemas.ImportJob
+	err := r.db.Where("request_id = ?", requestID).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return schemas.ImportJob{}, ErrImportJobNotFound
+	}
+
+	return job, err
+}
+
+func (r *sqliteImportJobRepository) Update(job *schemas.ImportJob) error {
+	return r.db.Save(job).Error
+}
+
+// UpdateWithTx stores the job in the import transaction, so its final status
+// is committed together with the imported rows.
+func (r *sqliteImportJobRepository) UpdateWithTx(tx *gorm.DB, job *schemas.ImportJob) error {
+	return tx.Save(job).Error
+}
+
+// ListUnfinished returns the queued and running jobs, oldest first.
+func (r *sqliteImportJobRepository) ListUnfinished() ([]schemas.ImportJob, error) {
+	var jobs []schemas.ImportJob
+	err := r.db.
+		Where("status IN ?", []string{schemas.ImportJobQueued, schemas.ImportJobRunning}).
+		Order("queued_at, request_id").
+		Find(&jobs).Error
+
+	return jobs, err
+}
+
+// FindByIdempotencyKey returns the newest job of owner queued since the given
+// time with the key.
+func (r *sqliteImportJobRepository) FindByIdempotencyKey(owner, key string, since time.Time) (schemas.ImportJob, error) {
+	return r.findRecent(r.db.Where("idempotency_key = ?", key), owner, since)
+}
+
+// FindByContentHash returns the newest job of owner queued since the given
+// time with the same content. Failed and cancelled jobs are left out, so the
+// same file can be sent again after fixing the upload options.
+func (r *sqliteImportJobRepository) FindByContentHash(owner, hash string, since time.Time) (schemas.ImportJob, error) {
+	query := r.db.
+		Where("content_hash = ?", hash).
+		Where("status NOT IN ?", []string{schemas.ImportJobFailed, schemas.ImportJobCancelled})
+
+	return r.findRecent(query, owner, since)
+}
+
+func (r *sqliteImportJobRepository) findRecent(query *gorm.DB, owner string, since time.Time) (schemas.ImportJob, error) {
+	var job schemas.ImportJob
+	err := query.
+		Where("owner = ? AND queued_at >= ?", owner, since).
+		Order("queued_at DESC").
+		First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return schemas.ImportJob{}, ErrImportJobNotFound
+	}
+
+	return job, err
+}
+
+func (r *sqliteImportJobRepository) ListByOwner(owner string, page, pageSize int) ([]schemas.ImportJob, int64, error) {
+	query := r.db.Model(&schemas.ImportJob{}).Where("owner = ?", owner)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []schemas.ImportJob
+	err := query.
+		Order("queued_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return jobs, total, nil
+}
+
+// ListByBatch returns the jobs of a batch in the order they were queued.
+func (r *sqliteImportJobRepository) ListByBatch(batchID string) ([]schemas.ImportJob, error) {
+	var jobs []schemas.ImportJob
+	err := r.db.Where("batch_id = ?", batchID).Order("queued_at, request_id").Find(&jobs).Error
+
+	return jobs, err
+}
+
+// SaveFailure stores the uploaded file and every row error of a failed job in
+// a single transaction.
+func (r *sqliteImportJobRepository) SaveFailure(file *schemas.ImportJobFile, rowErrors []schemas.ImportRowError) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(file).Error; err != nil {
+			return err
+		}
+
+		if len(rowErrors) == 0 {
+			return nil
+		}
+
+		return tx.CreateInBatches(rowErrors, 100).Error
+	})
+}
+
+// AppendRowErrors stores a batch of row errors in tx, or directly when tx is
+// nil.
+func (r *sqliteImportJobRepository) AppendRowErrors(tx *gorm.DB, rowErrors []schemas.ImportRowError) error {
+	if len(rowErrors) == 0 {
+		return nil
+	}
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.CreateInBatches(rowErrors, 100).Error
+}
+
+// DeleteRowErrors removes the row errors of a job, so a job run again after an
+// interruption does not list them twice.
+func (r *sqliteImportJobRepository) DeleteRowErrors(requestID string) error {
+	return r.db.Where("request_id = ?", requestID).Delete(&schemas.ImportRowError{}).Error
+}
+
+func (r *sqliteImportJobRepository) ListRowErrors(requestID string) ([]schemas.ImportRowError, error) {
+	var rowErrors []schemas.ImportRowError
+	err := r.db.
+		Where("request_id = ?", requestID).
+		Order("line_number, id").
+		Find(&rowErrors).Error
+
+	return rowErrors, err
+}
+
+func (r *sqliteImportJobRepository) GetFile(requestID string) (schemas.ImportJobFile, error) {
+	var file schemas.ImportJobFile
+	err := r.db.Where("request_id = ?", requestID).First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return schemas.ImportJobFile{}, ErrImportJobFileNotFound
+	}
+
+	return file, err
+}
+
+// ListFilesFinishedBefore returns the stored files of the jobs that finished
+// before the given time.
+func (r *sqliteImportJobRepository) ListFilesFinishedBefore(before time.Time) ([]schemas.ImportJobFile, error) {
+	var files []schemas.ImportJobFile
+	err := r.db.
+		Select("import_job_files.*").
+		Joins("JOIN import_jobs ON import_jobs.request_id = import_job_files.request_id").
+		Where("import_jobs.finished_at < ?", before).
+		Order("import_job_files.created_at").
+		Find(&files).Error
+
+	return files, err
+}
+
+// DeleteFile drops the stored file of a job and clears its spool path. The
+// row errors stay.
+func (r *sqliteImportJobRepository) DeleteFile(requestID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("request_id = ?", requestID).Delete(&schemas.ImportJobFile{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&schemas.ImportJob{}).
+			Where("request_id = ?", requestID).
+			Update("spool_path", "").Error
+	})
+}
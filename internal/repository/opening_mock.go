@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"opportunities/internal/schemas"
 
 	"github.com/stretchr/testify/mock"
@@ -21,6 +23,11 @@ func (m *OpeningRepositoryMock) CreateWithTx(tx *gorm.DB, opening *schemas.Openi
 	return args.Error(0)
 }
 
+func (m *OpeningRepositoryMock) CreateBatchWithTx(tx *gorm.DB, openings []schemas.Openings) error {
+	args := m.Called(tx, openings)
+	return args.Error(0)
+}
+
 func (m *OpeningRepositoryMock) BeginTx() (*gorm.DB, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
@@ -45,7 +52,37 @@ func (m *OpeningRepositoryMock) Update(opening *schemas.Openings) error {
 	return args.Error(0)
 }
 
-func (m *OpeningRepositoryMock) List() ([]schemas.Openings, error) {
-	args := m.Called()
+func (m *OpeningRepositoryMock) List(filter OpeningFilter) ([]schemas.Openings, error) {
+	args := m.Called(filter)
 	return args.Get(0).([]schemas.Openings), args.Error(1)
 }
+
+func (m *OpeningRepositoryMock) ListInBatches(filter OpeningFilter, batchSize int, fn func([]schemas.Openings) error) error {
+	args := m.Called(filter, batchSize, fn)
+	if batch, ok := args.Get(0).([]schemas.Openings); ok && len(batch) > 0 {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *OpeningRepositoryMock) UpdateWithTx(tx *gorm.DB, opening *schemas.Openings) error {
+	args := m.Called(tx, opening)
+	return args.Error(0)
+}
+
+func (m *OpeningRepositoryMock) FindImportMatchWithTx(tx *gorm.DB, owner, externalID, link string) (schemas.Openings, bool, error) {
+	args := m.Called(tx, owner, externalID, link)
+	return args.Get(0).(schemas.Openings), args.Bool(1), args.Error(2)
+}
+
+func (m *OpeningRepositoryMock) MarkImportRunWithTx(tx *gorm.DB, id uint, run string) error {
+	args := m.Called(tx, id, run)
+	return args.Error(0)
+}
+
+func (m *OpeningRepositoryMock) CloseMissingWithTx(tx *gorm.DB, owner, source, run string, closedAt time.Time) (int64, error) {
+	args := m.Called(tx, owner, source, run, closedAt)
+	return args.Get(0).(int64), args.Error(1)
+}
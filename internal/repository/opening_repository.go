@@ -1,19 +1,33 @@
 package repository
 
 import (
+	"errors"
+	"time"
+
 	"opportunities/internal/schemas"
 
 	"gorm.io/gorm"
 )
 
+// OpeningFilter narrows List results. Zero values are ignored.
+type OpeningFilter struct {
+	Owner string
+}
+
 type OpeningRepository interface {
 	Create(opening *schemas.Openings) error
 	CreateWithTx(tx *gorm.DB, opening *schemas.Openings) error
+	CreateBatchWithTx(tx *gorm.DB, openings []schemas.Openings) error
 	BeginTx() (*gorm.DB, error)
 	Get(id string) (schemas.Openings, error)
 	Delete(id string) error
 	Update(opening *schemas.Openings) error
-	List() ([]schemas.Openings, error)
+	List(filter OpeningFilter) ([]schemas.Openings, error)
+	ListInBatches(filter OpeningFilter, batchSize int, fn func([]schemas.Openings) error) error
+	UpdateWithTx(tx *gorm.DB, opening *schemas.Openings) error
+	FindImportMatchWithTx(tx *gorm.DB, owner, externalID, link string) (schemas.Openings, bool, error)
+	MarkImportRunWithTx(tx *gorm.DB, id uint, run string) error
+	CloseMissingWithTx(tx *gorm.DB, owner, source, run string, closedAt time.Time) (int64, error)
 }
 
 type sqliteRepository struct {
@@ -32,6 +46,10 @@ func (r *sqliteRepository) CreateWithTx(tx *gorm.DB, opening *schemas.Openings)
 	return tx.Create(opening).Error
 }
 
+func (r *sqliteRepository) CreateBatchWithTx(tx *gorm.DB, openings []schemas.Openings) error {
+	return tx.CreateInBatches(openings, len(openings)).Error
+}
+
 func (r *sqliteRepository) BeginTx() (*gorm.DB, error) {
 	tx := r.db.Begin()
 	if tx.Error != nil {
@@ -57,10 +75,80 @@ func (r *sqliteRepository) Update(opening *schemas.Openings) error {
 	return r.db.Save(opening).Error
 }
 
-func (r *sqliteRepository) List() ([]schemas.Openings, error) {
+func (r *sqliteRepository) List(filter OpeningFilter) ([]schemas.Openings, error) {
+	query := r.db
+	if filter.Owner != "" {
+		query = query.Where("owner = ?", filter.Owner)
+	}
+
 	var openings []schemas.Openings
-	if err := r.db.Find(&openings).Error; err != nil {
+	if err := query.Find(&openings).Error; err != nil {
 		return nil, err
 	}
 	return openings, nil
 }
+
+// ListInBatches calls fn with the openings matching filter, batchSize at a
+// time and ordered by ID, so callers can stream them with constant memory.
+func (r *sqliteRepository) ListInBatches(filter OpeningFilter, batchSize int, fn func([]schemas.Openings) error) error {
+	query := r.db.Order("id")
+	if filter.Owner != "" {
+		query = query.Where("owner = ?", filter.Owner)
+	}
+
+	var batch []schemas.Openings
+	return query.FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+func (r *sqliteRepository) UpdateWithTx(tx *gorm.DB, opening *schemas.Openings) error {
+	return tx.Save(opening).Error
+}
+
+// FindImportMatchWithTx looks for the owner's opening with the given external
+// ID. When there is none it falls back to the link, ignoring openings that
+// already carry a different external ID.
+func (r *sqliteRepository) FindImportMatchWithTx(tx *gorm.DB, owner, externalID, link string) (schemas.Openings, bool, error) {
+	var opening schemas.Openings
+
+	if externalID != "" {
+		err := tx.Where("owner = ? AND external_id = ?", owner, externalID).First(&opening).Error
+		if err == nil {
+			return opening, true, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return schemas.Openings{}, false, err
+		}
+	}
+
+	err := tx.
+		Where("owner = ? AND link = ?", owner, link).
+		Where("external_id = '' OR external_id IS NULL OR external_id = ?", externalID).
+		Order("id").
+		First(&opening).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return schemas.Openings{}, false, nil
+	}
+	if err != nil {
+		return schemas.Openings{}, false, err
+	}
+
+	return opening, true, nil
+}
+
+// MarkImportRunWithTx stamps an opening the import left unchanged with the
+// run, without touching its other columns or its UpdatedAt.
+func (r *sqliteRepository) MarkImportRunWithTx(tx *gorm.DB, id uint, run string) error {
+	return tx.Model(&schemas.Openings{}).Where("id = ?", id).UpdateColumn("import_run", run).Error
+}
+
+// CloseMissingWithTx closes the owner's open openings from the source that
+// were not stamped by the run and returns how many were closed.
+func (r *sqliteRepository) CloseMissingWithTx(tx *gorm.DB, owner, source, run string, closedAt time.Time) (int64, error) {
+	result := tx.Model(&schemas.Openings{}).
+		Where("owner = ? AND source = ? AND closed_at IS NULL", owner, source).
+		Where("import_run IS NULL OR import_run <> ?", run).
+		Update("closed_at", closedAt)
+	return result.RowsAffected, result.Error
+}
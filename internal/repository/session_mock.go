@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"time"
+
+	"opportunities/internal/schemas"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type SessionRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *SessionRepositoryMock) Create(session *schemas.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) GetByTokenHash(hash string) (schemas.Session, error) {
+	args := m.Called(hash)
+	return args.Get(0).(schemas.Session), args.Error(1)
+}
+
+func (m *SessionRepositoryMock) Update(session *schemas.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) Rotate(session *schemas.Session, previousHash string) error {
+	args := m.Called(session, previousHash)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) ListActive(email string, now time.Time) ([]schemas.Session, error) {
+	args := m.Called(email, now)
+	return args.Get(0).([]schemas.Session), args.Error(1)
+}
+
+func (m *SessionRepositoryMock) Revoke(id, email string, at time.Time) error {
+	args := m.Called(id, email, at)
+	return args.Error(0)
+}
+
+func (m *SessionRepositoryMock) IsActive(id string, now time.Time) (bool, error) {
+	args := m.Called(id, now)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"opportunities/internal/schemas"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
+)
+
+type SessionRepository interface {
+	Create(session *schemas.Session) error
+	GetByTokenHash(hash string) (schemas.Session, error)
+	Update(session *schemas.Session) error
+	Rotate(session *schemas.Session, previousHash string) error
+	ListActive(email string, now time.Time) ([]schemas.Session, error)
+	Revoke(id, email string, at time.Time) error
+	IsActive(id string, now time.Time) (bool, error)
+}
+
+type sqliteSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sqliteSessionRepository{db: db}
+}
+
+func (r *sqliteSessionRepository) Create(session *schemas.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sqliteSessionRepository) GetByTokenHash(hash string) (schemas.Session, error) {
+	var session schemas.Session
+	err := r.db.Where("token_hash = ?", hash).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return schemas.Session{}, ErrSessionNotFound
+	}
+
+	return session, err
+}
+
+func (r *sqliteSessionRepository) Update(session *schemas.Session) error {
+	return r.db.Save(session).Error
+}
+
+// Rotate stores the new token hash and usage of the session only while it
+// still holds previousHash and is not revoked. When another request rotated
+// it first, it returns ErrRefreshTokenReused.
+func (r *sqliteSessionRepository) Rotate(session *schemas.Session, previousHash string) error {
+	result := r.db.Model(&schemas.Session{}).
+		Where("id = ? AND token_hash = ? AND revoked_at IS NULL", session.ID, previousHash).
+		Updates(map[string]any{
+			"token_hash":   session.TokenHash,
+			"last_used_at": session.LastUsedAt,
+			"ip":           session.IP,
+			"user_agent":   session.UserAgent,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	return nil
+}
+
+func (r *sqliteSessionRepository) ListActive(email string, now time.Time) ([]schemas.Session, error) {
+	var sessions []schemas.Session
+	err := r.db.
+		Where("email = ? AND revoked_at IS NULL AND expires_at > ?", email, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (r *sqliteSessionRepository) Revoke(id, email string, at time.Time) error {
+	result := r.db.Model(&schemas.Session{}).
+		Where("id = ? AND email = ? AND revoked_at IS NULL", id, email).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// IsActive reports whether the session exists and is neither revoked nor
+// expired.
+func (r *sqliteSessionRepository) IsActive(id string, now time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&schemas.Session{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, now).
+		Count(&count).Error
+
+	return count > 0, err
+}
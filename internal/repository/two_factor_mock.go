@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"opportunities/internal/schemas"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type TwoFactorRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *TwoFactorRepositoryMock) Get(email string) (schemas.TwoFactor, error) {
+	args := m.Called(email)
+	return args.Get(0).(schemas.TwoFactor), args.Error(1)
+}
+
+func (m *TwoFactorRepositoryMock) Save(twoFactor *schemas.TwoFactor) error {
+	args := m.Called(twoFactor)
+	return args.Error(0)
+}
+
+func (m *TwoFactorRepositoryMock) Delete(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *TwoFactorRepositoryMock) ListPolicies() ([]schemas.TwoFactorPolicy, error) {
+	args := m.Called()
+	return args.Get(0).([]schemas.TwoFactorPolicy), args.Error(1)
+}
+
+func (m *TwoFactorRepositoryMock) SavePolicy(policy *schemas.TwoFactorPolicy) error {
+	args := m.Called(policy)
+	return args.Error(0)
+}
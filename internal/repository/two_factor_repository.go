@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+
+	"opportunities/internal/schemas"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrTwoFactorNotFound = errors.New("two-factor enrollment not found")
+
+type TwoFactorRepository interface {
+	Get(email string) (schemas.TwoFactor, error)
+	Save(twoFactor *schemas.TwoFactor) error
+	Delete(email string) error
+	ListPolicies() ([]schemas.TwoFactorPolicy, error)
+	SavePolicy(policy *schemas.TwoFactorPolicy) error
+}
+
+type sqliteTwoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &sqliteTwoFactorRepository{db: db}
+}
+
+func (r *sqliteTwoFactorRepository) Get(email string) (schemas.TwoFactor, error) {
+	var twoFactor schemas.TwoFactor
+	err := r.db.Where("email = ?", email).First(&twoFactor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return schemas.TwoFactor{}, ErrTwoFactorNotFound
+	}
+
+	return twoFactor, err
+}
+
+func (r *sqliteTwoFactorRepository) Save(twoFactor *schemas.TwoFactor) error {
+	return r.db.Save(twoFactor).Error
+}
+
+func (r *sqliteTwoFactorRepository) Delete(email string) error {
+	return r.db.Where("email = ?", email).Delete(&schemas.TwoFactor{}).Error
+}
+
+func (r *sqliteTwoFactorRepository) ListPolicies() ([]schemas.TwoFactorPolicy, error) {
+	var policies []schemas.TwoFactorPolicy
+	if err := r.db.Order("role").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+func (r *sqliteTwoFactorRepository) SavePolicy(policy *schemas.TwoFactorPolicy) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(policy).Error
+}
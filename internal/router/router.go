@@ -1,20 +1,29 @@
 package router
 
 import (
+	"fmt"
+
+	"opportunities/internal/handler"
 	"opportunities/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func Initialize(db *gorm.DB, csvService *service.OpeningCSVService) {
+// New builds the engine with every route. It is served by an http.Server, so
+// the caller controls the shutdown. Only trustedProxies may set the client IP
+// through X-Forwarded-For; with none, it is always the peer address, so a
+// client cannot pick the IP the login throttle counts.
+func New(db *gorm.DB, csvService *service.OpeningCSVService, trustedProxies []string, opts ...handler.Option) (*gin.Engine, error) {
 	router := gin.Default()
+	// Multipart files above this size are buffered on disk instead of memory.
+	router.MaxMultipartMemory = 1 << 20
 
-	initializeRoutes(router, db, csvService)
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
-	err := router.Run(":8080")
+	initializeRoutes(router, db, csvService, opts...)
 
-	if err != nil {
-		panic(err)
-	}
+	return router, nil
 }
@@ -0,0 +1,58 @@
+package router
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"opportunities/internal/auth"
+	"opportunities/internal/handler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestNew_IgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed opening test db: %v", err)
+	}
+
+	limiter := auth.NewLoginLimiter(auth.NewMemoryLoginAttemptStore(), auth.LoginLimiterConfig{
+		BaseDelay:  time.Minute,
+		MaxDelay:   time.Minute,
+		ResetAfter: time.Hour,
+	})
+
+	engine, err := New(db, nil, nil, handler.WithLoginLimiter(limiter))
+	if err != nil {
+		t.Fatalf("unexpected router error: %v", err)
+	}
+
+	login := func(email, forwardedFor string) int {
+		req, _ := http.NewRequest("POST", "/api/v1/login", bytes.NewBufferString(`{"email":"`+email+`","password":"wrong"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if code := login("first@test.com", "198.51.100.1"); code != http.StatusUnauthorized {
+		t.Fatalf("expected the first failure to be 401, got %d", code)
+	}
+
+	// Another account and another forwarded IP still come from the same peer.
+	if code := login("second@test.com", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the peer IP to stay throttled, got %d", code)
+	}
+
+	if _, err := New(db, nil, []string{"not-an-ip"}); err == nil {
+		t.Fatalf("expected an invalid proxy to be rejected")
+	}
+}
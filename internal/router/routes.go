@@ -3,6 +3,7 @@ package router
 import (
 	"net/http"
 	"opportunities/docs"
+	"opportunities/internal/auth"
 	"opportunities/internal/handler"
 	"opportunities/internal/middleware"
 	"opportunities/internal/repository"
@@ -14,9 +15,14 @@ import (
 	"gorm.io/gorm"
 )
 
-func initializeRoutes(router *gin.Engine, db *gorm.DB, csvService *service.OpeningCSVService) {
+func initializeRoutes(router *gin.Engine, db *gorm.DB, csvService *service.OpeningCSVService, opts ...handler.Option) {
 	repo := repository.New(db)
-	h := handler.New(repo, csvService)
+	sessions := repository.NewSessionRepository(db)
+	opts = append([]handler.Option{
+		handler.WithSessions(sessions),
+		handler.WithCSVMappings(repository.NewCSVMappingRepository(db)),
+	}, opts...)
+	h := handler.New(repo, csvService, opts...)
 
 	router.GET("/healthz", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
@@ -26,20 +32,57 @@ func initializeRoutes(router *gin.Engine, db *gorm.DB, csvService *service.Openi
 	docs.SwaggerInfo.BasePath = basePath
 
 	router.POST(basePath+"/login", h.LoginHandler)
+	router.POST(basePath+"/login/refresh", h.RefreshTokenHandler)
+	router.POST(basePath+"/login/2fa", h.TwoFactorLoginHandler)
+	router.POST(basePath+"/login/2fa/enroll", h.TwoFactorLoginEnrollHandler)
+	router.GET(basePath+"/login/oidc", h.OIDCLoginHandler)
+	router.GET(basePath+"/login/oidc/callback", h.OIDCCallbackHandler)
 
 	v1Public := router.Group(basePath)
+	v1Public.Use(middleware.OptionalAuth(middleware.WithSessions(sessions)))
 	{
 		v1Public.GET("/opening", h.ShowOpeningHandler)
 		v1Public.GET("/openings", h.ListOpeningHandler)
+		v1Public.GET("/openings/export", h.ExportOpeningsHandler)
 	}
 
 	v1Protected := router.Group(basePath)
-	v1Protected.Use(middleware.Auth())
+	v1Protected.Use(middleware.Auth(middleware.WithSessions(sessions)))
 	{
 		v1Protected.POST("/opening", h.CreateOpeningHandler)
 		v1Protected.POST("/opening/csv", h.CreateOpeningCSVHandler)
+		v1Protected.POST("/opening/import", h.ImportOpeningsHandler)
+		v1Protected.POST("/opening/csv/validate", h.ValidateOpeningCSVHandler)
+		v1Protected.POST("/opening/rules/dry-run", h.DryRunOpeningRulesHandler)
+		v1Protected.GET("/opening/csv", h.ListOpeningCSVJobsHandler)
+		v1Protected.GET("/opening/csv/queue", h.ShowOpeningCSVQueueHandler)
+		v1Protected.GET("/opening/csv/mappings", h.ListCSVMappingsHandler)
+		v1Protected.PUT("/opening/csv/mappings/:name", h.SaveCSVMappingHandler)
+		v1Protected.DELETE("/opening/csv/mappings/:name", h.DeleteCSVMappingHandler)
+		v1Protected.GET("/opening/csv/batches/:batch_id", h.ShowOpeningCSVBatchHandler)
+		v1Protected.GET("/opening/csv/:request_id", h.ShowOpeningCSVJobHandler)
+		v1Protected.DELETE("/opening/csv/:request_id", h.CancelOpeningCSVJobHandler)
+		v1Protected.GET("/opening/csv/:request_id/errors", h.ListOpeningCSVJobErrorsHandler)
+		v1Protected.GET("/opening/csv/:request_id/errors.csv", h.DownloadOpeningCSVJobErrorsHandler)
 		v1Protected.PUT("/opening", h.UpdateOpeningHandler)
 		v1Protected.DELETE("/opening", h.DeleteOpeningHandler)
+		v1Protected.GET("/openings/export/:id", h.ShowExportJobHandler)
+		v1Protected.GET("/openings/export/:id/download", h.DownloadExportHandler)
+		v1Protected.GET("/me", h.MeHandler)
+		v1Protected.GET("/me/sessions", h.ListSessionsHandler)
+		v1Protected.DELETE("/me/sessions/:id", h.RevokeSessionHandler)
+		v1Protected.POST("/me/2fa", h.EnrollTwoFactorHandler)
+		v1Protected.POST("/me/2fa/confirm", h.ConfirmTwoFactorHandler)
+		v1Protected.DELETE("/me/2fa", h.DisableTwoFactorHandler)
+	}
+
+	v1Admin := router.Group(basePath + "/admin")
+	v1Admin.Use(middleware.Auth(middleware.WithSessions(sessions)), middleware.RequireRole(auth.RoleAdmin))
+	{
+		v1Admin.GET("/2fa-policies", h.ListTwoFactorPoliciesHandler)
+		v1Admin.PUT("/2fa-policies/:role", h.SetTwoFactorPolicyHandler)
+		v1Admin.GET("/opening-rules", h.ShowOpeningRulesHandler)
+		v1Admin.POST("/opening-rules/reload", h.ReloadOpeningRulesHandler)
 	}
 
 	// swagger
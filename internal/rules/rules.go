@@ -0,0 +1,533 @@
+// Package rules applies the transforms and validations of a rules file to
+// openings, both to CSV rows and to the create and update requests, so each
+// team can enforce its own conventions without changing the parser.
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	csvutil "opportunities/internal/csv"
+	"opportunities/internal/schemas"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	CaseUpper = "upper"
+	CaseLower = "lower"
+	CaseTitle = "title"
+)
+
+// File is the rules file. It is written in YAML or JSON. Transforms run, in
+// order, on the raw values before they are parsed; validations run on the
+// parsed opening.
+type File struct {
+	Transforms  []Transform  `yaml:"transforms" json:"transforms"`
+	Validations []Validation `yaml:"validations" json:"validations"`
+}
+
+// Transform rewrites one field. Its steps run in the order trim, replace,
+// map, case and set.
+type Transform struct {
+	Field string `yaml:"field" json:"field"`
+	// Owners limits the rule to the openings of these users. Empty means
+	// every user.
+	Owners []string   `yaml:"owners,omitempty" json:"owners,omitempty"`
+	When   *Condition `yaml:"when,omitempty" json:"when,omitempty"`
+
+	Trim    bool    `yaml:"trim,omitempty" json:"trim,omitempty"`
+	Replace *Regexp `yaml:"replace,omitempty" json:"replace,omitempty"`
+	// Map replaces whole values, matched case-insensitively, such as
+	// "Home office" -> "true".
+	Map  map[string]string `yaml:"map,omitempty" json:"map,omitempty"`
+	Case string            `yaml:"case,omitempty" json:"case,omitempty"`
+	Set  *string           `yaml:"set,omitempty" json:"set,omitempty"`
+}
+
+// Regexp replaces the matches of Pattern with With, which may refer to groups
+// as in regexp.Regexp.ReplaceAllString.
+type Regexp struct {
+	Pattern string `yaml:"pattern" json:"pattern"`
+	With    string `yaml:"with" json:"with"`
+}
+
+// Validation checks one field. Checks other than required and required_if
+// are skipped when the field is empty.
+type Validation struct {
+	Field  string     `yaml:"field" json:"field"`
+	Owners []string   `yaml:"owners,omitempty" json:"owners,omitempty"`
+	When   *Condition `yaml:"when,omitempty" json:"when,omitempty"`
+
+	Required   bool       `yaml:"required,omitempty" json:"required,omitempty"`
+	RequiredIf *Condition `yaml:"required_if,omitempty" json:"required_if,omitempty"`
+	Pattern    string     `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Min        *float64   `yaml:"min,omitempty" json:"min,omitempty"`
+	Max        *float64   `yaml:"max,omitempty" json:"max,omitempty"`
+	MinLength  *int       `yaml:"min_length,omitempty" json:"min_length,omitempty"`
+	MaxLength  *int       `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+	OneOf      []string   `yaml:"one_of,omitempty" json:"one_of,omitempty"`
+	// Message replaces the default error of any failed check.
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+}
+
+// Condition holds when a field equals a value, case-insensitively, or
+// matches a pattern.
+type Condition struct {
+	Field   string  `yaml:"field" json:"field"`
+	Equals  *string `yaml:"equals,omitempty" json:"equals,omitempty"`
+	Matches string  `yaml:"matches,omitempty" json:"matches,omitempty"`
+}
+
+// ValidationError lists the validations an opening failed.
+type ValidationError struct {
+	Messages []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Messages, "; ")
+}
+
+// Set is a compiled rules file. A nil Set has no rules.
+type Set struct {
+	file        File
+	transforms  []transform
+	validations []validation
+}
+
+type transform struct {
+	Transform
+	when    *condition
+	replace *regexp.Regexp
+	mapping map[string]string
+}
+
+type validation struct {
+	Validation
+	when       *condition
+	requiredIf *condition
+	pattern    *regexp.Regexp
+}
+
+type condition struct {
+	Condition
+	matches *regexp.Regexp
+}
+
+// Parse reads and compiles a rules file. JSON is valid YAML, so both are
+// read the same way. Unknown keys are rejected to catch typos.
+func Parse(data []byte) (*Set, error) {
+	var file File
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+
+	return Compile(file)
+}
+
+// Compile checks the fields, patterns and steps of every rule.
+func Compile(file File) (*Set, error) {
+	set := &Set{file: file}
+
+	for i, t := range file.Transforms {
+		compiled, err := compileTransform(t)
+		if err != nil {
+			return nil, fmt.Errorf("transforms[%d]: %w", i, err)
+		}
+		set.transforms = append(set.transforms, compiled)
+	}
+
+	for i, v := range file.Validations {
+		compiled, err := compileValidation(v)
+		if err != nil {
+			return nil, fmt.Errorf("validations[%d]: %w", i, err)
+		}
+		set.validations = append(set.validations, compiled)
+	}
+
+	return set, nil
+}
+
+func compileTransform(t Transform) (transform, error) {
+	compiled := transform{Transform: t}
+
+	if err := checkField(t.Field); err != nil {
+		return compiled, err
+	}
+	if !t.Trim && t.Replace == nil && len(t.Map) == 0 && t.Case == "" && t.Set == nil {
+		return compiled, fmt.Errorf("%s: transform has no step", t.Field)
+	}
+
+	var err error
+	if compiled.when, err = compileCondition(t.When); err != nil {
+		return compiled, err
+	}
+
+	if t.Replace != nil {
+		if compiled.replace, err = regexp.Compile(t.Replace.Pattern); err != nil {
+			return compiled, fmt.Errorf("%s: invalid replace pattern: %w", t.Field, err)
+		}
+	}
+
+	if len(t.Map) > 0 {
+		compiled.mapping = make(map[string]string, len(t.Map))
+		for from, to := range t.Map {
+			compiled.mapping[strings.ToLower(strings.TrimSpace(from))] = to
+		}
+	}
+
+	switch t.Case {
+	case "", CaseUpper, CaseLower, CaseTitle:
+	default:
+		return compiled, fmt.Errorf("%s: case must be upper, lower or title", t.Field)
+	}
+
+	return compiled, nil
+}
+
+func compileValidation(v Validation) (validation, error) {
+	compiled := validation{Validation: v}
+
+	if err := checkField(v.Field); err != nil {
+		return compiled, err
+	}
+	if !v.Required && v.RequiredIf == nil && v.Pattern == "" && v.Min == nil && v.Max == nil &&
+		v.MinLength == nil && v.MaxLength == nil && len(v.OneOf) == 0 {
+		return compiled, fmt.Errorf("%s: validation has no check", v.Field)
+	}
+
+	var err error
+	if compiled.when, err = compileCondition(v.When); err != nil {
+		return compiled, err
+	}
+	if compiled.requiredIf, err = compileCondition(v.RequiredIf); err != nil {
+		return compiled, err
+	}
+
+	if v.Pattern != "" {
+		if compiled.pattern, err = regexp.Compile(v.Pattern); err != nil {
+			return compiled, fmt.Errorf("%s: invalid pattern: %w", v.Field, err)
+		}
+	}
+
+	return compiled, nil
+}
+
+func compileCondition(c *Condition) (*condition, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	if err := checkField(c.Field); err != nil {
+		return nil, fmt.Errorf("condition: %w", err)
+	}
+	if (c.Equals == nil) == (c.Matches == "") {
+		return nil, fmt.Errorf("condition on %s needs either equals or matches", c.Field)
+	}
+
+	compiled := &condition{Condition: *c}
+	if c.Matches != "" {
+		var err error
+		if compiled.matches, err = regexp.Compile(c.Matches); err != nil {
+			return nil, fmt.Errorf("condition on %s: invalid pattern: %w", c.Field, err)
+		}
+	}
+
+	return compiled, nil
+}
+
+func checkField(field string) error {
+	if !slices.Contains(csvutil.Header(), field) {
+		return fmt.Errorf("unknown field %q, expected one of %v", field, csvutil.Header())
+	}
+
+	return nil
+}
+
+// File returns the rules the set was compiled from.
+func (s *Set) File() File {
+	if s == nil {
+		return File{}
+	}
+
+	return s.file
+}
+
+// Empty reports whether the set has no rules.
+func (s *Set) Empty() bool {
+	return s == nil || len(s.transforms)+len(s.validations) == 0
+}
+
+// For returns the rules that apply to the openings of owner.
+func (s *Set) For(owner string) *Set {
+	if s == nil {
+		return nil
+	}
+
+	scoped := &Set{file: s.file}
+	for _, t := range s.transforms {
+		if appliesTo(t.Owners, owner) {
+			scoped.transforms = append(scoped.transforms, t)
+		}
+	}
+	for _, v := range s.validations {
+		if appliesTo(v.Owners, owner) {
+			scoped.validations = append(scoped.validations, v)
+		}
+	}
+
+	return scoped
+}
+
+func appliesTo(owners []string, owner string) bool {
+	if len(owners) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(owners, func(o string) bool {
+		return strings.EqualFold(o, owner)
+	})
+}
+
+// Transform rewrites the raw values of an opening, keyed by field.
+func (s *Set) Transform(values map[string]string) {
+	if s == nil {
+		return
+	}
+
+	for _, t := range s.transforms {
+		if !t.when.holds(values) {
+			continue
+		}
+
+		value := values[t.Field]
+		if t.Trim {
+			value = strings.TrimSpace(value)
+		}
+		if t.replace != nil {
+			value = t.replace.ReplaceAllString(value, t.Replace.With)
+		}
+		if mapped, ok := t.mapping[strings.ToLower(strings.TrimSpace(value))]; ok {
+			value = mapped
+		}
+		switch t.Case {
+		case CaseUpper:
+			value = strings.ToUpper(value)
+		case CaseLower:
+			value = strings.ToLower(value)
+		case CaseTitle:
+			// A Caser keeps state, so it is not shared between the parse
+			// workers.
+			value = cases.Title(language.Und).String(value)
+		}
+		if t.Set != nil {
+			value = *t.Set
+		}
+
+		values[t.Field] = value
+	}
+}
+
+// Validate checks an opening against every validation and reports all the
+// failures in a ValidationError.
+func (s *Set) Validate(opening schemas.Openings) error {
+	if s == nil {
+		return nil
+	}
+
+	values := Values(opening)
+
+	var messages []string
+	for _, v := range s.validations {
+		if !v.when.holds(values) {
+			continue
+		}
+
+		if message := v.check(values); message != "" {
+			if v.Message != "" {
+				message = v.Message
+			}
+			messages = append(messages, message)
+		}
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Messages: messages}
+}
+
+func (v validation) check(values map[string]string) string {
+	value := values[v.Field]
+
+	if value == "" {
+		switch {
+		case v.Required:
+			return fmt.Sprintf("%s is required", v.Field)
+		case v.requiredIf != nil && v.requiredIf.holds(values):
+			return fmt.Sprintf("%s is required when %s", v.Field, v.requiredIf)
+		default:
+			return ""
+		}
+	}
+
+	if v.pattern != nil && !v.pattern.MatchString(value) {
+		return fmt.Sprintf("%s must match %s", v.Field, v.Pattern)
+	}
+
+	if v.Min != nil || v.Max != nil {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Sprintf("%s must be a number", v.Field)
+		}
+		if v.Min != nil && number < *v.Min {
+			return fmt.Sprintf("%s must be at least %v", v.Field, *v.Min)
+		}
+		if v.Max != nil && number > *v.Max {
+			return fmt.Sprintf("%s must be at most %v", v.Field, *v.Max)
+		}
+	}
+
+	length := len([]rune(value))
+	if v.MinLength != nil && length < *v.MinLength {
+		return fmt.Sprintf("%s must have at least %d characters", v.Field, *v.MinLength)
+	}
+	if v.MaxLength != nil && length > *v.MaxLength {
+		return fmt.Sprintf("%s must have at most %d characters", v.Field, *v.MaxLength)
+	}
+
+	if len(v.OneOf) > 0 && !slices.ContainsFunc(v.OneOf, func(allowed string) bool {
+		return strings.EqualFold(allowed, value)
+	}) {
+		return fmt.Sprintf("%s must be one of %s", v.Field, strings.Join(v.OneOf, ", "))
+	}
+
+	return ""
+}
+
+// holds reports whether the condition is met. A nil condition always holds.
+func (c *condition) holds(values map[string]string) bool {
+	if c == nil {
+		return true
+	}
+
+	value := values[c.Field]
+	if c.matches != nil {
+		return c.matches.MatchString(value)
+	}
+
+	return strings.EqualFold(strings.TrimSpace(value), *c.Equals)
+}
+
+func (c *condition) String() string {
+	if c.matches != nil {
+		return fmt.Sprintf("%s matches %s", c.Field, c.Matches)
+	}
+
+	return fmt.Sprintf("%s is %s", c.Field, *c.Equals)
+}
+
+// Values returns the fields of an opening as the strings the rules work on.
+// Zero salaries are empty, so required checks them as missing.
+func Values(opening schemas.Openings) map[string]string {
+	salary := ""
+	if opening.Salary != 0 {
+		salary = strconv.FormatInt(opening.Salary, 10)
+	}
+
+	return map[string]string{
+		"role":        opening.Role,
+		"company":     opening.Company,
+		"location":    opening.Location,
+		"remote":      strconv.FormatBool(opening.Remote),
+		"link":        opening.Link,
+		"salary":      salary,
+		"external_id": opening.ExternalID,
+	}
+}
+
+// Apply transforms the fields of an opening and validates the result. It is
+// used for openings that are already typed, such as an updated opening.
+func (s *Set) Apply(opening *schemas.Openings) error {
+	if s.Empty() {
+		return nil
+	}
+
+	values := Values(*opening)
+	s.Transform(values)
+
+	if err := SetValues(opening, values); err != nil {
+		return &ValidationError{Messages: []string{err.Error()}}
+	}
+
+	return s.Validate(*opening)
+}
+
+// SetValues stores values, as returned by Values, in the fields of an
+// opening. An empty salary is zero.
+func SetValues(opening *schemas.Openings, values map[string]string) error {
+	remote, err := strconv.ParseBool(values["remote"])
+	if err != nil {
+		return fmt.Errorf("remote must be a boolean")
+	}
+
+	salary := int64(0)
+	if values["salary"] != "" {
+		if salary, err = strconv.ParseInt(values["salary"], 10, 64); err != nil {
+			return fmt.Errorf("salary must be an integer")
+		}
+	}
+
+	opening.Role = values["role"]
+	opening.Company = values["company"]
+	opening.Location = values["location"]
+	opening.Remote = remote
+	opening.Link = values["link"]
+	opening.Salary = salary
+	opening.ExternalID = values["external_id"]
+
+	return nil
+}
+
+// Result is a row checked by Check: its values after the transforms and the
+// errors it would be rejected with.
+type Result struct {
+	Values map[string]string `json:"values"`
+	Errors []string          `json:"errors"`
+}
+
+// Check runs the rules on one row without importing it. Fields missing from
+// values are empty.
+func (s *Set) Check(values map[string]string) Result {
+	transformed := make(map[string]string, len(csvutil.Header()))
+	for _, field := range csvutil.Header() {
+		transformed[field] = values[field]
+	}
+	s.Transform(transformed)
+
+	result := Result{Values: transformed, Errors: make([]string, 0)}
+
+	var opening schemas.Openings
+	if err := SetValues(&opening, transformed); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	var validationErr *ValidationError
+	if err := s.Validate(opening); errors.As(err, &validationErr) {
+		result.Errors = append(result.Errors, validationErr.Messages...)
+	}
+
+	return result
+}
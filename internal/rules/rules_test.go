@@ -0,0 +1,154 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	csvutil "opportunities/internal/csv"
+)
+
+const teamRules = `
+transforms:
+  - field: remote
+    map:
+      Home office: "true"
+      presencial: "false"
+  - field: company
+    case: title
+  - field: link
+    replace: { pattern: "^http://", with: "https://" }
+validations:
+  - field: salary
+    min: 3000
+  - field: link
+    pattern: "^https://"
+    message: link must use https
+  - field: external_id
+    required_if: { field: remote, equals: "false" }
+  - field: role
+    one_of: [Go Dev, SRE]
+    owners: [team-a@test.com]
+`
+
+func TestParse_AppliesRulesToCSVRows(t *testing.T) {
+	set, err := Parse([]byte(teamRules))
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	content := []byte("role,company,location,remote,link,salary,external_id\n" +
+		"Go Dev,acme corp,BR,Home office,http://acme.com/1,5000,\n" +
+		"QA,acme,BR,presencial,https://acme.com/2,1000,\n" +
+		"QA,acme,BR,true,ftp://acme.com/3,4000,\n")
+
+	parsed, rowErrors, err := csvutil.ParseAndValidateWithOptions(content, csvutil.ParseOptions{Rules: set.For("team-b@test.com")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(parsed) != 1 {
+		t.Fatalf("expected one valid row, got %d (%v)", len(parsed), rowErrors)
+	}
+	opening := parsed[0].Opening
+	if !opening.Remote || opening.Company != "Acme Corp" || opening.Link != "https://acme.com/1" {
+		t.Fatalf("expected the transforms applied, got %+v", opening)
+	}
+
+	expected := []csvutil.RowError{
+		{LineNumber: 3, Message: "salary must be at least 3000; external_id is required when remote is false"},
+		{LineNumber: 4, Message: "link must use https"},
+	}
+	if !reflect.DeepEqual(rowErrors, expected) {
+		t.Fatalf("expected %v, got %v", expected, rowErrors)
+	}
+
+	// The role rule only applies to team-a.
+	_, rowErrors, _ = csvutil.ParseAndValidateWithOptions(content, csvutil.ParseOptions{Rules: set.For("TEAM-A@test.com")})
+	if len(rowErrors) != 2 || !strings.Contains(rowErrors[1].Message, "role must be one of Go Dev, SRE") {
+		t.Fatalf("expected the role rule for team-a, got %v", rowErrors)
+	}
+}
+
+func TestParse_RejectsInvalidRules(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":        "transforms:\n  - field: role\n    uppercase: true\n",
+		"unknown field":      "validations:\n  - field: salry\n    min: 1\n",
+		"no step":            "transforms:\n  - field: role\n",
+		"invalid pattern":    "validations:\n  - field: link\n    pattern: \"(\"\n",
+		"invalid case":       "transforms:\n  - field: role\n    case: camel\n",
+		"condition operator": "validations:\n  - field: link\n    required_if: { field: remote }\n",
+	}
+
+	for name, rules := range tests {
+		if _, err := Parse([]byte(rules)); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := Parse([]byte(`{"validations": [{"field": "salary", "max": 100000}]}`)); err != nil {
+		t.Fatalf("expected JSON rules to parse, got %v", err)
+	}
+}
+
+func TestSet_Check(t *testing.T) {
+	set, err := Parse([]byte(teamRules))
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	result := set.Check(map[string]string{"role": "Go Dev", "company": "acme", "remote": "Home office", "link": "http://acme.com", "salary": "2000"})
+	if result.Values["remote"] != "true" || result.Values["link"] != "https://acme.com" {
+		t.Fatalf("expected transformed values, got %v", result.Values)
+	}
+	if !reflect.DeepEqual(result.Errors, []string{"salary must be at least 3000"}) {
+		t.Fatalf("expected the salary error, got %v", result.Errors)
+	}
+
+	var empty *Set
+	if result := empty.Check(map[string]string{"remote": "maybe"}); !reflect.DeepEqual(result.Errors, []string{"remote must be a boolean"}) {
+		t.Fatalf("expected the parse error without rules, got %v", result.Errors)
+	}
+}
+
+func TestStore_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("validations:\n  - field: salary\n    min: 3000\n"), 0o644); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("unexpected store error: %v", err)
+	}
+	if len(store.Current().File().Validations) != 1 {
+		t.Fatalf("expected one validation, got %+v", store.Current().File())
+	}
+
+	if err := os.WriteFile(path, []byte("validations:\n  - field: salry\n    min: 3000\n"), 0o644); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	if _, err := store.Reload(); err == nil {
+		t.Fatalf("expected an invalid file to fail the reload")
+	}
+	if len(store.Current().File().Validations) != 1 {
+		t.Fatalf("expected the previous rules to stay in effect")
+	}
+
+	if err := os.WriteFile(path, []byte("transforms:\n  - field: role\n    case: upper\n"), 0o644); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	if _, err := store.Reload(); err != nil {
+		t.Fatalf("unexpected reload error: %v", err)
+	}
+	if file := store.Current().File(); len(file.Validations) != 0 || len(file.Transforms) != 1 {
+		t.Fatalf("expected the new rules, got %+v", file)
+	}
+
+	empty, err := NewStore("")
+	if err != nil || !empty.Current().Empty() {
+		t.Fatalf("expected no rules without a file, got %v", err)
+	}
+}
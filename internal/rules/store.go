@@ -0,0 +1,78 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store holds the rules loaded from a file and swaps them on Reload, so the
+// rules can change without a restart. Imports and requests read the rules
+// current when they start.
+type Store struct {
+	path     string
+	mu       sync.Mutex
+	current  atomic.Pointer[Set]
+	loadedAt atomic.Pointer[time.Time]
+}
+
+// NewStore loads the rules file at path. An empty path means no rules.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Reload reads the file again. An invalid file leaves the current rules in
+// place.
+func (s *Store) Reload() (*Set, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set := &Set{}
+	if s.path != "" {
+		data, err := os.ReadFile(s.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules file: %w", err)
+		}
+
+		if set, err = Parse(data); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	s.current.Store(set)
+	s.loadedAt.Store(&now)
+
+	return set, nil
+}
+
+// Current returns the rules in effect. It returns nil on a nil Store.
+func (s *Store) Current() *Set {
+	if s == nil {
+		return nil
+	}
+
+	return s.current.Load()
+}
+
+// For returns the rules in effect for the openings of owner.
+func (s *Store) For(owner string) *Set {
+	return s.Current().For(owner)
+}
+
+// Path returns the rules file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// LoadedAt returns when the rules in effect were read.
+func (s *Store) LoadedAt() time.Time {
+	return *s.loadedAt.Load()
+}
@@ -0,0 +1,16 @@
+package schemas
+
+import (
+	"time"
+)
+
+// CSVMappingPreset is a named column mapping saved by a user and referenced
+// on later uploads.
+type CSVMappingPreset struct {
+	ID        uint              `gorm:"primaryKey" json:"-"`
+	Owner     string            `gorm:"uniqueIndex:idx_csv_mapping_owner_name" json:"-"`
+	Name      string            `gorm:"uniqueIndex:idx_csv_mapping_owner_name" json:"name"`
+	Mapping   map[string]string `gorm:"serializer:json" json:"mapping"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package schemas
+
+import (
+	"time"
+)
+
+// ExportJob tracks an asynchronous export. It uses the ImportJob statuses
+// queued, running, succeeded and failed.
+type ExportJob struct {
+	ID           string     `gorm:"primaryKey" json:"id"`
+	Owner        string     `gorm:"index" json:"owner"`
+	Format       string     `json:"format"`
+	Mine         bool       `json:"mine"`
+	Status       string     `json:"status"`
+	Rows         int        `json:"rows"`
+	ErrorSummary string     `json:"error_summary,omitempty"`
+	Path         string     `json:"-"`
+	StartedAt    *time.Time `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,105 @@
+package schemas
+
+import (
+	"time"
+)
+
+const (
+	ImportJobQueued    = "queued"
+	ImportJobRunning   = "running"
+	ImportJobSucceeded = "succeeded"
+	ImportJobFailed    = "failed"
+	// ImportJobPartialSuccess means some rows were skipped in partial mode.
+	ImportJobPartialSuccess = "partial_success"
+	ImportJobCancelled      = "cancelled"
+)
+
+// ImportJob tracks an asynchronous bulk import (CSV or JSON) from upload to
+// completion.
+type ImportJob struct {
+	RequestID string `gorm:"primaryKey" json:"request_id"`
+	// BatchID groups the jobs of the files of one upload, such as a zip
+	// archive or several file parts.
+	BatchID        string `gorm:"index" json:"batch_id,omitempty"`
+	Owner          string `gorm:"index" json:"owner"`
+	FileName       string `json:"file_name"`
+	Format         string `json:"format"`
+	Mode           string `json:"mode"`
+	Upsert         bool   `json:"upsert"`
+	Source         string `json:"source,omitempty"`
+	Status         string `gorm:"index" json:"status"`
+	TotalRows      int    `json:"total_rows"`
+	ProcessedRows  int    `json:"processed_rows"`
+	InsertedRows   int    `json:"inserted_rows"`
+	UpdatedRows    int    `json:"updated_rows"`
+	UnchangedRows  int    `json:"unchanged_rows"`
+	ClosedRows     int    `json:"closed_rows"`
+	SkippedRows    int    `json:"skipped_rows"`
+	ErrorCount     int    `json:"error_count"`
+	FirstErrorLine int    `json:"first_error_line"`
+	ErrorSummary   string `json:"error_summary"`
+	// Delimiter and Encoding hold the values chosen on upload, if any, and
+	// once the job ran the ones the CSV was read with. BOM reports whether the
+	// file started with a UTF-8 BOM.
+	Delimiter string `json:"delimiter,omitempty"`
+	Encoding  string `json:"encoding,omitempty"`
+	BOM       bool   `json:"bom"`
+	// Locale is how booleans and amounts of a CSV were read. NormalizedValues
+	// counts the values the locale rules rewrote and Normalizations lists
+	// them, each distinct raw value once, up to a limit.
+	Locale           string                `json:"locale,omitempty"`
+	NormalizedValues int                   `json:"normalized_values"`
+	Normalizations   []ImportNormalization `gorm:"serializer:json" json:"normalizations,omitempty"`
+	// IdempotencyKey and ContentHash (SHA-256 of the upload) recognize a
+	// repeated upload of the same file.
+	IdempotencyKey string `gorm:"index" json:"idempotency_key,omitempty"`
+	ContentHash    string `gorm:"index" json:"content_sha256,omitempty"`
+	// Phase and EstimatedFinishAt report the progress of a running job. They
+	// are filled from the worker and not stored.
+	Phase             string     `gorm:"-" json:"phase,omitempty"`
+	EstimatedFinishAt *time.Time `gorm:"-" json:"estimated_finish_at,omitempty"`
+	// Attempts counts the runs of the job. It is above one when a restart
+	// interrupted a run and the job was queued again.
+	Attempts      int        `json:"attempts"`
+	InterruptedAt *time.Time `json:"interrupted_at,omitempty"`
+	QueuedAt      time.Time  `json:"queued_at"`
+	StartedAt     *time.Time `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// The fields below let a queued job be rebuilt after a restart.
+	CloseMissing         bool              `json:"-"`
+	SpoolPath            string            `json:"-"`
+	Mapping              map[string]string `gorm:"serializer:json" json:"-"`
+	RejectUnknownColumns bool              `json:"-"`
+}
+
+// ImportNormalization is a raw value of an import read as Value, such as
+// "R$ 5.000,00" as 5000. Count is how many rows had it and FirstLine the
+// first of them.
+type ImportNormalization struct {
+	Field     string `json:"field"`
+	Raw       string `json:"raw"`
+	Value     string `json:"value"`
+	Count     int    `json:"count"`
+	FirstLine int    `json:"first_line"`
+}
+
+// ImportRowError is a validation or insert error for a single CSV line.
+type ImportRowError struct {
+	ID         uint   `gorm:"primaryKey" json:"-"`
+	RequestID  string `gorm:"index" json:"-"`
+	LineNumber int    `json:"line_number"`
+	Message    string `json:"message"`
+}
+
+// ImportJobFile keeps the uploaded CSV of a failed job so the error report
+// can be rebuilt on top of it. Spooled uploads are referenced by Path;
+// in-memory uploads are stored in Content.
+type ImportJobFile struct {
+	RequestID string `gorm:"primaryKey"`
+	Path      string
+	Content   []byte
+	CreatedAt time.Time
+}
@@ -14,17 +14,30 @@ type Openings struct {
 	Remote   bool
 	Link     string
 	Salary   int64
+	Owner    string `gorm:"index"`
+	// ExternalID and Source identify openings imported from another system
+	// so later imports can update them instead of creating duplicates.
+	ExternalID string `gorm:"index"`
+	Source     string `gorm:"index"`
+	ClosedAt   *time.Time
+	// ImportRun is the request ID of the last close_missing import that
+	// listed the opening; the rest of the source is closed by that import.
+	ImportRun string
 }
 
 type OpeningResponse struct {
-	ID        uint           `json:"id"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty"`
-	Role      string         `json:"role"`
-	Company   string         `json:"company"`
-	Location  string         `json:"location"`
-	Remote    bool           `json:"remote"`
-	Link      string         `json:"link"`
-	Salary    int64          `json:"salary"`
+	ID         uint           `json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at,omitempty"`
+	Role       string         `json:"role"`
+	Company    string         `json:"company"`
+	Location   string         `json:"location"`
+	Remote     bool           `json:"remote"`
+	Link       string         `json:"link"`
+	Salary     int64          `json:"salary"`
+	Owner      string         `json:"owner"`
+	ExternalID string         `json:"external_id,omitempty"`
+	Source     string         `json:"source,omitempty"`
+	ClosedAt   *time.Time     `json:"closed_at,omitempty"`
 }
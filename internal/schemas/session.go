@@ -0,0 +1,31 @@
+package schemas
+
+import (
+	"time"
+)
+
+// Session is a refresh session created at login. Only the SHA-256 hash of the
+// refresh token is stored.
+type Session struct {
+	ID         string `gorm:"primaryKey"`
+	Email      string `gorm:"index"`
+	Roles      string
+	TokenHash  string `gorm:"uniqueIndex"`
+	UserAgent  string
+	IP         string
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+}
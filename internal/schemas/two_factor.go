@@ -0,0 +1,26 @@
+package schemas
+
+import (
+	"time"
+)
+
+// TwoFactor holds the TOTP enrollment of an account. The secret is kept until
+// the enrollment is confirmed with a valid code; only then Enabled is set.
+type TwoFactor struct {
+	Email         string `gorm:"primaryKey"`
+	Secret        string
+	Enabled       bool
+	LastUsedStep  int64
+	RecoveryCodes string
+	ConfirmedAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// TwoFactorPolicy enforces 2FA for every account holding the role.
+type TwoFactorPolicy struct {
+	Role      string    `gorm:"primaryKey" json:"role"`
+	Required  bool      `json:"required"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+)
+
+const defaultDedupeWindow = 24 * time.Hour
+
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different file")
+
+// WithDedupeWindow sets for how long a repeated upload returns the original
+// job instead of being imported again. Zero turns deduplication off.
+func WithDedupeWindow(window time.Duration) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		if window >= 0 {
+			s.dedupeWindow = window
+		}
+	}
+}
+
+// EnqueueOnce enqueues job unless the owner already uploaded it within the
+// dedupe window: with the same IdempotencyKey or, without a key match, with
+// the same ContentHash. A repeat is not queued; its spooled file is removed
+// and the original job is returned with true. Reusing a key for another file
+// returns ErrIdempotencyKeyReused.
+func (s *OpeningCSVService) EnqueueOnce(job OpeningCSVJob) (schemas.ImportJob, bool, error) {
+	if s.jobRepo == nil || s.dedupeWindow == 0 || (job.IdempotencyKey == "" && job.ContentHash == "") {
+		return schemas.ImportJob{}, false, s.Enqueue(job)
+	}
+
+	// Concurrent retries of one upload must not both miss the lookup.
+	s.dedupeMu.Lock()
+	defer s.dedupeMu.Unlock()
+
+	original, found, err := s.findDuplicate(job)
+	if err != nil {
+		s.removeSpool(job.Path)
+		return schemas.ImportJob{}, false, err
+	}
+	if found {
+		s.removeSpool(job.Path)
+		s.logger.Info("repeated csv upload",
+			slog.String("request_id", original.RequestID),
+			slog.String("owner", job.Owner))
+		return original, true, nil
+	}
+
+	return schemas.ImportJob{}, false, s.Enqueue(job)
+}
+
+func (s *OpeningCSVService) findDuplicate(job OpeningCSVJob) (schemas.ImportJob, bool, error) {
+	since := time.Now().UTC().Add(-s.dedupeWindow)
+
+	if job.IdempotencyKey != "" {
+		original, err := s.jobRepo.FindByIdempotencyKey(job.Owner, job.IdempotencyKey, since)
+		if err == nil {
+			if job.ContentHash != "" && original.ContentHash != "" && original.ContentHash != job.ContentHash {
+				return schemas.ImportJob{}, false, ErrIdempotencyKeyReused
+			}
+			return original, true, nil
+		}
+		if !errors.Is(err, repository.ErrImportJobNotFound) {
+			return schemas.ImportJob{}, false, fmt.Errorf("failed to look up idempotency key: %w", err)
+		}
+	}
+
+	if job.ContentHash != "" {
+		original, err := s.jobRepo.FindByContentHash(job.Owner, job.ContentHash, since)
+		if err == nil {
+			return original, true, nil
+		}
+		if !errors.Is(err, repository.ErrImportJobNotFound) {
+			return schemas.ImportJob{}, false, fmt.Errorf("failed to look up upload hash: %w", err)
+		}
+	}
+
+	return schemas.ImportJob{}, false, nil
+}
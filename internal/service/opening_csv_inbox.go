@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"opportunities/internal/schemas"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultInboxOwner is the identity inbox jobs are imported under.
+	DefaultInboxOwner = "inbox@system"
+
+	defaultInboxInterval  = 5 * time.Second
+	defaultInboxStableFor = 10 * time.Second
+
+	inboxProcessedDir = "processed"
+	inboxFailedDir    = "failed"
+	// readyMarkerExt marks a file as completely written: "openings.csv" is
+	// picked up as soon as "openings.csv.ready" exists.
+	readyMarkerExt = ".ready"
+	inboxResultExt = ".result.json"
+)
+
+// CSVInboxResult is written next to a file moved out of the inbox.
+type CSVInboxResult struct {
+	File      string `json:"file"`
+	RequestID string `json:"request_id,omitempty"`
+	// Duplicate means the file was already imported within the dedupe
+	// window; Job is then the original import.
+	Duplicate bool               `json:"duplicate,omitempty"`
+	Status    string             `json:"status"`
+	Error     string             `json:"error,omitempty"`
+	Job       *schemas.ImportJob `json:"job,omitempty"`
+	MovedAt   time.Time          `json:"moved_at"`
+}
+
+// inboxFile is the last size seen of a file that is not ready yet.
+type inboxFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+}
+
+type inboxJob struct {
+	requestID string
+	duplicate bool
+}
+
+// CSVInboxWatcher imports the *.csv files dropped into a directory. A file is
+// picked up once its ".ready" marker exists or, unless markers are required,
+// once its size stopped changing. When its job finishes the file moves to
+// processed/ or failed/ with a ".result.json" sidecar.
+//
+// Files stay in the inbox while they are imported, so after a restart they
+// are enqueued again and matched to their job by the content hash.
+type CSVInboxWatcher struct {
+	logger *slog.Logger
+	csv    *OpeningCSVService
+	dir    string
+	owner  string
+
+	interval      time.Duration
+	stableFor     time.Duration
+	requireMarker bool
+
+	// seen and inflight are only used by the scan loop.
+	seen     map[string]inboxFile
+	inflight map[string]inboxJob
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	running  sync.WaitGroup
+}
+
+type CSVInboxOption func(*CSVInboxWatcher)
+
+// WithInboxOwner sets the identity the inbox jobs belong to.
+func WithInboxOwner(owner string) CSVInboxOption {
+	return func(w *CSVInboxWatcher) {
+		if owner != "" {
+			w.owner = owner
+		}
+	}
+}
+
+// WithInboxInterval sets how often the inbox is scanned.
+func WithInboxInterval(interval time.Duration) CSVInboxOption {
+	return func(w *CSVInboxWatcher) {
+		if interval > 0 {
+			w.interval = interval
+		}
+	}
+}
+
+// WithInboxStableFor sets for how long the size of a file must not change
+// before it is picked up.
+func WithInboxStableFor(stableFor time.Duration) CSVInboxOption {
+	return func(w *CSVInboxWatcher) {
+		if stableFor >= 0 {
+			w.stableFor = stableFor
+		}
+	}
+}
+
+// WithInboxReadyMarker only picks up files with a ".ready" marker, for
+// writers that cannot guarantee a steady write.
+func WithInboxReadyMarker() CSVInboxOption {
+	return func(w *CSVInboxWatcher) {
+		w.requireMarker = true
+	}
+}
+
+// NewCSVInboxWatcher watches dir. The service must track jobs, since the
+// watcher waits for their result.
+func NewCSVInboxWatcher(csv *OpeningCSVService, dir string, opts ...CSVInboxOption) (*CSVInboxWatcher, error) {
+	if csv.jobRepo == nil {
+		return nil, ErrJobTrackingDisabled
+	}
+
+	for _, sub := range []string{"", inboxProcessedDir, inboxFailedDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create inbox dir: %w", err)
+		}
+	}
+
+	w := &CSVInboxWatcher{
+		logger:    slog.Default().With("group", "opening_csv_inbox"),
+		csv:       csv,
+		dir:       dir,
+		owner:     DefaultInboxOwner,
+		interval:  defaultInboxInterval,
+		stableFor: defaultInboxStableFor,
+		seen:      make(map[string]inboxFile),
+		inflight:  make(map[string]inboxJob),
+		stop:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w, nil
+}
+
+// Start scans the inbox every interval until ctx is done or Shutdown is
+// called.
+func (w *CSVInboxWatcher) Start(ctx context.Context) {
+	w.running.Add(1)
+	go func() {
+		defer w.running.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			w.scan(time.Now())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Shutdown stops scanning and waits for the current scan, until ctx is done.
+// Files being imported stay in the inbox.
+func (w *CSVInboxWatcher) Shutdown(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	stopped := make(chan struct{})
+	go func() {
+		w.running.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("inbox scan still running: %w", ctx.Err())
+	}
+}
+
+// scan moves the files whose job finished and enqueues the new files that
+// are ready.
+func (w *CSVInboxWatcher) scan(now time.Time) {
+	w.collect()
+
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		w.logger.Error("failed to read inbox", slog.String("error", err.Error()))
+		return
+	}
+
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(name), ".csv") {
+			continue
+		}
+		present[name] = true
+
+		if _, ok := w.inflight[name]; ok {
+			continue
+		}
+		if w.ready(name, now) {
+			w.enqueue(name)
+		}
+	}
+
+	for name := range w.seen {
+		if !present[name] {
+			delete(w.seen, name)
+		}
+	}
+}
+
+// ready reports whether the writer is done with the file.
+func (w *CSVInboxWatcher) ready(name string, now time.Time) bool {
+	if _, err := os.Stat(filepath.Join(w.dir, name+readyMarkerExt)); err == nil {
+		return true
+	}
+	if w.requireMarker {
+		return false
+	}
+
+	info, err := os.Stat(filepath.Join(w.dir, name))
+	if err != nil {
+		return false
+	}
+
+	seen, ok := w.seen[name]
+	if !ok || seen.size != info.Size() || !seen.modTime.Equal(info.ModTime()) {
+		w.seen[name] = inboxFile{size: info.Size(), modTime: info.ModTime(), since: now}
+		return false
+	}
+
+	return now.Sub(seen.since) >= w.stableFor
+}
+
+func (w *CSVInboxWatcher) enqueue(name string) {
+	logger := w.logger.With("file", name)
+
+	file, err := os.Open(filepath.Join(w.dir, name))
+	if err != nil {
+		logger.Error("failed to open inbox file", slog.String("error", err.Error()))
+		return
+	}
+
+	hash := sha256.New()
+	path, err := w.csv.Spool(io.TeeReader(file, hash))
+	file.Close()
+	if err != nil {
+		logger.Error("failed to spool inbox file", slog.String("error", err.Error()))
+		return
+	}
+
+	requestID := uuid.NewString()
+	original, duplicate, err := w.csv.EnqueueOnce(OpeningCSVJob{
+		RequestID:   requestID,
+		Owner:       w.owner,
+		FileName:    name,
+		Mode:        ImportModeStrict,
+		ContentHash: hex.EncodeToString(hash.Sum(nil)),
+		Path:        path,
+	})
+	if errors.Is(err, ErrCSVQueueFull) || errors.Is(err, ErrCSVServiceStopped) {
+		// The file stays in the inbox and is retried on the next scan.
+		logger.Warn("inbox file not enqueued", slog.String("error", err.Error()))
+		return
+	}
+	if err != nil {
+		w.finish(name, CSVInboxResult{Status: schemas.ImportJobFailed, Error: err.Error()})
+		return
+	}
+
+	delete(w.seen, name)
+	if duplicate {
+		requestID = original.RequestID
+	}
+	w.inflight[name] = inboxJob{requestID: requestID, duplicate: duplicate}
+	logger.Info("inbox file enqueued", slog.String("request_id", requestID), slog.Bool("duplicate", duplicate))
+}
+
+// collect moves the files whose job finished.
+func (w *CSVInboxWatcher) collect() {
+	for name, entry := range w.inflight {
+		job, err := w.csv.GetJob(entry.requestID)
+		if err != nil {
+			w.logger.Error("failed to load inbox job",
+				slog.String("file", name),
+				slog.String("request_id", entry.requestID),
+				slog.String("error", err.Error()))
+			continue
+		}
+
+		switch job.Status {
+		case schemas.ImportJobQueued, schemas.ImportJobRunning:
+			continue
+		}
+
+		w.finish(name, CSVInboxResult{
+			RequestID: job.RequestID,
+			Duplicate: entry.duplicate,
+			Status:    job.Status,
+			Error:     job.ErrorSummary,
+			Job:       &job,
+		})
+		delete(w.inflight, name)
+	}
+}
+
+// finish moves the file to processed/ or failed/ and writes its sidecar.
+func (w *CSVInboxWatcher) finish(name string, result CSVInboxResult) {
+	logger := w.logger.With("file", name)
+
+	dir := inboxProcessedDir
+	if result.Status == schemas.ImportJobFailed || result.Status == schemas.ImportJobCancelled {
+		dir = inboxFailedDir
+	}
+
+	target := filepath.Join(w.dir, dir, name)
+	if _, err := os.Stat(target); err == nil {
+		// Keep the earlier drop of a file with the same name.
+		ext := filepath.Ext(name)
+		target = filepath.Join(w.dir, dir, fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), time.Now().UTC().Format("20060102T150405.000000000"), ext))
+	}
+
+	if err := os.Rename(filepath.Join(w.dir, name), target); err != nil {
+		logger.Error("failed to move inbox file", slog.String("error", err.Error()))
+		return
+	}
+	os.Remove(filepath.Join(w.dir, name+readyMarkerExt))
+
+	result.File = filepath.Base(target)
+	result.MovedAt = time.Now().UTC()
+
+	body, err := json.MarshalIndent(result, "", "  ")
+	if err == nil {
+		err = os.WriteFile(target+inboxResultExt, body, 0o644)
+	}
+	if err != nil {
+		logger.Error("failed to write inbox result", slog.String("error", err.Error()))
+	}
+
+	logger.Info("inbox file done", slog.String("status", result.Status), slog.String("moved_to", dir))
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+)
+
+func TestCSVInboxWatcher_ImportsStableFiles(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewOpeningCSVService(repository.New(db), &feedbackProducerSpy{}, 5,
+		WithImportJobs(repository.NewImportJobRepository(db)),
+		WithSpoolDir(t.TempDir()))
+
+	inbox := t.TempDir()
+	watcher, err := NewCSVInboxWatcher(svc, inbox, WithInboxStableFor(time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected watcher error: %v", err)
+	}
+
+	writeInboxFile(t, inbox, "good.csv", "role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com/inbox,2000\n")
+	writeInboxFile(t, inbox, "bad.csv", "role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com/inbox-bad,0\n")
+	writeInboxFile(t, inbox, "notes.txt", "ignored")
+
+	start := time.Now()
+	watcher.scan(start)
+	watcher.scan(start.Add(30 * time.Second))
+	if _, ok := svc.queue.take(); ok {
+		t.Fatalf("expected files to wait until their size is stable")
+	}
+
+	// A file still being written starts its wait over.
+	writeInboxFile(t, inbox, "bad.csv", "role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com/inbox-bad,0\nmore")
+	watcher.scan(start.Add(2 * time.Minute))
+	if len(watcher.inflight) != 1 {
+		t.Fatalf("expected only the stable file to be enqueued, got %+v", watcher.inflight)
+	}
+
+	watcher.scan(start.Add(4 * time.Minute))
+	for range 2 {
+		svc.processJob(context.Background(), nextJob(t, svc))
+	}
+	watcher.scan(start.Add(5 * time.Minute))
+
+	if len(watcher.inflight) != 0 {
+		t.Fatalf("expected every job to be collected, got %+v", watcher.inflight)
+	}
+
+	good := readInboxResult(t, filepath.Join(inbox, inboxProcessedDir, "good.csv"))
+	if good.Status != schemas.ImportJobSucceeded || good.Job == nil || good.Job.Owner != DefaultInboxOwner {
+		t.Fatalf("expected good.csv to be imported by the inbox owner, got %+v", good)
+	}
+
+	bad := readInboxResult(t, filepath.Join(inbox, inboxFailedDir, "bad.csv"))
+	if bad.Status != schemas.ImportJobFailed || bad.Error == "" {
+		t.Fatalf("expected bad.csv to fail with an error, got %+v", bad)
+	}
+
+	if _, err := os.Stat(filepath.Join(inbox, "notes.txt")); err != nil {
+		t.Fatalf("expected other files to stay in the inbox: %v", err)
+	}
+}
+
+func TestCSVInboxWatcher_RequiresReadyMarker(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewOpeningCSVService(repository.New(db), &feedbackProducerSpy{}, 5,
+		WithImportJobs(repository.NewImportJobRepository(db)),
+		WithSpoolDir(t.TempDir()))
+
+	inbox := t.TempDir()
+	watcher, err := NewCSVInboxWatcher(svc, inbox, WithInboxReadyMarker(), WithInboxOwner("hr@system"))
+	if err != nil {
+		t.Fatalf("unexpected watcher error: %v", err)
+	}
+
+	writeInboxFile(t, inbox, "drop.csv", "role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com/inbox-marker,2000\n")
+	watcher.scan(time.Now())
+	watcher.scan(time.Now().Add(time.Hour))
+	if len(watcher.inflight) != 0 {
+		t.Fatalf("expected files without a marker to be ignored")
+	}
+
+	writeInboxFile(t, inbox, "drop.csv.ready", "")
+	watcher.scan(time.Now())
+	svc.processJob(context.Background(), nextJob(t, svc))
+	watcher.scan(time.Now())
+
+	result := readInboxResult(t, filepath.Join(inbox, inboxProcessedDir, "drop.csv"))
+	if result.Status != schemas.ImportJobSucceeded || result.Job.Owner != "hr@system" {
+		t.Fatalf("expected drop.csv to be imported by hr@system, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(inbox, "drop.csv.ready")); !os.IsNotExist(err) {
+		t.Fatalf("expected the marker to be removed, got %v", err)
+	}
+
+	// Dropping the same file again is matched to the first import.
+	writeInboxFile(t, inbox, "drop.csv", "role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com/inbox-marker,2000\n")
+	writeInboxFile(t, inbox, "drop.csv.ready", "")
+	watcher.scan(time.Now())
+	watcher.scan(time.Now())
+
+	if _, ok := svc.queue.take(); ok {
+		t.Fatalf("expected the repeated file not to be imported again")
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(inbox, inboxProcessedDir))
+	if len(entries) != 4 {
+		t.Fatalf("expected both drops and their results in processed/, got %d files", len(entries))
+	}
+}
+
+func writeInboxFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("failed writing %s: %v", name, err)
+	}
+}
+
+func readInboxResult(t *testing.T, path string) CSVInboxResult {
+	t.Helper()
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected %s to exist: %v", path, err)
+	}
+
+	body, err := os.ReadFile(path + inboxResultExt)
+	if err != nil {
+		t.Fatalf("expected a result next to %s: %v", path, err)
+	}
+
+	var result CSVInboxResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("invalid result json: %v", err)
+	}
+
+	return result
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	csvutil "opportunities/internal/csv"
+	"opportunities/internal/messaging"
+	"opportunities/internal/schemas"
+)
+
+// maxReportedNormalizations caps the distinct raw values kept in a job
+// report. Values past it are still counted.
+const maxReportedNormalizations = 50
+
+// normalizationReport collects the values the locale rules rewrote during an
+// import, each distinct raw value of a field once.
+type normalizationReport struct {
+	total   int
+	entries []schemas.ImportNormalization
+	index   map[csvutil.Normalization]int
+}
+
+func newNormalizationReport() *normalizationReport {
+	return &normalizationReport{index: make(map[csvutil.Normalization]int)}
+}
+
+func (r *normalizationReport) add(rows []csvutil.ParsedOpening) {
+	for _, row := range rows {
+		for _, normalized := range row.Normalized {
+			r.total++
+
+			if i, ok := r.index[normalized]; ok {
+				r.entries[i].Count++
+				continue
+			}
+			if len(r.entries) >= maxReportedNormalizations {
+				continue
+			}
+
+			r.index[normalized] = len(r.entries)
+			r.entries = append(r.entries, schemas.ImportNormalization{
+				Field:     normalized.Field,
+				Raw:       normalized.Raw,
+				Value:     normalized.Value,
+				Count:     1,
+				FirstLine: row.LineNumber,
+			})
+		}
+	}
+}
+
+func (r *normalizationReport) apply(feedback *messaging.OpeningCSVFeedback) {
+	feedback.NormalizedValues = r.total
+	feedback.Normalizations = r.entries
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"opportunities/internal/messaging"
+	"opportunities/internal/schemas"
+)
+
+// Phases of a running job, reported in progress feedback.
+const (
+	PhaseParsing   = "parsing"
+	PhaseInserting = "inserting"
+)
+
+const (
+	defaultProgressRows     = 1000
+	defaultProgressInterval = 5 * time.Second
+	// minProgressGap keeps small row thresholds from flooding the topic.
+	minProgressGap = time.Second
+)
+
+// WithProgress publishes "running" feedback once rows more rows were handled
+// or interval passed since the last event, whichever comes first. Zero
+// values turn off that trigger; both zero turn progress off.
+func WithProgress(rows int, interval time.Duration) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		s.progressRows = max(0, rows)
+		s.progressInterval = max(0, interval)
+	}
+}
+
+// jobProgress is the live state of a running job. It is kept in memory
+// because the import transaction holds the SQLite write lock until the job
+// ends, and GetJob and ListJobs apply it to the stored record.
+type jobProgress struct {
+	Phase             string
+	TotalRows         int
+	ProcessedRows     int
+	EstimatedFinishAt time.Time
+}
+
+type progressTracker struct {
+	mu   sync.Mutex
+	jobs map[string]jobProgress
+}
+
+func (t *progressTracker) set(requestID string, progress jobProgress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.jobs == nil {
+		t.jobs = make(map[string]jobProgress)
+	}
+	t.jobs[requestID] = progress
+}
+
+func (t *progressTracker) clear(requestID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.jobs, requestID)
+}
+
+// apply copies the live progress onto a record that is still running.
+func (t *progressTracker) apply(job *schemas.ImportJob) {
+	if job.Status != schemas.ImportJobRunning {
+		return
+	}
+
+	t.mu.Lock()
+	progress, ok := t.jobs[job.RequestID]
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	job.Phase = progress.Phase
+	job.TotalRows = progress.TotalRows
+	job.ProcessedRows = progress.ProcessedRows
+	if !progress.EstimatedFinishAt.IsZero() {
+		finish := progress.EstimatedFinishAt
+		job.EstimatedFinishAt = &finish
+	}
+}
+
+// countingReader counts the bytes read from an upload, which gives the share
+// of the file already handled.
+type countingReader struct {
+	r    io.Reader
+	read int64
+	mu   sync.Mutex
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.mu.Lock()
+	c.read += int64(n)
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *countingReader) count() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.read
+}
+
+// progressReporter decides when a job publishes progress and estimates when
+// it will finish from the share of the file read so far.
+type progressReporter struct {
+	service *OpeningCSVService
+	job     OpeningCSVJob
+	source  *countingReader
+	size    int64
+	start   time.Time
+	lastAt  time.Time
+	// lastRows holds the rows of each phase at its last event.
+	lastRows map[string]int
+}
+
+func (s *OpeningCSVService) newProgressReporter(job OpeningCSVJob, source *countingReader, start time.Time) *progressReporter {
+	return &progressReporter{
+		service:  s,
+		job:      job,
+		source:   source,
+		size:     job.size(),
+		start:    start,
+		lastAt:   start,
+		lastRows: make(map[string]int, 2),
+	}
+}
+
+// report publishes progress when it is due. totalRows are the rows read and
+// processed the rows saved so far; the row trigger counts the rows of the
+// phase.
+func (p *progressReporter) report(ctx context.Context, phase string, totalRows, processed int) {
+	s := p.service
+	if s.progressRows == 0 && s.progressInterval == 0 {
+		return
+	}
+
+	handled := totalRows
+	if phase == PhaseInserting {
+		handled = processed
+	}
+
+	now := time.Now()
+	if now.Sub(p.lastAt) < s.progressMinGap {
+		return
+	}
+	rowsDue := s.progressRows > 0 && handled-p.lastRows[phase] >= s.progressRows
+	timeDue := s.progressInterval > 0 && now.Sub(p.lastAt) >= s.progressInterval
+	if !rowsDue && !timeDue {
+		return
+	}
+	p.lastAt = now
+	p.lastRows[phase] = handled
+
+	progress := jobProgress{Phase: phase, TotalRows: totalRows, ProcessedRows: processed}
+	feedback := messaging.OpeningCSVFeedback{
+		RequestID:     p.job.RequestID,
+		Format:        p.job.Format,
+		Status:        "running",
+		Phase:         phase,
+		TotalRows:     totalRows,
+		ProcessedRows: processed,
+		DurationMS:    now.Sub(p.start).Milliseconds(),
+		Message:       "csv processing in progress",
+		Timestamp:     now.UTC(),
+	}
+
+	if eta, ok := p.eta(now); ok {
+		progress.EstimatedFinishAt = now.Add(eta).UTC()
+		feedback.ETAMS = eta.Milliseconds()
+	}
+
+	s.progress.set(p.job.RequestID, progress)
+	s.publishFeedback(context.WithoutCancel(ctx), feedback)
+}
+
+func (p *progressReporter) eta(now time.Time) (time.Duration, bool) {
+	read := p.source.count()
+	if p.size <= 0 || read <= 0 || read >= p.size {
+		return 0, false
+	}
+
+	elapsed := now.Sub(p.start)
+	return time.Duration(float64(elapsed) * float64(p.size-read) / float64(read)), true
+}
+
+// size returns the size of the upload, or zero when it is unknown.
+func (j OpeningCSVJob) size() int64 {
+	if j.Path == "" {
+		return int64(len(j.Content))
+	}
+
+	info, err := os.Stat(j.Path)
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// maxErrorReportSweepInterval bounds how long an expired error report may
+// outlive its retention.
+const maxErrorReportSweepInterval = time.Hour
+
+// WithErrorReportTTL sets how long the upload of a finished job is kept for
+// its error report. Zero keeps it forever.
+func WithErrorReportTTL(ttl time.Duration) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		if ttl >= 0 {
+			s.errorReportTTL = ttl
+		}
+	}
+}
+
+// startErrorReportSweep removes expired error report files now and then
+// periodically, until ctx is done or Shutdown is called.
+func (s *OpeningCSVService) startErrorReportSweep(ctx context.Context) {
+	if s.jobRepo == nil || s.errorReportTTL <= 0 {
+		return
+	}
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+
+		ticker := time.NewTicker(min(s.errorReportTTL, maxErrorReportSweepInterval))
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.SweepErrorReports(time.Now()); err != nil {
+				s.logger.Error("failed to sweep csv error reports", slog.String("error", err.Error()))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SweepErrorReports deletes the stored uploads of jobs that finished more
+// than the retention before now. Their errors.csv then answers not found,
+// while the row errors stay listed. It returns how many files were removed.
+func (s *OpeningCSVService) SweepErrorReports(now time.Time) (int, error) {
+	if s.jobRepo == nil || s.errorReportTTL <= 0 {
+		return 0, nil
+	}
+
+	files, err := s.jobRepo.ListFilesFinishedBefore(now.Add(-s.errorReportTTL))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired csv error reports: %w", err)
+	}
+
+	removed := 0
+	for _, file := range files {
+		// The record goes first, so a report is never served without its file.
+		if err := s.jobRepo.DeleteFile(file.RequestID); err != nil {
+			return removed, fmt.Errorf("failed to delete csv error report %s: %w", file.RequestID, err)
+		}
+
+		s.removeSpool(file.Path)
+		removed++
+	}
+
+	if removed > 0 {
+		s.logger.Info("expired csv error reports removed", slog.Int("files", removed))
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	csvutil "opportunities/internal/csv"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+
+	"gorm.io/gorm"
+)
+
+// rowErrorBatchSize is how many row errors are buffered before they are
+// written to the job repository.
+const rowErrorBatchSize = 100
+
+// rowErrorSink streams the row errors of a job to the job repository in
+// batches, keeping only their count and the first line in memory.
+type rowErrorSink struct {
+	repo      repository.ImportJobRepository
+	tx        *gorm.DB
+	requestID string
+	pending   []schemas.ImportRowError
+	count     int
+	firstLine int
+}
+
+func newRowErrorSink(repo repository.ImportJobRepository, tx *gorm.DB, requestID string) *rowErrorSink {
+	return &rowErrorSink{repo: repo, tx: tx, requestID: requestID}
+}
+
+// add buffers rowErrors and writes them once a batch is full.
+func (w *rowErrorSink) add(rowErrors []csvutil.RowError) error {
+	for _, rowErr := range rowErrors {
+		if w.count == 0 {
+			w.firstLine = rowErr.LineNumber
+		}
+		w.count++
+
+		if w.repo == nil {
+			continue
+		}
+
+		w.pending = append(w.pending, schemas.ImportRowError{
+			RequestID:  w.requestID,
+			LineNumber: rowErr.LineNumber,
+			Message:    rowErr.Message,
+		})
+		if len(w.pending) >= rowErrorBatchSize {
+			if err := w.flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// flush writes the buffered row errors.
+func (w *rowErrorSink) flush() error {
+	if w.repo == nil || len(w.pending) == 0 {
+		return nil
+	}
+
+	err := w.repo.AppendRowErrors(w.tx, w.pending)
+	w.pending = w.pending[:0]
+	return err
+}
+
+// detach makes later batches bypass the job transaction, for a job whose
+// transaction was rolled back but whose errors are still reported.
+func (w *rowErrorSink) detach() {
+	w.tx = nil
+}
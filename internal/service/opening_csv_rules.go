@@ -0,0 +1,25 @@
+package service
+
+import (
+	csvutil "opportunities/internal/csv"
+	"opportunities/internal/rules"
+)
+
+// WithRules applies the rules of store to the rows of every import and of
+// Validate.
+func WithRules(store *rules.Store) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		s.rules = store
+	}
+}
+
+// rowRules returns the rules for the rows of owner, or nil when there are
+// none.
+func (s *OpeningCSVService) rowRules(owner string) csvutil.RowRules {
+	set := s.rules.For(owner)
+	if set.Empty() {
+		return nil
+	}
+
+	return set
+}
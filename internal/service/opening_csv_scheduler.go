@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// QueueStats is a snapshot of the CSV job queue.
+type QueueStats struct {
+	Workers  int `json:"workers"`
+	Capacity int `json:"capacity"`
+	Queued   int `json:"queued"`
+	Active   int `json:"active"`
+}
+
+// jobScheduler keeps one FIFO queue per owner and hands jobs to workers in
+// round-robin order across owners, so a user with many uploads cannot keep
+// every worker busy while others wait.
+type jobScheduler struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	capacity int
+	// maxActivePerOwner caps how many jobs of the same owner run at once.
+	// Zero means no cap.
+	maxActivePerOwner int
+
+	queues map[string][]OpeningCSVJob
+	// owners lists the owners with queued jobs, in the order they are served.
+	owners []string
+	queued int
+	active map[string]int
+	// cancels stops the running jobs, by request ID.
+	cancels map[string]context.CancelCauseFunc
+	closed  bool
+}
+
+func newJobScheduler(capacity, maxActivePerOwner int) *jobScheduler {
+	q := &jobScheduler{
+		capacity:          capacity,
+		maxActivePerOwner: maxActivePerOwner,
+		queues:            make(map[string][]OpeningCSVJob),
+		active:            make(map[string]int),
+		cancels:           make(map[string]context.CancelCauseFunc),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	return q
+}
+
+// push queues the job and reports false when the queue is full.
+func (q *jobScheduler) push(job OpeningCSVJob) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.queued >= q.capacity {
+		return false
+	}
+
+	q.add(job)
+	return true
+}
+
+// restore queues a job accepted before a restart. It ignores the capacity,
+// since the upload was already accepted.
+func (q *jobScheduler) restore(job OpeningCSVJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.add(job)
+}
+
+// add appends the job to its owner's queue. Callers hold mu.
+func (q *jobScheduler) add(job OpeningCSVJob) {
+	if len(q.queues[job.Owner]) == 0 {
+		q.owners = append(q.owners, job.Owner)
+	}
+	q.queues[job.Owner] = append(q.queues[job.Owner], job)
+	q.queued++
+
+	q.cond.Signal()
+}
+
+// next blocks until a job can run and marks it active. The returned context
+// is cancelled by cancelRunning. It returns false once the scheduler is
+// closed.
+func (q *jobScheduler) next(ctx context.Context) (OpeningCSVJob, context.Context, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if q.closed {
+			return OpeningCSVJob{}, nil, false
+		}
+
+		if job, ok := q.take(); ok {
+			jobCtx, cancel := context.WithCancelCause(ctx)
+			q.cancels[job.RequestID] = cancel
+			return job, jobCtx, true
+		}
+
+		q.cond.Wait()
+	}
+}
+
+// take pops the first job of the first owner, in serving order, that is below
+// the active cap. The owner moves to the back of the order. Callers hold mu.
+func (q *jobScheduler) take() (OpeningCSVJob, bool) {
+	for i, owner := range q.owners {
+		if q.maxActivePerOwner > 0 && q.active[owner] >= q.maxActivePerOwner {
+			continue
+		}
+
+		queue := q.queues[owner]
+		job := queue[0]
+		q.queued--
+		q.active[owner]++
+
+		q.owners = append(q.owners[:i], q.owners[i+1:]...)
+		if len(queue) == 1 {
+			delete(q.queues, owner)
+		} else {
+			q.queues[owner] = queue[1:]
+			q.owners = append(q.owners, owner)
+		}
+
+		return job, true
+	}
+
+	return OpeningCSVJob{}, false
+}
+
+// done releases the active slot of a finished job.
+func (q *jobScheduler) done(job OpeningCSVJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if cancel, ok := q.cancels[job.RequestID]; ok {
+		cancel(nil)
+		delete(q.cancels, job.RequestID)
+	}
+
+	q.active[job.Owner]--
+	if q.active[job.Owner] <= 0 {
+		delete(q.active, job.Owner)
+	}
+
+	q.cond.Broadcast()
+}
+
+// dequeue removes a job that has not started yet.
+func (q *jobScheduler) dequeue(requestID string) (OpeningCSVJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, owner := range q.owners {
+		queue := q.queues[owner]
+		for j, job := range queue {
+			if job.RequestID != requestID {
+				continue
+			}
+
+			q.queued--
+			if len(queue) == 1 {
+				delete(q.queues, owner)
+				q.owners = append(q.owners[:i], q.owners[i+1:]...)
+			} else {
+				q.queues[owner] = append(queue[:j], queue[j+1:]...)
+			}
+
+			return job, true
+		}
+	}
+
+	return OpeningCSVJob{}, false
+}
+
+// cancelRunning stops a running job with the given cause. It reports false
+// when the job is not running.
+func (q *jobScheduler) cancelRunning(requestID string, cause error) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	cancel, ok := q.cancels[requestID]
+	if ok {
+		cancel(cause)
+	}
+
+	return ok
+}
+
+// cancelAll stops every running job with the given cause and returns how
+// many there were.
+func (q *jobScheduler) cancelAll(cause error) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, cancel := range q.cancels {
+		cancel(cause)
+	}
+
+	return len(q.cancels)
+}
+
+func (q *jobScheduler) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// stats returns the global counters and the ones of the given owner.
+func (q *jobScheduler) stats(owner string) (QueueStats, QueueStats) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	total := QueueStats{Capacity: q.capacity, Queued: q.queued}
+	for _, count := range q.active {
+		total.Active += count
+	}
+
+	mine := QueueStats{Queued: len(q.queues[owner]), Active: q.active[owner]}
+	return total, mine
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestJobScheduler_RoundRobinAcrossOwners(t *testing.T) {
+	queue := newJobScheduler(10, 0)
+
+	for _, job := range []OpeningCSVJob{
+		{RequestID: "a1", Owner: "a@test.com"},
+		{RequestID: "a2", Owner: "a@test.com"},
+		{RequestID: "a3", Owner: "a@test.com"},
+		{RequestID: "b1", Owner: "b@test.com"},
+		{RequestID: "c1", Owner: "c@test.com"},
+	} {
+		if !queue.push(job) {
+			t.Fatalf("unexpected full queue on %s", job.RequestID)
+		}
+	}
+
+	order := make([]string, 0, 5)
+	for range 5 {
+		job, _, ok := queue.next(context.Background())
+		if !ok {
+			t.Fatalf("unexpected closed queue")
+		}
+		order = append(order, job.RequestID)
+	}
+
+	expected := []string{"a1", "b1", "c1", "a2", "a3"}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected order %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestJobScheduler_CapsActiveJobsPerOwner(t *testing.T) {
+	queue := newJobScheduler(10, 1)
+	queue.push(OpeningCSVJob{RequestID: "a1", Owner: "a@test.com"})
+	queue.push(OpeningCSVJob{RequestID: "a2", Owner: "a@test.com"})
+
+	if job, _, _ := queue.next(context.Background()); job.RequestID != "a1" {
+		t.Fatalf("expected a1, got %s", job.RequestID)
+	}
+	if _, ok := queue.take(); ok {
+		t.Fatalf("expected second job of the same owner to wait")
+	}
+
+	queue.push(OpeningCSVJob{RequestID: "b1", Owner: "b@test.com"})
+	if job, _, _ := queue.next(context.Background()); job.RequestID != "b1" {
+		t.Fatalf("expected other owner's job to run, got %s", job.RequestID)
+	}
+
+	total, mine := queue.stats("a@test.com")
+	if total.Queued != 1 || total.Active != 2 || mine.Queued != 1 || mine.Active != 1 {
+		t.Fatalf("unexpected stats %+v %+v", total, mine)
+	}
+
+	queue.done(OpeningCSVJob{RequestID: "a1", Owner: "a@test.com"})
+	if job, _, _ := queue.next(context.Background()); job.RequestID != "a2" {
+		t.Fatalf("expected a2 after a1 finished, got %s", job.RequestID)
+	}
+}
+
+func TestJobScheduler_RejectsWhenFullAndStopsWhenClosed(t *testing.T) {
+	queue := newJobScheduler(1, 0)
+
+	if !queue.push(OpeningCSVJob{RequestID: "a1", Owner: "a@test.com"}) {
+		t.Fatalf("expected first job to be queued")
+	}
+	if queue.push(OpeningCSVJob{RequestID: "a2", Owner: "a@test.com"}) {
+		t.Fatalf("expected full queue")
+	}
+
+	queue.next(context.Background())
+	queue.close()
+
+	if _, _, ok := queue.next(context.Background()); ok {
+		t.Fatalf("expected closed queue")
+	}
+}
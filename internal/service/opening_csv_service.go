@@ -1,61 +1,624 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"opportunities/internal/archive"
 	csvutil "opportunities/internal/csv"
+	"opportunities/internal/importer"
 	"opportunities/internal/messaging"
 	"opportunities/internal/repository"
+	"opportunities/internal/rules"
+	"opportunities/internal/schemas"
+
+	"gorm.io/gorm"
+)
+
+// Import modes. Strict rejects the whole file on any invalid row; partial
+// inserts the valid rows and skips the invalid ones.
+const (
+	ImportModeStrict  = "strict"
+	ImportModePartial = "partial"
 )
 
-var ErrCSVQueueFull = errors.New("csv processing queue is full")
+var (
+	ErrCSVQueueFull        = errors.New("csv processing queue is full")
+	ErrCSVJobTimeout       = errors.New("csv processing timed out")
+	ErrCSVJobInterrupted   = errors.New("csv processing was interrupted too many times")
+	ErrCSVUploadLost       = errors.New("csv upload was lost before processing")
+	ErrCSVJobCancelled     = errors.New("csv processing cancelled")
+	ErrCSVJobNotCancelable = errors.New("csv job is not queued or running")
+	ErrCSVServiceStopped   = errors.New("csv service is shutting down")
+	ErrErrorReportFormat   = errors.New("the annotated error report is only available for csv imports")
+	ErrJobTrackingDisabled = errors.New("csv job tracking is not configured")
+	ErrInvalidImportMode   = fmt.Errorf("mode must be %q or %q", ImportModeStrict, ImportModePartial)
+)
 
 type OpeningCSVJob struct {
 	RequestID string
-	Content   []byte
+	// BatchID groups the jobs of the files of one upload.
+	BatchID  string
+	Owner    string
+	FileName string
+	// Format selects the parser of the upload. Empty means CSV.
+	Format string
+	Mode   string
+	// Upsert updates openings matched by external_id (or link) instead of
+	// inserting duplicates. CloseMissing also closes the owner's openings from
+	// Source that are not in the file.
+	Upsert       bool
+	CloseMissing bool
+	Source       string
+	Columns      csvutil.ParseOptions
+	// IdempotencyKey and ContentHash identify repeated uploads. See
+	// EnqueueOnce.
+	IdempotencyKey string
+	ContentHash    string
+	// Path is the spooled upload. Content is only used when Path is empty,
+	// for small in-memory files.
+	Path    string
+	Content []byte
+}
+
+// ParseImportMode validates the mode chosen for an upload. An empty value
+// means strict.
+func ParseImportMode(raw string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(raw)); mode {
+	case "":
+		return ImportModeStrict, nil
+	case ImportModeStrict, ImportModePartial:
+		return mode, nil
+	default:
+		return "", ErrInvalidImportMode
+	}
 }
 
+const (
+	defaultCSVBatchSize  = 500
+	defaultCSVWorkers    = 1
+	defaultCSVJobTimeout = 30 * time.Minute
+	// maxCSVJobAttempts limits how often a job interrupted by restarts runs
+	// again.
+	maxCSVJobAttempts = 3
+	batchSavePoint    = "csv_batch"
+	// defaultShutdownGrace is how long Shutdown waits for the stopped jobs to
+	// roll back once its context ended.
+	defaultShutdownGrace = 5 * time.Second
+)
+
 type OpeningCSVService struct {
-	logger   *slog.Logger
-	repo     repository.OpeningRepository
-	producer messaging.FeedbackProducer
-	jobs     chan OpeningCSVJob
+	logger       *slog.Logger
+	repo         repository.OpeningRepository
+	producer     messaging.FeedbackProducer
+	queue        *jobScheduler
+	parsers      map[string]importer.Parser
+	jobRepo      repository.ImportJobRepository
+	spoolDir     string
+	batchSize    int
+	parseWorkers int
+	workers      int
+	jobTimeout   time.Duration
+	// validateMaxBytes limits the files checked by Validate, which reads them
+	// in memory.
+	validateMaxBytes int64
+	// uploadMaxBytes limits the body of an upload request.
+	uploadMaxBytes int64
+	// archiveLimits bounds what a compressed upload may expand to.
+	archiveLimits archive.Limits
+	// rules are the team rules applied to every row, read when a job starts.
+	rules *rules.Store
+	// dedupeWindow is how long repeated uploads are recognized. dedupeMu
+	// serializes their lookup.
+	dedupeWindow time.Duration
+	dedupeMu     sync.Mutex
+	// progressRows and progressInterval trigger progress feedback, at most
+	// once every progressMinGap. progress holds the state of running jobs.
+	progressRows     int
+	progressInterval time.Duration
+	progressMinGap   time.Duration
+	progress         progressTracker
+	// running counts the workers; stopping rejects uploads once Shutdown was
+	// called.
+	running  sync.WaitGroup
+	stopping atomic.Bool
+	// shutdownGrace bounds the wait for stopped jobs after the Shutdown
+	// deadline.
+	shutdownGrace time.Duration
+	// maxActivePerOwner is resolved in NewOpeningCSVService; zero means the
+	// default for the configured number of workers.
+	maxActivePerOwner int
+	// errorReportTTL is how long the upload of a finished job is kept for
+	// its error report; zero keeps it. stop ends the sweep on Shutdown.
+	errorReportTTL time.Duration
+	stop           chan struct{}
+	stopOnce       sync.Once
+}
+
+type OpeningCSVServiceOption func(*OpeningCSVService)
+
+// WithImportJobs persists the status of every job so it can be queried after
+// the upload returns.
+func WithImportJobs(jobRepo repository.ImportJobRepository) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		s.jobRepo = jobRepo
+	}
+}
+
+// WithBatchSize sets how many rows are validated and inserted at a time.
+func WithBatchSize(size int) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		if size > 0 {
+			s.batchSize = size
+		}
+	}
+}
+
+// WithParseWorkers sets how many goroutines validate the rows of a batch.
+func WithParseWorkers(workers int) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		if workers > 0 {
+			s.parseWorkers = workers
+		}
+	}
+}
+
+// WithSpoolDir sets where uploads are written while they wait to be processed.
+func WithSpoolDir(dir string) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		if dir != "" {
+			s.spoolDir = dir
+		}
+	}
+}
+
+// WithParser registers the parser of an upload format, replacing the
+// built-in one.
+func WithParser(format string, parser importer.Parser) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		s.parsers[format] = parser
+	}
+}
+
+// WithWorkers sets how many jobs are processed at the same time.
+func WithWorkers(workers int) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		if workers > 0 {
+			s.workers = workers
+		}
+	}
+}
+
+// WithJobTimeout limits how long a single job may run before it is rolled back.
+func WithJobTimeout(timeout time.Duration) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		if timeout > 0 {
+			s.jobTimeout = timeout
+		}
+	}
+}
+
+// WithMaxActivePerOwner caps how many jobs of the same user run at once. By
+// default one worker is always left for other users.
+func WithMaxActivePerOwner(limit int) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		if limit > 0 {
+			s.maxActivePerOwner = limit
+		}
+	}
 }
 
-func NewOpeningCSVService(repo repository.OpeningRepository, producer messaging.FeedbackProducer, queueSize int) *OpeningCSVService {
-	return &OpeningCSVService{
-		logger:   slog.Default().With("group", "opening_csv_service"),
-		repo:     repo,
-		producer: producer,
-		jobs:     make(chan OpeningCSVJob, queueSize),
+// NewOpeningCSVService creates the service. queueSize is the number of jobs
+// that may wait for a worker.
+func NewOpeningCSVService(repo repository.OpeningRepository, producer messaging.FeedbackProducer, queueSize int, opts ...OpeningCSVServiceOption) *OpeningCSVService {
+	s := &OpeningCSVService{
+		logger:           slog.Default().With("group", "opening_csv_service"),
+		repo:             repo,
+		producer:         producer,
+		parsers:          importer.Parsers(),
+		spoolDir:         filepath.Join(os.TempDir(), "opportunities-csv"),
+		batchSize:        defaultCSVBatchSize,
+		parseWorkers:     runtime.NumCPU(),
+		workers:          defaultCSVWorkers,
+		jobTimeout:       defaultCSVJobTimeout,
+		validateMaxBytes: defaultValidateMaxBytes,
+		uploadMaxBytes:   defaultUploadMaxBytes,
+		archiveLimits:    archive.Limits{MaxEntries: defaultArchiveMaxEntries, MaxBytes: defaultArchiveMaxBytes},
+		dedupeWindow:     defaultDedupeWindow,
+		progressRows:     defaultProgressRows,
+		progressInterval: defaultProgressInterval,
+		progressMinGap:   minProgressGap,
+		shutdownGrace:    defaultShutdownGrace,
+		stop:             make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.maxActivePerOwner == 0 {
+		s.maxActivePerOwner = max(1, s.workers-1)
 	}
+	s.queue = newJobScheduler(queueSize, s.maxActivePerOwner)
+
+	return s
 }
 
+// Start launches the workers. They stop taking jobs when ctx is done.
 func (s *OpeningCSVService) Start(ctx context.Context) {
 	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				s.logger.Info("opening csv service stopped")
-				return
-			case job := <-s.jobs:
-				s.processJob(ctx, job)
-			}
-		}
+		<-ctx.Done()
+		s.queue.close()
+		s.logger.Info("opening csv service stopped")
 	}()
+
+	for range s.workers {
+		s.running.Add(1)
+		go func() {
+			defer s.running.Done()
+			for {
+				job, jobCtx, ok := s.queue.next(ctx)
+				if !ok {
+					return
+				}
+
+				s.runJob(jobCtx, job)
+			}
+		}()
+	}
+
+	s.startErrorReportSweep(ctx)
 }
 
-func (s *OpeningCSVService) Enqueue(job OpeningCSVJob) error {
+// Shutdown stops accepting uploads, lets the workers finish their running
+// jobs and stops them. Queued jobs stay in the job store and are restored on
+// the next start. When ctx ends first, the running jobs are stopped too: they
+// roll back at the next chunk and are queued again, and Shutdown returns an
+// error. A job stuck past a short grace period is left behind.
+func (s *OpeningCSVService) Shutdown(ctx context.Context) error {
+	s.stopping.Store(true)
+	s.queue.close()
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	stopped := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(stopped)
+	}()
+
 	select {
-	case s.jobs <- job:
+	case <-stopped:
+		s.logger.Info("csv workers stopped")
 		return nil
-	default:
+	case <-ctx.Done():
+	}
+
+	interrupted := s.queue.cancelAll(ErrCSVServiceStopped)
+	s.logger.Warn("stopping running csv jobs", slog.Int("jobs", interrupted))
+	// Jobs check their context before every chunk, so this wait is usually
+	// short; a job stuck in a database call must not hold the exit forever.
+	grace := time.NewTimer(s.shutdownGrace)
+	defer grace.Stop()
+
+	select {
+	case <-stopped:
+		return fmt.Errorf("%d running csv jobs were interrupted: %w", interrupted, context.Cause(ctx))
+	case <-grace.C:
+		return fmt.Errorf("%d running csv jobs did not stop within %s: %w", interrupted, s.shutdownGrace, context.Cause(ctx))
+	}
+}
+
+func (s *OpeningCSVService) runJob(ctx context.Context, job OpeningCSVJob) {
+	defer s.queue.done(job)
+
+	ctx, cancel := context.WithTimeout(ctx, s.jobTimeout)
+	defer cancel()
+
+	s.processJob(ctx, job)
+}
+
+// QueueStats returns the state of the whole queue and of the owner's jobs.
+func (s *OpeningCSVService) QueueStats(owner string) (QueueStats, QueueStats) {
+	total, mine := s.queue.stats(owner)
+	total.Workers = s.workers
+
+	return total, mine
+}
+
+func (s *OpeningCSVService) Enqueue(job OpeningCSVJob) error {
+	if s.stopping.Load() {
+		s.removeSpool(job.Path)
+		return ErrCSVServiceStopped
+	}
+
+	if job.Format == "" {
+		job.Format = importer.FormatCSV
+	}
+
+	if s.jobRepo != nil {
+		// Tracked jobs are restored after a restart, which needs the upload on
+		// disk.
+		if job.Path == "" {
+			path, err := s.Spool(bytes.NewReader(job.Content))
+			if err != nil {
+				return fmt.Errorf("failed to persist csv job: %w", err)
+			}
+			job.Path = path
+			job.Content = nil
+		}
+
+		err := s.jobRepo.Create(&schemas.ImportJob{
+			RequestID:            job.RequestID,
+			BatchID:              job.BatchID,
+			Owner:                job.Owner,
+			FileName:             job.FileName,
+			Format:               job.Format,
+			Mode:                 job.Mode,
+			Upsert:               job.Upsert,
+			Source:               job.Source,
+			Status:               schemas.ImportJobQueued,
+			QueuedAt:             time.Now().UTC(),
+			IdempotencyKey:       job.IdempotencyKey,
+			ContentHash:          job.ContentHash,
+			CloseMissing:         job.CloseMissing,
+			SpoolPath:            job.Path,
+			Mapping:              job.Columns.Mapping,
+			RejectUnknownColumns: job.Columns.RejectUnknownColumns,
+			Delimiter:            job.Columns.Delimiter,
+			Encoding:             job.Columns.Encoding,
+			Locale:               job.Columns.Locale,
+		})
+		if err != nil {
+			s.removeSpool(job.Path)
+			return fmt.Errorf("failed to persist csv job: %w", err)
+		}
+	}
+
+	if !s.queue.push(job) {
+		s.removeSpool(job.Path)
+		s.recordResult(messaging.OpeningCSVFeedback{
+			RequestID:  job.RequestID,
+			Status:     "error",
+			ErrorCount: 1,
+			Message:    ErrCSVQueueFull.Error(),
+		})
 		return ErrCSVQueueFull
 	}
+
+	return nil
+}
+
+// Restore queues again the jobs a previous process accepted but did not
+// finish. A job interrupted mid-run committed nothing, because its final
+// status is committed together with its rows, so running it again cannot
+// insert rows twice.
+func (s *OpeningCSVService) Restore() error {
+	if s.jobRepo == nil {
+		return nil
+	}
+
+	records, err := s.jobRepo.ListUnfinished()
+	if err != nil {
+		return fmt.Errorf("failed to list unfinished csv jobs: %w", err)
+	}
+
+	for _, record := range records {
+		s.restoreJob(record)
+	}
+
+	return nil
+}
+
+func (s *OpeningCSVService) restoreJob(record schemas.ImportJob) {
+	logger := s.logger.With("request_id", record.RequestID)
+
+	fail := func(err error) {
+		logger.Error("failed to restore csv job", slog.String("error", err.Error()))
+		s.removeSpool(record.SpoolPath)
+		s.finishJob(context.Background(), messaging.OpeningCSVFeedback{
+			RequestID:  record.RequestID,
+			Format:     record.Format,
+			Status:     "error",
+			ErrorCount: 1,
+			Message:    err.Error(),
+			Timestamp:  time.Now().UTC(),
+		})
+	}
+
+	if record.Status == schemas.ImportJobRunning {
+		if record.Attempts >= maxCSVJobAttempts {
+			fail(ErrCSVJobInterrupted)
+			return
+		}
+
+		interrupted := time.Now().UTC()
+		record.Status = schemas.ImportJobQueued
+		record.InterruptedAt = &interrupted
+
+		if err := s.jobRepo.Update(&record); err != nil {
+			logger.Error("failed to update csv job", slog.String("error", err.Error()))
+			return
+		}
+
+		logger.Warn("csv job was interrupted, retrying", slog.Int("attempts", record.Attempts))
+	}
+
+	if !spoolExists(record.SpoolPath) {
+		fail(ErrCSVUploadLost)
+		return
+	}
+
+	s.queue.restore(OpeningCSVJob{
+		RequestID:    record.RequestID,
+		Owner:        record.Owner,
+		FileName:     record.FileName,
+		Format:       record.Format,
+		Mode:         record.Mode,
+		Upsert:       record.Upsert,
+		CloseMissing: record.CloseMissing,
+		Source:       record.Source,
+		Columns: csvutil.ParseOptions{
+			Mapping:              record.Mapping,
+			RejectUnknownColumns: record.RejectUnknownColumns,
+			Delimiter:            record.Delimiter,
+			Encoding:             record.Encoding,
+			Locale:               record.Locale,
+		},
+		Path: record.SpoolPath,
+	})
+	logger.Info("csv job restored")
+}
+
+// Cancel drops a queued job or asks a running one to stop. A running job
+// rolls back and publishes the cancelled status on its own, so Cancel reports
+// true while that is still pending.
+func (s *OpeningCSVService) Cancel(requestID string) (bool, error) {
+	if job, ok := s.queue.dequeue(requestID); ok {
+		s.removeSpool(job.Path)
+		s.finishJob(context.Background(), messaging.OpeningCSVFeedback{
+			RequestID: requestID,
+			Format:    job.Format,
+			Status:    "cancelled",
+			Message:   ErrCSVJobCancelled.Error(),
+			Timestamp: time.Now().UTC(),
+		})
+		s.logger.Info("queued csv job cancelled", slog.String("request_id", requestID))
+		return false, nil
+	}
+
+	if s.queue.cancelRunning(requestID, ErrCSVJobCancelled) {
+		s.logger.Info("cancelling running csv job", slog.String("request_id", requestID))
+		return true, nil
+	}
+
+	return false, ErrCSVJobNotCancelable
+}
+
+// Parser returns the parser of an upload format.
+func (s *OpeningCSVService) Parser(format string) (importer.Parser, error) {
+	if format == "" {
+		format = importer.FormatCSV
+	}
+
+	parser, ok := s.parsers[format]
+	if !ok {
+		return nil, importer.ErrUnsupportedFormat
+	}
+
+	return parser, nil
+}
+
+func (s *OpeningCSVService) GetJob(requestID string) (schemas.ImportJob, error) {
+	if s.jobRepo == nil {
+		return schemas.ImportJob{}, ErrJobTrackingDisabled
+	}
+
+	job, err := s.jobRepo.Get(requestID)
+	if err != nil {
+		return schemas.ImportJob{}, err
+	}
+
+	s.progress.apply(&job)
+	return job, nil
+}
+
+func (s *OpeningCSVService) ListJobs(owner string, page, pageSize int) ([]schemas.ImportJob, int64, error) {
+	if s.jobRepo == nil {
+		return nil, 0, ErrJobTrackingDisabled
+	}
+
+	jobs, total, err := s.jobRepo.ListByOwner(owner, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range jobs {
+		s.progress.apply(&jobs[i])
+	}
+
+	return jobs, total, nil
+}
+
+func (s *OpeningCSVService) RowErrors(requestID string) ([]schemas.ImportRowError, error) {
+	if s.jobRepo == nil {
+		return nil, ErrJobTrackingDisabled
+	}
+
+	return s.jobRepo.ListRowErrors(requestID)
+}
+
+// ErrorReport is the uploaded CSV of a failed job together with its row
+// errors.
+type ErrorReport struct {
+	job       OpeningCSVJob
+	rowErrors []csvutil.RowError
+}
+
+// WriteCSV streams the uploaded CSV to w with an extra "error" column.
+func (r *ErrorReport) WriteCSV(w io.Writer) error {
+	source, err := r.job.open()
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	return csvutil.AnnotateErrorsWithOptions(source, w, r.rowErrors, r.job.Columns)
+}
+
+// ErrorReport loads the report of a job. Jobs without row errors have no
+// stored file and return repository.ErrImportJobFileNotFound; uploads in other
+// formats return ErrErrorReportFormat.
+func (s *OpeningCSVService) ErrorReport(requestID string) (*ErrorReport, error) {
+	if s.jobRepo == nil {
+		return nil, ErrJobTrackingDisabled
+	}
+
+	record, err := s.jobRepo.Get(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Format != "" && record.Format != importer.FormatCSV {
+		return nil, ErrErrorReportFormat
+	}
+
+	file, err := s.jobRepo.GetFile(requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowErrors, err := s.jobRepo.ListRowErrors(requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	csvErrors := make([]csvutil.RowError, 0, len(rowErrors))
+	for _, rowErr := range rowErrors {
+		csvErrors = append(csvErrors, csvutil.RowError{
+			LineNumber: rowErr.LineNumber,
+			Message:    rowErr.Message,
+		})
+	}
+
+	return &ErrorReport{
+		job: OpeningCSVJob{
+			RequestID: requestID,
+			Columns:   csvutil.ParseOptions{Delimiter: record.Delimiter, Encoding: record.Encoding},
+			Path:      file.Path,
+			Content:   file.Content,
+		},
+		rowErrors: csvErrors,
+	}, nil
 }
 
 func (s *OpeningCSVService) processJob(ctx context.Context, job OpeningCSVJob) {
@@ -63,124 +626,587 @@ func (s *OpeningCSVService) processJob(ctx context.Context, job OpeningCSVJob) {
 	logger := s.logger.With("request_id", job.RequestID)
 
 	logger.Info("starting csv processing")
+	record, tracked := s.markRunning(job.RequestID, startTime)
+
+	keepFile := false
+	defer func() {
+		if !keepFile {
+			s.removeSpool(job.Path)
+		}
+	}()
+
+	dialect := csvutil.Dialect{}
+	normalized := newNormalizationReport()
+
+	fail := func(feedback messaging.OpeningCSVFeedback) {
+		feedback.RequestID = job.RequestID
+		feedback.Format = job.Format
+		feedback.Delimiter = dialect.Delimiter
+		feedback.Encoding = dialect.Encoding
+		feedback.BOM = dialect.BOM
+		normalized.apply(&feedback)
+		if feedback.Status == "" {
+			feedback.Status = "error"
+		}
+		feedback.DurationMS = time.Since(startTime).Milliseconds()
+		feedback.Timestamp = time.Now().UTC()
+		s.finishJob(ctx, feedback)
+	}
 
-	parsedRows, rowErrors, err := csvutil.ParseAndValidate(job.Content)
+	source, err := job.open()
 	if err != nil {
-		logger.Error("failed to parse csv", slog.String("error", err.Error()))
-		s.publishFeedback(ctx, messaging.OpeningCSVFeedback{
-			RequestID:      job.RequestID,
-			Status:         "error",
-			TotalRows:      0,
-			ProcessedRows:  0,
-			DurationMS:     time.Since(startTime).Milliseconds(),
-			ErrorCount:     1,
-			FirstErrorLine: 0,
-			Message:        err.Error(),
-			Timestamp:      time.Now().UTC(),
-		})
+		logger.Error("failed to open csv", slog.String("error", err.Error()))
+		fail(messaging.OpeningCSVFeedback{ErrorCount: 1, Message: "failed to open csv file"})
 		return
 	}
+	defer source.Close()
 
-	totalRows := len(parsedRows) + len(rowErrors)
-	if len(rowErrors) > 0 {
-		for _, rowErr := range rowErrors {
-			logger.Error("csv validation error",
-				slog.Int("line_number", rowErr.LineNumber),
-				slog.String("error", rowErr.Message))
-		}
+	parser, err := s.Parser(job.Format)
+	if err != nil {
+		logger.Error("unsupported upload format", slog.String("format", job.Format))
+		fail(messaging.OpeningCSVFeedback{ErrorCount: 1, Message: err.Error()})
+		return
+	}
 
-		s.publishFeedback(ctx, messaging.OpeningCSVFeedback{
-			RequestID:      job.RequestID,
-			Status:         "error",
-			TotalRows:      totalRows,
-			ProcessedRows:  0,
-			DurationMS:     time.Since(startTime).Milliseconds(),
-			ErrorCount:     len(rowErrors),
-			FirstErrorLine: rowErrors[0].LineNumber,
-			Message:        "csv validation failed",
-			Timestamp:      time.Now().UTC(),
-		})
+	counter := &countingReader{r: source}
+	progress := s.newProgressReporter(job, counter, startTime)
+	defer s.progress.clear(job.RequestID)
+
+	job.Columns.Rules = s.rowRules(job.Owner)
+	stream, err := parser.Open(counter, job.Columns)
+	if err != nil {
+		logger.Error("failed to parse upload", slog.String("error", err.Error()))
+		fail(messaging.OpeningCSVFeedback{ErrorCount: 1, Message: err.Error()})
 		return
 	}
+	if reader, ok := stream.(importer.DialectReader); ok {
+		dialect = reader.Dialect()
+		logger.Info("csv dialect",
+			slog.String("delimiter", dialect.Delimiter),
+			slog.String("encoding", dialect.Encoding),
+			slog.Bool("bom", dialect.BOM))
+	}
 
 	tx, err := s.repo.BeginTx()
 	if err != nil {
 		logger.Error("failed to begin transaction", slog.String("error", err.Error()))
-		s.publishFeedback(ctx, messaging.OpeningCSVFeedback{
-			RequestID:      job.RequestID,
-			Status:         "error",
-			TotalRows:      totalRows,
-			ProcessedRows:  0,
-			DurationMS:     time.Since(startTime).Milliseconds(),
-			ErrorCount:     1,
-			FirstErrorLine: 0,
-			Message:        "failed to begin transaction",
-			Timestamp:      time.Now().UTC(),
-		})
+		fail(messaging.OpeningCSVFeedback{ErrorCount: 1, Message: "failed to begin transaction"})
 		return
 	}
 
+	txDone := false
+	rollback := func() {
+		if txDone {
+			return
+		}
+		txDone = true
+		tx.Rollback()
+		logger.Error("transaction rolled back", slog.Bool("transaction_rolled_back", true))
+	}
+
+	totalRows := 0
 	processed := 0
-	for _, row := range parsedRows {
-		opening := row.Opening
-		if err := s.repo.CreateWithTx(tx, &opening); err != nil {
+	counts := importCounts{}
+	// Row errors are written in batches with the job transaction, so only
+	// their count and first line stay in memory.
+	rowErrors := newRowErrorSink(s.jobRepo, tx, job.RequestID)
+	failRowErrors := func(err error) {
+		logger.Error("failed to store csv row errors", slog.String("error", err.Error()))
+		rollback()
+		fail(messaging.OpeningCSVFeedback{TotalRows: totalRows, ErrorCount: 1, Message: "failed to record csv row errors"})
+	}
+
+	// stopped rolls back and reports the job once its context ends, because
+	// it was cancelled, timed out or the service stopped.
+	stopped := func() bool {
+		if ctx.Err() == nil {
+			return false
+		}
+
+		// A job stopped by Shutdown runs again on the next start.
+		if errors.Is(context.Cause(ctx), ErrCSVServiceStopped) && tracked {
+			logger.Warn("csv job interrupted by shutdown", slog.Int("total_rows", totalRows))
+			rollback()
+			keepFile = true
+			s.requeue(record)
+			return true
+		}
+
+		feedback := messaging.OpeningCSVFeedback{TotalRows: totalRows, ErrorCount: 1, Message: "csv processing stopped"}
+		switch cause := context.Cause(ctx); {
+		case errors.Is(cause, ErrCSVJobCancelled):
+			feedback = messaging.OpeningCSVFeedback{TotalRows: totalRows, Status: "cancelled", Message: cause.Error()}
+		case errors.Is(cause, context.DeadlineExceeded):
+			feedback.Message = ErrCSVJobTimeout.Error()
+		}
+
+		logger.Error(feedback.Message, slog.Int("total_rows", totalRows))
+		rollback()
+		fail(feedback)
+		return true
+	}
+
+	for {
+		if stopped() {
+			return
+		}
+
+		parsedRows, chunkErrors, err := stream.ReadChunk(s.batchSize, s.parseWorkers)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logger.Error("failed to parse csv", slog.String("error", err.Error()))
+			rollback()
+			fail(messaging.OpeningCSVFeedback{TotalRows: totalRows, ErrorCount: 1, Message: err.Error()})
+			return
+		}
+
+		totalRows += len(parsedRows) + len(chunkErrors)
+		for _, rowErr := range chunkErrors {
+			logger.Error("csv validation error",
+				slog.Int("line_number", rowErr.LineNumber),
+				slog.String("error", rowErr.Message))
+		}
+		// In strict mode the job fails at the first invalid row, so its
+		// openings are rolled back right away and the errors are written on
+		// their own.
+		if len(chunkErrors) > 0 && job.Mode != ImportModePartial && !txDone {
+			rollback()
+			rowErrors.detach()
+		}
+		if err := rowErrors.add(chunkErrors); err != nil {
+			failRowErrors(err)
+			return
+		}
+		normalized.add(parsedRows)
+		progress.report(ctx, PhaseParsing, totalRows, processed)
+
+		// In strict mode nothing else is inserted after an invalid row, but the
+		// rest of the file is still read so every error is reported.
+		if rowErrors.count > 0 && job.Mode != ImportModePartial {
+			continue
+		}
+
+		line, err := s.saveChunk(tx, job, parsedRows, &counts)
+		if err != nil {
 			logger.Error("failed to insert csv row",
-				slog.Int("line_number", row.LineNumber),
+				slog.Int("line_number", line),
 				slog.String("error", err.Error()))
-			tx.Rollback()
-			logger.Error("transaction rolled back", slog.Bool("transaction_rolled_back", true))
+			rollback()
 
-			s.publishFeedback(ctx, messaging.OpeningCSVFeedback{
-				RequestID:      job.RequestID,
-				Status:         "error",
+			keepFile = s.recordRowErrors(job, []csvutil.RowError{{
+				LineNumber: line,
+				Message:    "failed to insert row",
+			}})
+			fail(messaging.OpeningCSVFeedback{
 				TotalRows:      totalRows,
-				ProcessedRows:  0,
-				DurationMS:     time.Since(startTime).Milliseconds(),
 				ErrorCount:     1,
-				FirstErrorLine: row.LineNumber,
-				Message:        fmt.Sprintf("failed to insert line %d", row.LineNumber),
-				Timestamp:      time.Now().UTC(),
+				FirstErrorLine: line,
+				Message:        fmt.Sprintf("failed to insert line %d", line),
 			})
 			return
 		}
 
-		processed++
+		processed += len(parsedRows)
+		progress.report(ctx, PhaseInserting, totalRows, processed)
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		logger.Error("failed to commit transaction", slog.String("error", err.Error()))
-		tx.Rollback()
-		logger.Error("transaction rolled back", slog.Bool("transaction_rolled_back", true))
-		s.publishFeedback(ctx, messaging.OpeningCSVFeedback{
-			RequestID:      job.RequestID,
-			Status:         "error",
-			TotalRows:      totalRows,
-			ProcessedRows:  0,
-			DurationMS:     time.Since(startTime).Milliseconds(),
-			ErrorCount:     1,
-			FirstErrorLine: 0,
-			Message:        "failed to commit transaction",
-			Timestamp:      time.Now().UTC(),
-		})
-		return
+	if rowErrors.count > 0 {
+		if job.Mode != ImportModePartial || processed == 0 {
+			// No opening was saved. A partial job commits the transaction,
+			// which then holds only its row errors.
+			err := rowErrors.flush()
+			if err == nil && !txDone {
+				txDone = true
+				err = tx.Commit().Error
+			}
+			if err != nil {
+				failRowErrors(err)
+				return
+			}
+
+			keepFile = s.recordRowErrors(job, nil)
+			fail(messaging.OpeningCSVFeedback{
+				TotalRows:      totalRows,
+				SkippedRows:    rowErrors.count,
+				ErrorCount:     rowErrors.count,
+				FirstErrorLine: rowErrors.firstLine,
+				Message:        "csv validation failed",
+			})
+			return
+		}
+
+		if err := rowErrors.flush(); err != nil {
+			failRowErrors(err)
+			return
+		}
+
+		logger.Info("skipping invalid csv rows", slog.Int("skipped_rows", rowErrors.count))
 	}
 
-	logger.Info("csv processing completed",
-		slog.Int("total_rows", totalRows),
-		slog.Int("processed_rows", processed),
-		slog.Int64("duration_ms", time.Since(startTime).Milliseconds()))
+	if job.Upsert && job.CloseMissing {
+		closed, err := s.repo.CloseMissingWithTx(tx, job.Owner, job.Source, job.RequestID, time.Now().UTC())
+		if err != nil {
+			logger.Error("failed to close missing openings", slog.String("error", err.Error()))
+			rollback()
+			fail(messaging.OpeningCSVFeedback{TotalRows: totalRows, ErrorCount: 1, Message: "failed to close missing openings"})
+			return
+		}
+
+		counts.closed = int(closed)
+	}
 
-	s.publishFeedback(ctx, messaging.OpeningCSVFeedback{
+	feedback := messaging.OpeningCSVFeedback{
 		RequestID:      job.RequestID,
+		Format:         job.Format,
 		Status:         "success",
 		TotalRows:      totalRows,
 		ProcessedRows:  processed,
-		DurationMS:     time.Since(startTime).Milliseconds(),
+		InsertedRows:   counts.created,
+		UpdatedRows:    counts.updated,
+		UnchangedRows:  counts.unchanged,
+		ClosedRows:     counts.closed,
 		ErrorCount:     0,
 		FirstErrorLine: 0,
 		Message:        "csv processed successfully",
-		Timestamp:      time.Now().UTC(),
-	})
+		Delimiter:      dialect.Delimiter,
+		Encoding:       dialect.Encoding,
+		BOM:            dialect.BOM,
+	}
+	normalized.apply(&feedback)
+	if rowErrors.count > 0 {
+		feedback.Status = "partial_success"
+		feedback.SkippedRows = rowErrors.count
+		feedback.ErrorCount = rowErrors.count
+		feedback.FirstErrorLine = rowErrors.firstLine
+		feedback.Message = fmt.Sprintf("csv processed with %d skipped rows", rowErrors.count)
+	}
+
+	if stopped() {
+		return
+	}
+
+	// The final status is committed with the rows, so a restart never runs
+	// the job again once its rows are saved.
+	if tracked {
+		applyResult(&record, feedback)
+		if err := s.jobRepo.UpdateWithTx(tx, &record); err != nil {
+			logger.Error("failed to update csv job", slog.String("error", err.Error()))
+			rollback()
+			fail(messaging.OpeningCSVFeedback{TotalRows: totalRows, ErrorCount: 1, Message: "failed to record csv job"})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Error("failed to commit transaction", slog.String("error", err.Error()))
+		rollback()
+		fail(messaging.OpeningCSVFeedback{TotalRows: totalRows, ErrorCount: 1, Message: "failed to commit transaction"})
+		return
+	}
+
+	if rowErrors.count > 0 {
+		keepFile = s.recordRowErrors(job, nil)
+	}
+
+	logger.Info("csv processing completed",
+		slog.Int("total_rows", totalRows),
+		slog.Int("processed_rows", processed),
+		slog.Int("skipped_rows", rowErrors.count),
+		slog.Int64("duration_ms", time.Since(startTime).Milliseconds()))
+
+	feedback.DurationMS = time.Since(startTime).Milliseconds()
+	feedback.Timestamp = time.Now().UTC()
+	if !tracked {
+		s.recordResult(feedback)
+	}
+	s.publishFeedback(context.WithoutCancel(ctx), feedback)
+}
+
+// saveChunk stores one chunk of valid rows. On error it returns the line that
+// could not be saved. With CloseMissing every saved row is stamped with the
+// job's request ID, so the openings it did not list can be closed without
+// keeping their IDs.
+func (s *OpeningCSVService) saveChunk(tx *gorm.DB, job OpeningCSVJob, rows []csvutil.ParsedOpening, counts *importCounts) (int, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	openings := make([]schemas.Openings, len(rows))
+	for i, row := range rows {
+		openings[i] = row.Opening
+		openings[i].Owner = job.Owner
+		openings[i].Source = job.Source
+		if job.CloseMissing {
+			openings[i].ImportRun = job.RequestID
+		}
+	}
+
+	if job.Upsert {
+		for i := range openings {
+			if err := s.saveRow(tx, job, &openings[i], counts); err != nil {
+				return rows[i].LineNumber, err
+			}
+		}
+
+		return 0, nil
+	}
+
+	if err := tx.SavePoint(batchSavePoint).Error; err != nil {
+		return rows[0].LineNumber, err
+	}
+
+	if err := s.repo.CreateBatchWithTx(tx, openings); err != nil {
+		// Retry row by row to find the line that failed. When every row goes
+		// in on its own, the chunk is saved.
+		if rollbackErr := tx.RollbackTo(batchSavePoint).Error; rollbackErr != nil {
+			return rows[0].LineNumber, err
+		}
+
+		for i := range openings {
+			openings[i].ID = 0
+			if rowErr := s.repo.CreateWithTx(tx, &openings[i]); rowErr != nil {
+				return rows[i].LineNumber, rowErr
+			}
+		}
+	}
+
+	counts.created += len(openings)
+
+	return 0, nil
+}
+
+type importCounts struct {
+	created   int
+	updated   int
+	unchanged int
+	closed    int
+}
+
+// saveRow inserts the opening or, in upsert mode, updates the existing one it
+// matches.
+func (s *OpeningCSVService) saveRow(tx *gorm.DB, job OpeningCSVJob, opening *schemas.Openings, counts *importCounts) error {
+	if !job.Upsert {
+		if err := s.repo.CreateWithTx(tx, opening); err != nil {
+			return err
+		}
+
+		counts.created++
+		return nil
+	}
+
+	existing, found, err := s.repo.FindImportMatchWithTx(tx, job.Owner, opening.ExternalID, opening.Link)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		if err := s.repo.CreateWithTx(tx, opening); err != nil {
+			return err
+		}
+
+		counts.created++
+		return nil
+	}
+
+	if sameImportedFields(existing, *opening) {
+		if opening.ImportRun != "" && existing.ImportRun != opening.ImportRun {
+			if err := s.repo.MarkImportRunWithTx(tx, existing.ID, opening.ImportRun); err != nil {
+				return err
+			}
+		}
+
+		counts.unchanged++
+		return nil
+	}
+
+	existing.Role = opening.Role
+	existing.Company = opening.Company
+	existing.Location = opening.Location
+	existing.Remote = opening.Remote
+	existing.Link = opening.Link
+	existing.Salary = opening.Salary
+	if opening.ExternalID != "" {
+		existing.ExternalID = opening.ExternalID
+	}
+	if opening.Source != "" {
+		existing.Source = opening.Source
+	}
+	if opening.ImportRun != "" {
+		existing.ImportRun = opening.ImportRun
+	}
+	existing.ClosedAt = nil
+
+	if err := s.repo.UpdateWithTx(tx, &existing); err != nil {
+		return err
+	}
+
+	counts.updated++
+	return nil
+}
+
+func sameImportedFields(existing, incoming schemas.Openings) bool {
+	return existing.Role == incoming.Role &&
+		existing.Company == incoming.Company &&
+		existing.Location == incoming.Location &&
+		existing.Remote == incoming.Remote &&
+		existing.Link == incoming.Link &&
+		existing.Salary == incoming.Salary &&
+		(incoming.ExternalID == "" || existing.ExternalID == incoming.ExternalID) &&
+		(incoming.Source == "" || existing.Source == incoming.Source) &&
+		existing.ClosedAt == nil
+}
+
+// finishJob publishes the final feedback and stores the same outcome in the
+// job record.
+func (s *OpeningCSVService) finishJob(ctx context.Context, feedback messaging.OpeningCSVFeedback) {
+	s.recordResult(feedback)
+	// The feedback is still sent when the job stopped because its context ended.
+	s.publishFeedback(context.WithoutCancel(ctx), feedback)
+}
+
+// markRunning stores the start of a run and returns the updated record. It
+// reports false when the job is not tracked.
+func (s *OpeningCSVService) markRunning(requestID string, startedAt time.Time) (schemas.ImportJob, bool) {
+	if s.jobRepo == nil {
+		return schemas.ImportJob{}, false
+	}
+
+	job, err := s.jobRepo.Get(requestID)
+	if err != nil {
+		s.logger.Error("failed to load csv job",
+			slog.String("request_id", requestID),
+			slog.String("error", err.Error()))
+		return schemas.ImportJob{}, false
+	}
+
+	started := startedAt.UTC()
+	job.Status = schemas.ImportJobRunning
+	job.StartedAt = &started
+	job.Attempts++
+
+	// Row errors are stored while the job runs, so an interrupted run may
+	// have left some behind.
+	if job.Attempts > 1 {
+		if err := s.jobRepo.DeleteRowErrors(requestID); err != nil {
+			s.logger.Error("failed to clear csv row errors",
+				slog.String("request_id", requestID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	if err := s.jobRepo.Update(&job); err != nil {
+		s.logger.Error("failed to update csv job",
+			slog.String("request_id", requestID),
+			slog.String("error", err.Error()))
+		return schemas.ImportJob{}, false
+	}
+
+	return job, true
+}
+
+// requeue stores a job stopped by Shutdown as queued, so Restore runs it
+// again.
+func (s *OpeningCSVService) requeue(job schemas.ImportJob) {
+	interrupted := time.Now().UTC()
+	job.Status = schemas.ImportJobQueued
+	job.InterruptedAt = &interrupted
+
+	if err := s.jobRepo.Update(&job); err != nil {
+		s.logger.Error("failed to requeue csv job",
+			slog.String("request_id", job.RequestID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// recordRowErrors stores the uploaded file, along with rowErrors that were
+// not streamed while the job ran, before the job is marked as failed, so the
+// report is available as soon as the status changes. It reports whether a spooled upload must be kept for the report.
+func (s *OpeningCSVService) recordRowErrors(job OpeningCSVJob, rowErrors []csvutil.RowError) bool {
+	if s.jobRepo == nil {
+		return false
+	}
+
+	records := make([]schemas.ImportRowError, 0, len(rowErrors))
+	for _, rowErr := range rowErrors {
+		records = append(records, schemas.ImportRowError{
+			RequestID:  job.RequestID,
+			LineNumber: rowErr.LineNumber,
+			Message:    rowErr.Message,
+		})
+	}
+
+	file := &schemas.ImportJobFile{RequestID: job.RequestID, Path: job.Path}
+	if job.Path == "" {
+		file.Content = job.Content
+	}
+
+	if err := s.jobRepo.SaveFailure(file, records); err != nil {
+		s.logger.Error("failed to store csv row errors",
+			slog.String("request_id", job.RequestID),
+			slog.String("error", err.Error()))
+		return false
+	}
+
+	return job.Path != ""
+}
+
+func (s *OpeningCSVService) recordResult(feedback messaging.OpeningCSVFeedback) {
+	if s.jobRepo == nil {
+		return
+	}
+
+	job, err := s.jobRepo.Get(feedback.RequestID)
+	if err != nil {
+		s.logger.Error("failed to load csv job",
+			slog.String("request_id", feedback.RequestID),
+			slog.String("error", err.Error()))
+		return
+	}
+
+	applyResult(&job, feedback)
+
+	if err := s.jobRepo.Update(&job); err != nil {
+		s.logger.Error("failed to update csv job",
+			slog.String("request_id", feedback.RequestID),
+			slog.String("error", err.Error()))
+	}
+}
+
+func applyResult(job *schemas.ImportJob, feedback messaging.OpeningCSVFeedback) {
+	finished := time.Now().UTC()
+	switch feedback.Status {
+	case "success":
+		job.Status = schemas.ImportJobSucceeded
+		job.ErrorSummary = ""
+	case "partial_success":
+		job.Status = schemas.ImportJobPartialSuccess
+		job.ErrorSummary = feedback.Message
+	case "cancelled":
+		job.Status = schemas.ImportJobCancelled
+		job.ErrorSummary = feedback.Message
+	default:
+		job.Status = schemas.ImportJobFailed
+		job.ErrorSummary = feedback.Message
+	}
+	job.TotalRows = feedback.TotalRows
+	job.ProcessedRows = feedback.ProcessedRows
+	job.InsertedRows = feedback.InsertedRows
+	job.UpdatedRows = feedback.UpdatedRows
+	job.UnchangedRows = feedback.UnchangedRows
+	job.ClosedRows = feedback.ClosedRows
+	job.SkippedRows = feedback.SkippedRows
+	job.ErrorCount = feedback.ErrorCount
+	job.FirstErrorLine = feedback.FirstErrorLine
+	if feedback.Encoding != "" {
+		job.Delimiter = feedback.Delimiter
+		job.Encoding = feedback.Encoding
+		job.BOM = feedback.BOM
+	}
+	job.NormalizedValues = feedback.NormalizedValues
+	job.Normalizations = feedback.Normalizations
+	job.FinishedAt = &finished
 }
 
 func (s *OpeningCSVService) publishFeedback(ctx context.Context, feedback messaging.OpeningCSVFeedback) {
@@ -1,12 +1,23 @@
 package service
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	csvutil "opportunities/internal/csv"
 	"opportunities/internal/messaging"
 	"opportunities/internal/repository"
+	"opportunities/internal/rules"
 	"opportunities/internal/schemas"
 
 	"github.com/glebarez/sqlite"
@@ -22,48 +33,89 @@ func (f *feedbackProducerSpy) PublishOpeningCSVFeedback(_ context.Context, feedb
 	return nil
 }
 
-type failOnSecondInsertRepo struct {
-	db      *gorm.DB
-	inserts int
+// failingInsertRepo fails every insert of the opening with failLink.
+type failingInsertRepo struct {
+	db       *gorm.DB
+	failLink string
 }
 
-func (r *failOnSecondInsertRepo) Create(opening *schemas.Openings) error {
+func (r *failingInsertRepo) Create(opening *schemas.Openings) error {
 	return r.db.Create(opening).Error
 }
 
-func (r *failOnSecondInsertRepo) CreateWithTx(tx *gorm.DB, opening *schemas.Openings) error {
-	r.inserts++
-	if r.inserts == 2 {
+func (r *failingInsertRepo) CreateWithTx(tx *gorm.DB, opening *schemas.Openings) error {
+	if opening.Link == r.failLink {
 		return errors.New("forced insert error")
 	}
 	return tx.Create(opening).Error
 }
 
-func (r *failOnSecondInsertRepo) BeginTx() (*gorm.DB, error) {
+func (r *failingInsertRepo) CreateBatchWithTx(tx *gorm.DB, openings []schemas.Openings) error {
+	for i := range openings {
+		if err := r.CreateWithTx(tx, &openings[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchFailingRepo fails every batch insert but accepts the rows one by one.
+type batchFailingRepo struct {
+	failingInsertRepo
+}
+
+func (r *batchFailingRepo) CreateBatchWithTx(*gorm.DB, []schemas.Openings) error {
+	return errors.New("forced batch insert error")
+}
+
+func (r *failingInsertRepo) BeginTx() (*gorm.DB, error) {
 	tx := r.db.Begin()
 	return tx, tx.Error
 }
 
-func (r *failOnSecondInsertRepo) Get(id string) (schemas.Openings, error) {
+func (r *failingInsertRepo) Get(id string) (schemas.Openings, error) {
 	var opening schemas.Openings
 	err := r.db.First(&opening, id).Error
 	return opening, err
 }
 
-func (r *failOnSecondInsertRepo) Delete(id string) error {
+func (r *failingInsertRepo) Delete(id string) error {
 	return r.db.Delete(&schemas.Openings{}, id).Error
 }
 
-func (r *failOnSecondInsertRepo) Update(opening *schemas.Openings) error {
+func (r *failingInsertRepo) Update(opening *schemas.Openings) error {
 	return r.db.Save(opening).Error
 }
 
-func (r *failOnSecondInsertRepo) List() ([]schemas.Openings, error) {
+func (r *failingInsertRepo) List(_ repository.OpeningFilter) ([]schemas.Openings, error) {
 	var openings []schemas.Openings
 	err := r.db.Find(&openings).Error
 	return openings, err
 }
 
+func (r *failingInsertRepo) ListInBatches(_ repository.OpeningFilter, batchSize int, fn func([]schemas.Openings) error) error {
+	var openings []schemas.Openings
+	return r.db.FindInBatches(&openings, batchSize, func(_ *gorm.DB, _ int) error {
+		return fn(openings)
+	}).Error
+}
+
+func (r *failingInsertRepo) UpdateWithTx(tx *gorm.DB, opening *schemas.Openings) error {
+	return tx.Save(opening).Error
+}
+
+func (r *failingInsertRepo) FindImportMatchWithTx(_ *gorm.DB, _, _, _ string) (schemas.Openings, bool, error) {
+	return schemas.Openings{}, false, nil
+}
+
+func (r *failingInsertRepo) MarkImportRunWithTx(*gorm.DB, uint, string) error {
+	return nil
+}
+
+func (r *failingInsertRepo) CloseMissingWithTx(_ *gorm.DB, _, _, _ string, _ time.Time) (int64, error) {
+	return 0, nil
+}
+
 func TestOpeningCSVService_ProcessJobSuccess(t *testing.T) {
 	db := openTestDB(t)
 	repo := repository.New(db)
@@ -73,6 +125,7 @@ func TestOpeningCSVService_ProcessJobSuccess(t *testing.T) {
 	content := []byte("role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,2000\n")
 	svc.processJob(context.Background(), OpeningCSVJob{
 		RequestID: "req-success",
+		Owner:     "uploader@test.com",
 		Content:   content,
 	})
 
@@ -83,6 +136,9 @@ func TestOpeningCSVService_ProcessJobSuccess(t *testing.T) {
 	if len(openings) != 1 {
 		t.Fatalf("expected 1 opening persisted, got %d", len(openings))
 	}
+	if openings[0].Owner != "uploader@test.com" {
+		t.Fatalf("expected owner uploader@test.com, got %s", openings[0].Owner)
+	}
 	if len(producer.messages) != 1 {
 		t.Fatalf("expected 1 feedback message, got %d", len(producer.messages))
 	}
@@ -126,7 +182,7 @@ func TestOpeningCSVService_ProcessJobValidationError(t *testing.T) {
 
 func TestOpeningCSVService_ProcessJobRollbackOnInsertError(t *testing.T) {
 	db := openTestDB(t)
-	repo := &failOnSecondInsertRepo{db: db}
+	repo := &failingInsertRepo{db: db, failLink: "https://acme2.com"}
 	producer := &feedbackProducerSpy{}
 	svc := NewOpeningCSVService(repo, producer, 1)
 
@@ -154,7 +210,1161 @@ func TestOpeningCSVService_ProcessJobRollbackOnInsertError(t *testing.T) {
 	}
 }
 
-func openTestDB(t *testing.T) *gorm.DB {
+func TestOpeningCSVService_ProcessJobSavesChunkRowByRow(t *testing.T) {
+	db := openTestDB(t)
+	repo := &batchFailingRepo{failingInsertRepo{db: db}}
+	producer := &feedbackProducerSpy{}
+	svc := NewOpeningCSVService(repo, producer, 1)
+
+	content := []byte("role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,1000\nGo Dev 2,Acme,BR,false,https://acme2.com,1000\n")
+	svc.processJob(context.Background(), OpeningCSVJob{
+		RequestID: "req-row-by-row",
+		Content:   content,
+	})
+
+	var openings []schemas.Openings
+	if err := db.Find(&openings).Error; err != nil {
+		t.Fatalf("unexpected db error: %v", err)
+	}
+	if len(openings) != 2 {
+		t.Fatalf("expected the retried rows to persist 2 openings, got %d", len(openings))
+	}
+	if len(producer.messages) != 1 {
+		t.Fatalf("expected 1 feedback message, got %d", len(producer.messages))
+	}
+	if producer.messages[0].Status != "success" {
+		t.Fatalf("expected success status, got %s (%s)", producer.messages[0].Status, producer.messages[0].Message)
+	}
+	if producer.messages[0].InsertedRows != 2 {
+		t.Fatalf("expected inserted_rows 2, got %d", producer.messages[0].InsertedRows)
+	}
+}
+
+func TestOpeningCSVService_TracksJobStatus(t *testing.T) {
+	db := openTestDB(t)
+	jobs := repository.NewImportJobRepository(db)
+	svc := NewOpeningCSVService(repository.New(db), &feedbackProducerSpy{}, 2, WithImportJobs(jobs))
+
+	okJob := OpeningCSVJob{
+		RequestID: "req-tracked-ok",
+		Owner:     "uploader@test.com",
+		FileName:  "ok.csv",
+		Content:   []byte("role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,2000\n"),
+	}
+	badJob := OpeningCSVJob{
+		RequestID: "req-tracked-bad",
+		Owner:     "uploader@test.com",
+		FileName:  "bad.csv",
+		Content:   []byte("role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,0\n"),
+	}
+
+	for _, job := range []OpeningCSVJob{okJob, badJob} {
+		if err := svc.Enqueue(job); err != nil {
+			t.Fatalf("unexpected enqueue error: %v", err)
+		}
+
+		record, err := svc.GetJob(job.RequestID)
+		if err != nil {
+			t.Fatalf("unexpected get error: %v", err)
+		}
+		if record.Status != schemas.ImportJobQueued {
+			t.Fatalf("expected queued status, got %s", record.Status)
+		}
+
+		svc.processJob(context.Background(), nextJob(t, svc))
+	}
+
+	record, _ := svc.GetJob(okJob.RequestID)
+	if record.Status != schemas.ImportJobSucceeded || record.ProcessedRows != 1 {
+		t.Fatalf("expected succeeded with 1 processed row, got %s with %d", record.Status, record.ProcessedRows)
+	}
+	if record.StartedAt == nil || record.FinishedAt == nil {
+		t.Fatalf("expected start and finish timestamps")
+	}
+
+	record, _ = svc.GetJob(badJob.RequestID)
+	if record.Status != schemas.ImportJobFailed || record.ErrorCount != 1 || record.ErrorSummary == "" {
+		t.Fatalf("expected failed job with error summary, got %+v", record)
+	}
+
+	listed, total, err := svc.ListJobs("uploader@test.com", 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected list error: %v", err)
+	}
+	if total != 2 || len(listed) != 1 {
+		t.Fatalf("expected 1 of 2 jobs on first page, got %d of %d", len(listed), total)
+	}
+}
+
+func TestOpeningCSVService_ReportsDialect(t *testing.T) {
+	db := openTestDB(t)
+	producer := &feedbackProducerSpy{}
+	svc := NewOpeningCSVService(repository.New(db), producer, 1,
+		WithImportJobs(repository.NewImportJobRepository(db)),
+		WithSpoolDir(t.TempDir()))
+
+	job := OpeningCSVJob{
+		RequestID: "req-dialect",
+		Owner:     "uploader@test.com",
+		Content:   []byte("role;company;location;remote;link;salary\nGo Dev;Constru\xe7\xe3o;S\xe3o Paulo;true;https://acme.com/dialect;2000\n"),
+	}
+	if err := svc.Enqueue(job); err != nil {
+		t.Fatalf("unexpected enqueue error: %v", err)
+	}
+	svc.processJob(context.Background(), nextJob(t, svc))
+
+	record, _ := svc.GetJob(job.RequestID)
+	if record.Status != schemas.ImportJobSucceeded || record.Delimiter != ";" || record.Encoding != "windows-1252" || record.BOM {
+		t.Fatalf("expected succeeded job read as ; and windows-1252, got %+v", record)
+	}
+	if len(producer.messages) != 1 || producer.messages[0].Delimiter != ";" || producer.messages[0].Encoding != "windows-1252" {
+		t.Fatalf("expected the dialect in the feedback, got %+v", producer.messages)
+	}
+
+	var opening schemas.Openings
+	if err := db.Where("link = ?", "https://acme.com/dialect").First(&opening).Error; err != nil {
+		t.Fatalf("unexpected db error: %v", err)
+	}
+	if opening.Company != "Construção" || opening.Location != "São Paulo" {
+		t.Fatalf("expected text converted to UTF-8, got %q / %q", opening.Company, opening.Location)
+	}
+}
+
+func TestOpeningCSVService_ReportsNormalizedValues(t *testing.T) {
+	db := openTestDB(t)
+	producer := &feedbackProducerSpy{}
+	svc := NewOpeningCSVService(repository.New(db), producer, 1,
+		WithImportJobs(repository.NewImportJobRepository(db)),
+		WithSpoolDir(t.TempDir()))
+
+	job := OpeningCSVJob{
+		RequestID: "req-locale",
+		Owner:     "uploader@test.com",
+		Columns:   csvutil.ParseOptions{Locale: csvutil.LocalePTBR},
+		Content: []byte("role;company;location;remote;link;salary\n" +
+			"Go Dev;Acme;BR;sim;https://acme.com/locale-1;R$ 5.000,00\n" +
+			"SRE;Acme;BR;não;https://acme.com/locale-2;120.000\n" +
+			"QA;Acme;BR;sim;https://acme.com/locale-3;5k\n"),
+	}
+	if err := svc.Enqueue(job); err != nil {
+		t.Fatalf("unexpected enqueue error: %v", err)
+	}
+	svc.processJob(context.Background(), nextJob(t, svc))
+
+	record, _ := svc.GetJob(job.RequestID)
+	if record.Status != schemas.ImportJobSucceeded || record.Locale != csvutil.LocalePTBR || record.NormalizedValues != 6 {
+		t.Fatalf("expected succeeded pt-BR job with 6 normalized values, got %+v", record)
+	}
+	expected := []schemas.ImportNormalization{
+		{Field: "remote", Raw: "sim", Value: "true", Count: 2, FirstLine: 2},
+		{Field: "salary", Raw: "R$ 5.000,00", Value: "5000", Count: 1, FirstLine: 2},
+		{Field: "remote", Raw: "não", Value: "false", Count: 1, FirstLine: 3},
+		{Field: "salary", Raw: "120.000", Value: "120000", Count: 1, FirstLine: 3},
+		{Field: "salary", Raw: "5k", Value: "5000", Count: 1, FirstLine: 4},
+	}
+	if !reflect.DeepEqual(record.Normalizations, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, record.Normalizations)
+	}
+	if len(producer.messages) != 1 || producer.messages[0].NormalizedValues != 6 {
+		t.Fatalf("expected the normalized values in the feedback, got %+v", producer.messages)
+	}
+
+	var opening schemas.Openings
+	if err := db.Where("link = ?", "https://acme.com/locale-2").First(&opening).Error; err != nil {
+		t.Fatalf("unexpected db error: %v", err)
+	}
+	if opening.Remote || opening.Salary != 120000 {
+		t.Fatalf("expected remote false and salary 120000, got %+v", opening)
+	}
+}
+
+func TestOpeningCSVService_StreamsRowErrors(t *testing.T) {
+	db := openTestDB(t)
+	jobRepo := repository.NewImportJobRepository(db)
+	producer := &feedbackProducerSpy{}
+	svc := NewOpeningCSVService(repository.New(db), producer, 1, WithImportJobs(jobRepo))
+
+	// More invalid rows than one batch, after a valid one.
+	const invalid = 2*rowErrorBatchSize + 50
+	var content strings.Builder
+	content.WriteString("role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com/valid,2000\n")
+	for i := range invalid {
+		fmt.Fprintf(&content, ",Acme,BR,true,https://acme.com/%d,2000\n", i)
+	}
+
+	for _, mode := range []string{ImportModeStrict, ImportModePartial} {
+		requestID := "req-row-errors-" + mode
+		if err := svc.Enqueue(OpeningCSVJob{RequestID: requestID, Owner: mode + "@test.com", Mode: mode, Content: []byte(content.String())}); err != nil {
+			t.Fatalf("unexpected enqueue error: %v", err)
+		}
+		svc.processJob(context.Background(), nextJob(t, svc))
+
+		feedback := producer.messages[len(producer.messages)-1]
+		if feedback.ErrorCount != invalid || feedback.FirstErrorLine != 3 {
+			t.Fatalf("%s: unexpected feedback: %+v", mode, feedback)
+		}
+
+		rowErrors, _ := jobRepo.ListRowErrors(requestID)
+		if len(rowErrors) != invalid || rowErrors[0].LineNumber != 3 {
+			t.Fatalf("%s: expected %d stored row errors, got %d", mode, invalid, len(rowErrors))
+		}
+
+		var saved int64
+		db.Model(&schemas.Openings{}).Where("owner = ?", mode+"@test.com").Count(&saved)
+		if mode == ImportModeStrict && saved != 0 || mode == ImportModePartial && saved != 1 {
+			t.Fatalf("%s: unexpected saved openings: %d", mode, saved)
+		}
+	}
+
+	// A job run again after an interruption does not list its errors twice.
+	record, _ := jobRepo.Get("req-row-errors-strict")
+	record.Status = schemas.ImportJobQueued
+	_ = jobRepo.Update(&record)
+	svc.processJob(context.Background(), OpeningCSVJob{RequestID: record.RequestID, Owner: "strict@test.com", Content: []byte(content.String())})
+
+	rowErrors, _ := jobRepo.ListRowErrors(record.RequestID)
+	if len(rowErrors) != invalid {
+		t.Fatalf("expected the rerun to replace the row errors, got %d", len(rowErrors))
+	}
+}
+
+// BenchmarkOpeningCSVService_InvalidRows runs strict jobs where every other
+// row is invalid. The row errors are streamed to the job repository, so the
+// peak-heap-KB metric stays flat as the file grows.
+func BenchmarkOpeningCSVService_InvalidRows(b *testing.B) {
+	for _, rows := range []int{10_000, 100_000} {
+		b.Run(fmt.Sprintf("rows=%d", rows), func(b *testing.B) {
+			db := openTestDB(b)
+			jobRepo := repository.NewImportJobRepository(db)
+			svc := NewOpeningCSVService(repository.New(db), &feedbackProducerSpy{}, 1, WithImportJobs(jobRepo))
+
+			path := filepath.Join(b.TempDir(), "upload.csv")
+
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				writeInvalidRows(b, path, rows)
+				requestID := fmt.Sprintf("req-bench-%d-%d", rows, i)
+				if err := jobRepo.Create(&schemas.ImportJob{RequestID: requestID, Status: schemas.ImportJobQueued}); err != nil {
+					b.Fatal(err)
+				}
+				runtime.GC()
+				b.StartTimer()
+
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					svc.processJob(context.Background(), OpeningCSVJob{RequestID: requestID, Path: path})
+				}()
+
+				for sampling := true; sampling; {
+					select {
+					case <-done:
+						sampling = false
+					case <-time.After(10 * time.Millisecond):
+					}
+					peak = max(peak, heapInUse())
+				}
+			}
+
+			b.ReportMetric(float64(peak)/1024, "peak-heap-KB")
+		})
+	}
+}
+
+// writeInvalidRows writes a CSV to path where every other row lacks a role,
+// without holding the file in memory.
+func writeInvalidRows(b *testing.B, path string, rows int) {
+	file, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	w.WriteString("role,company,location,remote,link,salary\n")
+	for i := range rows {
+		role := "Go Dev"
+		if i%2 == 1 {
+			role = ""
+		}
+		fmt.Fprintf(w, "%s,Acme,BR,true,https://acme.com/%d,2000\n", role, i)
+	}
+
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+func TestOpeningCSVService_AppliesRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("transforms:\n  - field: company\n    case: upper\nvalidations:\n  - field: salary\n    min: 3000\n    owners: [strict@test.com]\n"), 0o644); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	store, err := rules.NewStore(path)
+	if err != nil {
+		t.Fatalf("load rules: %v", err)
+	}
+
+	db := openTestDB(t)
+	svc := NewOpeningCSVService(repository.New(db), &feedbackProducerSpy{}, 2,
+		WithImportJobs(repository.NewImportJobRepository(db)),
+		WithSpoolDir(t.TempDir()),
+		WithRules(store))
+
+	content := []byte("role,company,location,remote,link,salary\nGo Dev,acme,BR,true,https://acme.com/rules,2000\n")
+	for _, owner := range []string{"strict@test.com", "relaxed@test.com"} {
+		if err := svc.Enqueue(OpeningCSVJob{RequestID: "req-rules-" + owner, Owner: owner, Content: content}); err != nil {
+			t.Fatalf("unexpected enqueue error: %v", err)
+		}
+		svc.processJob(context.Background(), nextJob(t, svc))
+	}
+
+	strict, _ := svc.GetJob("req-rules-strict@test.com")
+	if strict.Status != schemas.ImportJobFailed {
+		t.Fatalf("expected the owner's salary floor to fail the job, got %+v", strict)
+	}
+	rowErrors, _ := repository.NewImportJobRepository(db).ListRowErrors(strict.RequestID)
+	if len(rowErrors) != 1 || rowErrors[0].Message != "salary must be at least 3000" {
+		t.Fatalf("expected the rule error, got %+v", rowErrors)
+	}
+
+	var opening schemas.Openings
+	if err := db.Where("owner = ?", "relaxed@test.com").First(&opening).Error; err != nil {
+		t.Fatalf("unexpected db error: %v", err)
+	}
+	if opening.Company != "ACME" {
+		t.Fatalf("expected the company transform, got %q", opening.Company)
+	}
+}
+
+func TestOpeningCSVService_StoresRowErrors(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewOpeningCSVService(repository.New(db), &feedbackProducerSpy{}, 1,
+		WithImportJobs(repository.NewImportJobRepository(db)),
+		WithSpoolDir(t.TempDir()))
+
+	path, err := svc.Spool(strings.NewReader("role,company,location,remote,link,salary\n" +
+		"Go Dev,Acme,BR,true,https://acme.com,0\n" +
+		",Acme,BR,true,https://acme.com,1000\n"))
+	if err != nil {
+		t.Fatalf("unexpected spool error: %v", err)
+	}
+
+	job := OpeningCSVJob{
+		RequestID: "req-row-errors",
+		Owner:     "uploader@test.com",
+		Path:      path,
+	}
+	if err := svc.Enqueue(job); err != nil {
+		t.Fatalf("unexpected enqueue error: %v", err)
+	}
+	svc.processJob(context.Background(), nextJob(t, svc))
+
+	rowErrors, err := svc.RowErrors(job.RequestID)
+	if err != nil {
+		t.Fatalf("unexpected row errors error: %v", err)
+	}
+	if len(rowErrors) != 2 || rowErrors[0].LineNumber != 2 || rowErrors[1].LineNumber != 3 {
+		t.Fatalf("expected errors on lines 2 and 3, got %+v", rowErrors)
+	}
+
+	report, err := svc.ErrorReport(job.RequestID)
+	if err != nil {
+		t.Fatalf("unexpected report error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+
+	expected := "role,company,location,remote,link,salary,error\n" +
+		"Go Dev,Acme,BR,true,https://acme.com,0,salary must be greater than zero\n" +
+		",Acme,BR,true,https://acme.com,1000,role is required\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected report:\n%s", buf.String())
+	}
+}
+
+func TestOpeningCSVService_SweepsExpiredErrorReports(t *testing.T) {
+	db := openTestDB(t)
+	jobs := repository.NewImportJobRepository(db)
+	svc := NewOpeningCSVService(repository.New(db), &feedbackProducerSpy{}, 1,
+		WithImportJobs(jobs),
+		WithSpoolDir(t.TempDir()),
+		WithErrorReportTTL(time.Hour))
+
+	path, err := svc.Spool(strings.NewReader("role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,0\n"))
+	if err != nil {
+		t.Fatalf("unexpected spool error: %v", err)
+	}
+
+	job := OpeningCSVJob{RequestID: "req-expired-report", Owner: "uploader@test.com", Path: path}
+	if err := svc.Enqueue(job); err != nil {
+		t.Fatalf("unexpected enqueue error: %v", err)
+	}
+	svc.processJob(context.Background(), nextJob(t, svc))
+
+	if removed, err := svc.SweepErrorReports(time.Now()); err != nil || removed != 0 {
+		t.Fatalf("expected a recent report to stay, got %d, %v", removed, err)
+	}
+	if _, err := svc.ErrorReport(job.RequestID); err != nil {
+		t.Fatalf("unexpected report error: %v", err)
+	}
+
+	if removed, err := svc.SweepErrorReports(time.Now().Add(2 * time.Hour)); err != nil || removed != 1 {
+		t.Fatalf("expected the expired report removed, got %d, %v", removed, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the spool file removed, got %v", err)
+	}
+	if _, err := svc.ErrorReport(job.RequestID); !errors.Is(err, repository.ErrImportJobFileNotFound) {
+		t.Fatalf("expected ErrImportJobFileNotFound, got %v", err)
+	}
+
+	record, err := jobs.Get(job.RequestID)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	if record.SpoolPath != "" {
+		t.Fatalf("expected the spool path cleared, got %q", record.SpoolPath)
+	}
+	if rowErrors, err := svc.RowErrors(job.RequestID); err != nil || len(rowErrors) != 1 {
+		t.Fatalf("expected the row errors to stay, got %+v, %v", rowErrors, err)
+	}
+}
+
+func TestOpeningCSVService_StreamsSpooledUploadInBatches(t *testing.T) {
+	db := openTestDB(t)
+	producer := &feedbackProducerSpy{}
+	svc := NewOpeningCSVService(repository.New(db), producer, 1, WithSpoolDir(t.TempDir()), WithBatchSize(2), WithParseWorkers(2))
+
+	var content strings.Builder
+	content.WriteString("role,company,location,remote,link,salary\n")
+	for i := 0; i < 7; i++ {
+		fmt.Fprintf(&content, "Go Dev %d,Acme,BR,true,https://acme.com/%d,1000\n", i, i)
+	}
+
+	path, err := svc.Spool(strings.NewReader(content.String()))
+	if err != nil {
+		t.Fatalf("unexpected spool error: %v", err)
+	}
+
+	svc.processJob(context.Background(), OpeningCSVJob{RequestID: "req-stream", Path: path})
+
+	var count int64
+	db.Model(&schemas.Openings{}).Count(&count)
+	if count != 7 {
+		t.Fatalf("expected 7 openings, got %d", count)
+	}
+	if producer.messages[0].Status != "success" || producer.messages[0].TotalRows != 7 {
+		t.Fatalf("unexpected feedback: %+v", producer.messages[0])
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected spool file to be removed, got %v", err)
+	}
+}
+
+func TestOpeningCSVService_PartialModeSkipsInvalidRows(t *testing.T) {
+	db := openTestDB(t)
+	repo := repository.New(db)
+	producer := &feedbackProducerSpy{}
+	jobs := repository.NewImportJobRepository(db)
+	svc := NewOpeningCSVService(repo, producer, 1, WithImportJobs(jobs))
+
+	job := OpeningCSVJob{
+		RequestID: "req-partial",
+		Mode:      ImportModePartial,
+		Content: []byte("role,company,location,remote,link,salary\n" +
+			"Go Dev,Acme,BR,true,https://acme.com,2000\n" +
+			"Go Dev,Acme,BR,true,https://acme.com,0\n" +
+			"Rust Dev,Acme,BR,false,https://acme.com/rust,3000\n"),
+	}
+	if err := svc.Enqueue(job); err != nil {
+		t.Fatalf("unexpected enqueue error: %v", err)
+	}
+	svc.processJob(context.Background(), nextJob(t, svc))
+
+	openings, err := repo.List(repository.OpeningFilter{})
+	if err != nil {
+		t.Fatalf("unexpected list error: %v", err)
+	}
+	if len(openings) != 2 {
+		t.Fatalf("expected 2 inserted openings, got %d", len(openings))
+	}
+
+	if len(producer.messages) != 1 {
+		t.Fatalf("expected 1 feedback, got %d", len(producer.messages))
+	}
+	feedback := producer.messages[0]
+	if feedback.Status != "partial_success" || feedback.InsertedRows != 2 || feedback.SkippedRows != 1 || feedback.FirstErrorLine != 3 {
+		t.Fatalf("unexpected feedback: %+v", feedback)
+	}
+
+	record, _ := svc.GetJob(job.RequestID)
+	if record.Status != schemas.ImportJobPartialSuccess || record.InsertedRows != 2 || record.SkippedRows != 1 {
+		t.Fatalf("unexpected job record: %+v", record)
+	}
+
+	rowErrors, _ := svc.RowErrors(job.RequestID)
+	if len(rowErrors) != 1 || rowErrors[0].LineNumber != 3 {
+		t.Fatalf("expected skipped row error on line 3, got %+v", rowErrors)
+	}
+}
+
+func TestOpeningCSVService_UpsertMode(t *testing.T) {
+	db := openTestDB(t)
+	repo := repository.New(db)
+	producer := &feedbackProducerSpy{}
+	svc := NewOpeningCSVService(repo, producer, 1)
+
+	owner := "uploader@test.com"
+	seed := []schemas.Openings{
+		{Role: "Go Dev", Company: "Acme", Location: "BR", Remote: true, Link: "https://acme.com/go", Salary: 2000, Owner: owner, ExternalID: "ATS-1", Source: "ats"},
+		{Role: "Old Title", Company: "Acme", Location: "BR", Link: "https://acme.com/rust", Salary: 1000, Owner: owner, Source: "ats"},
+		{Role: "Gone", Company: "Acme", Location: "BR", Link: "https://acme.com/gone", Salary: 1000, Owner: owner, ExternalID: "ATS-9", Source: "ats"},
+		{Role: "Other Owner", Company: "Acme", Location: "BR", Link: "https://acme.com/new", Salary: 1000, Owner: "other@test.com"},
+	}
+	for i := range seed {
+		if err := db.Create(&seed[i]).Error; err != nil {
+			t.Fatalf("seed error: %v", err)
+		}
+	}
+
+	svc.processJob(context.Background(), OpeningCSVJob{
+		RequestID:    "req-upsert",
+		Owner:        owner,
+		Upsert:       true,
+		CloseMissing: true,
+		Source:       "ats",
+		Content: []byte("role,company,location,remote,link,salary,external_id\n" +
+			"Go Dev,Acme,BR,true,https://acme.com/go,2000,ATS-1\n" +
+			"Rust Dev,Acme,BR,false,https://acme.com/rust,3000,ATS-2\n" +
+			"New Dev,Acme,BR,false,https://acme.com/new,1500,ATS-3\n"),
+	})
+
+	if len(producer.messages) != 1 {
+		t.Fatalf("expected 1 feedback, got %d", len(producer.messages))
+	}
+	feedback := producer.messages[0]
+	if feedback.Status != "success" || feedback.InsertedRows != 1 || feedback.UpdatedRows != 1 || feedback.UnchangedRows != 1 || feedback.ClosedRows != 1 {
+		t.Fatalf("unexpected feedback: %+v", feedback)
+	}
+
+	var rust schemas.Openings
+	db.First(&rust, seed[1].ID)
+	if rust.Role != "Rust Dev" || rust.ExternalID != "ATS-2" || rust.Salary != 3000 {
+		t.Fatalf("expected opening matched by link to be updated, got %+v", rust)
+	}
+
+	var gone schemas.Openings
+	db.First(&gone, seed[2].ID)
+	if gone.ClosedAt == nil {
+		t.Fatalf("expected missing opening to be closed")
+	}
+
+	var count int64
+	db.Model(&schemas.Openings{}).Where("link = ?", "https://acme.com/new").Count(&count)
+	if count != 2 {
+		t.Fatalf("expected a new opening next to the other owner's, got %d", count)
+	}
+}
+
+func TestOpeningCSVService_CloseMissingAboveParameterLimit(t *testing.T) {
+	db := openTestDB(t)
+	producer := &feedbackProducerSpy{}
+	svc := NewOpeningCSVService(repository.New(db), producer, 1)
+
+	// The run lists more openings than SQLite accepts as bound parameters in
+	// one statement. Those before the last row are stamped upfront, as the
+	// earlier chunks of the run would have, so the test skips 33000 upserts.
+	const listed = 33000
+	owner := "uploader@test.com"
+	run := "req-close-many"
+
+	seed := make([]schemas.Openings, 0, listed+1)
+	for i := range listed {
+		seed = append(seed, schemas.Openings{Role: "Go Dev", Company: "Acme", Location: "BR", Link: fmt.Sprintf("https://acme.com/%d", i), Salary: 2000, Owner: owner, ExternalID: fmt.Sprintf("ATS-%d", i), Source: "ats", ImportRun: run})
+	}
+	seed = append(seed, schemas.Openings{Role: "Gone", Company: "Acme", Location: "BR", Link: "https://acme.com/gone", Salary: 1000, Owner: owner, ExternalID: "ATS-GONE", Source: "ats", ImportRun: "req-previous"})
+	if err := db.CreateInBatches(seed, 500).Error; err != nil {
+		t.Fatalf("seed error: %v", err)
+	}
+
+	svc.processJob(context.Background(), OpeningCSVJob{
+		RequestID:    run,
+		Owner:        owner,
+		Upsert:       true,
+		CloseMissing: true,
+		Source:       "ats",
+		Content: []byte("role,company,location,remote,link,salary,external_id\n" +
+			"New Dev,Acme,BR,false,https://acme.com/new,1500,ATS-NEW\n"),
+	})
+
+	if len(producer.messages) != 1 {
+		t.Fatalf("expected 1 feedback, got %d", len(producer.messages))
+	}
+	feedback := producer.messages[0]
+	if feedback.Status != "success" || feedback.InsertedRows != 1 || feedback.ClosedRows != 1 {
+		t.Fatalf("unexpected feedback: %+v", feedback)
+	}
+
+	var open int64
+	db.Model(&schemas.Openings{}).Where("closed_at IS NULL").Count(&open)
+	if open != listed+1 {
+		t.Fatalf("expected the listed openings to stay open, got %d", open)
+	}
+
+	var gone schemas.Openings
+	db.Where("external_id = ?", "ATS-GONE").First(&gone)
+	if gone.ClosedAt == nil {
+		t.Fatalf("expected the unlisted opening to be closed")
+	}
+}
+
+func TestParseImportMode(t *testing.T) {
+	for raw, expected := range map[string]string{"": ImportModeStrict, "strict": ImportModeStrict, " Partial ": ImportModePartial} {
+		mode, err := ParseImportMode(raw)
+		if err != nil || mode != expected {
+			t.Fatalf("ParseImportMode(%q) = %q, %v", raw, mode, err)
+		}
+	}
+
+	if _, err := ParseImportMode("lenient"); err != ErrInvalidImportMode {
+		t.Fatalf("expected ErrInvalidImportMode, got %v", err)
+	}
+}
+
+func TestOpeningCSVService_StopsJobOnTimeout(t *testing.T) {
+	db := openTestDB(t)
+	producer := &feedbackProducerSpy{}
+	svc := NewOpeningCSVService(repository.New(db), producer, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	svc.processJob(ctx, OpeningCSVJob{
+		RequestID: "req-timeout",
+		Content:   []byte("role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,2000\n"),
+	})
+
+	if len(producer.messages) != 1 || producer.messages[0].Message != ErrCSVJobTimeout.Error() {
+		t.Fatalf("expected timeout feedback, got %+v", producer.messages)
+	}
+
+	var count int64
+	db.Model(&schemas.Openings{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected no openings after timeout, got %d", count)
+	}
+}
+
+func TestOpeningCSVService_RestoresUnfinishedJobs(t *testing.T) {
+	db := openTestDB(t)
+	jobs := repository.NewImportJobRepository(db)
+	spoolDir := t.TempDir()
+
+	before := NewOpeningCSVService(repository.New(db), &feedbackProducerSpy{}, 5, WithImportJobs(jobs), WithSpoolDir(spoolDir))
+	for i, owner := range []string{"first@test.com", "second@test.com", "third@test.com"} {
+		err := before.Enqueue(OpeningCSVJob{
+			RequestID: fmt.Sprintf("req-restore-%d", i),
+			Owner:     owner,
+			Mode:      ImportModePartial,
+			Columns:   csvutil.ParseOptions{Mapping: csvutil.ColumnMapping{"Pay": "salary"}},
+			Content:   []byte(fmt.Sprintf("role,company,location,remote,link,Pay\nGo Dev,Acme,BR,true,https://acme.com/%d,2000\n", i)),
+		})
+		if err != nil {
+			t.Fatalf("unexpected enqueue error: %v", err)
+		}
+	}
+
+	// The first job finishes, the second is interrupted mid-run and the third
+	// never leaves the queue.
+	before.processJob(context.Background(), nextJob(t, before))
+	before.markRunning(nextJob(t, before).RequestID, time.Now())
+
+	exhausted := schemas.ImportJob{
+		RequestID: "req-restore-exhausted",
+		Owner:     "first@test.com",
+		Status:    schemas.ImportJobRunning,
+		Attempts:  maxCSVJobAttempts,
+		QueuedAt:  time.Now().UTC(),
+	}
+	lost := schemas.ImportJob{
+		RequestID: "req-restore-lost",
+		Owner:     "first@test.com",
+		Status:    schemas.ImportJobQueued,
+		SpoolPath: spoolDir + "/missing.csv",
+		QueuedAt:  time.Now().UTC(),
+	}
+	for _, record := range []*schemas.ImportJob{&exhausted, &lost} {
+		if err := jobs.Create(record); err != nil {
+			t.Fatalf("unexpected create error: %v", err)
+		}
+	}
+
+	producer := &feedbackProducerSpy{}
+	after := NewOpeningCSVService(repository.New(db), producer, 5, WithImportJobs(jobs), WithSpoolDir(spoolDir))
+	if err := after.Restore(); err != nil {
+		t.Fatalf("unexpected restore error: %v", err)
+	}
+
+	interrupted, _ := after.GetJob("req-restore-1")
+	if interrupted.Status != schemas.ImportJobQueued || interrupted.InterruptedAt == nil {
+		t.Fatalf("expected interrupted job to be queued again, got %+v", interrupted)
+	}
+
+	for range 2 {
+		after.processJob(context.Background(), nextJob(t, after))
+	}
+	if _, ok := after.queue.take(); ok {
+		t.Fatalf("expected only the unfinished jobs to be restored")
+	}
+
+	for i := range 3 {
+		record, _ := after.GetJob(fmt.Sprintf("req-restore-%d", i))
+		if record.Status != schemas.ImportJobSucceeded {
+			t.Fatalf("expected job %d to succeed, got %+v", i, record)
+		}
+	}
+
+	interrupted, _ = after.GetJob("req-restore-1")
+	if interrupted.Attempts != 2 {
+		t.Fatalf("expected interrupted job to run twice, got %d", interrupted.Attempts)
+	}
+
+	for request, message := range map[string]string{
+		exhausted.RequestID: ErrCSVJobInterrupted.Error(),
+		lost.RequestID:      ErrCSVUploadLost.Error(),
+	} {
+		record, _ := after.GetJob(request)
+		if record.Status != schemas.ImportJobFailed || record.ErrorSummary != message {
+			t.Fatalf("expected %s to fail with %q, got %+v", request, message, record)
+		}
+	}
+
+	var count int64
+	db.Model(&schemas.Openings{}).Count(&count)
+	if count != 3 {
+		t.Fatalf("expected 3 openings without duplicates, got %d", count)
+	}
+}
+
+func TestOpeningCSVService_Cancel(t *testing.T) {
+	db := openTestDB(t)
+	producer := &feedbackProducerSpy{}
+	svc := NewOpeningCSVService(repository.New(db), producer, 5,
+		WithImportJobs(repository.NewImportJobRepository(db)),
+		WithSpoolDir(t.TempDir()))
+
+	content := []byte("role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,2000\n")
+	for _, requestID := range []string{"req-cancel-running", "req-cancel-queued"} {
+		if err := svc.Enqueue(OpeningCSVJob{RequestID: requestID, Owner: "uploader@test.com", Content: content}); err != nil {
+			t.Fatalf("unexpected enqueue error: %v", err)
+		}
+	}
+
+	running, ctx, _ := svc.queue.next(context.Background())
+	defer svc.queue.done(running)
+
+	queued, _ := svc.GetJob("req-cancel-queued")
+	if stopping, err := svc.Cancel(queued.RequestID); err != nil || stopping {
+		t.Fatalf("expected queued job to be dropped, got %v, %v", stopping, err)
+	}
+	if spoolExists(queued.SpoolPath) {
+		t.Fatalf("expected spooled upload of the cancelled job to be removed")
+	}
+
+	if stopping, err := svc.Cancel(running.RequestID); err != nil || !stopping {
+		t.Fatalf("expected running job to be stopped, got %v, %v", stopping, err)
+	}
+	svc.processJob(ctx, running)
+
+	for _, requestID := range []string{"req-cancel-running", "req-cancel-queued"} {
+		record, _ := svc.GetJob(requestID)
+		if record.Status != schemas.ImportJobCancelled {
+			t.Fatalf("expected %s to be cancelled, got %s", requestID, record.Status)
+		}
+	}
+
+	if len(producer.messages) != 2 || producer.messages[0].Status != "cancelled" || producer.messages[1].Status != "cancelled" {
+		t.Fatalf("expected two cancelled feedback messages, got %+v", producer.messages)
+	}
+
+	var count int64
+	db.Model(&schemas.Openings{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected no openings after cancel, got %d", count)
+	}
+
+	if _, err := svc.Cancel("req-cancel-queued"); err != ErrCSVJobNotCancelable {
+		t.Fatalf("expected ErrCSVJobNotCancelable, got %v", err)
+	}
+}
+
+func TestOpeningCSVService_ShutdownRequeuesRunningJobs(t *testing.T) {
+	db := openTestDB(t)
+	producer := &feedbackProducerSpy{}
+	spoolDir := t.TempDir()
+	svc := NewOpeningCSVService(repository.New(db), producer, 5,
+		WithImportJobs(repository.NewImportJobRepository(db)),
+		WithSpoolDir(spoolDir))
+
+	content := []byte("role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,2000\n")
+	for _, requestID := range []string{"req-shutdown-running", "req-shutdown-queued"} {
+		if err := svc.Enqueue(OpeningCSVJob{RequestID: requestID, Owner: "uploader@test.com", Content: content}); err != nil {
+			t.Fatalf("unexpected enqueue error: %v", err)
+		}
+	}
+
+	// Stand in for a worker holding the first job past the deadline.
+	running, jobCtx, _ := svc.queue.next(context.Background())
+	svc.running.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- svc.Shutdown(ctx) }()
+
+	<-jobCtx.Done()
+	svc.processJob(jobCtx, running)
+	svc.queue.done(running)
+	svc.running.Done()
+
+	if err := <-shutdown; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected shutdown to report the deadline, got %v", err)
+	}
+
+	for _, requestID := range []string{"req-shutdown-running", "req-shutdown-queued"} {
+		record, _ := svc.GetJob(requestID)
+		if record.Status != schemas.ImportJobQueued || !spoolExists(record.SpoolPath) {
+			t.Fatalf("expected %s to stay queued with its upload, got %+v", requestID, record)
+		}
+	}
+
+	interrupted, _ := svc.GetJob("req-shutdown-running")
+	if interrupted.InterruptedAt == nil {
+		t.Fatalf("expected the running job to be marked as interrupted")
+	}
+	if len(producer.messages) != 0 {
+		t.Fatalf("expected no feedback for requeued jobs, got %+v", producer.messages)
+	}
+
+	var count int64
+	db.Model(&schemas.Openings{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected the interrupted job to roll back, got %d openings", count)
+	}
+
+	err := svc.Enqueue(OpeningCSVJob{RequestID: "req-shutdown-late", Content: content})
+	if err != ErrCSVServiceStopped {
+		t.Fatalf("expected ErrCSVServiceStopped, got %v", err)
+	}
+	entries, _ := os.ReadDir(spoolDir)
+	if len(entries) != 2 {
+		t.Fatalf("expected the rejected upload not to be spooled, got %d files", len(entries))
+	}
+}
+
+func TestOpeningCSVService_ShutdownGivesUpOnStuckJobs(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewOpeningCSVService(repository.New(db), &feedbackProducerSpy{}, 5)
+	svc.shutdownGrace = 10 * time.Millisecond
+
+	if err := svc.Enqueue(OpeningCSVJob{RequestID: "req-shutdown-stuck", Content: []byte("role\n")}); err != nil {
+		t.Fatalf("unexpected enqueue error: %v", err)
+	}
+
+	// Stand in for a worker that never notices its context.
+	svc.queue.next(context.Background())
+	svc.running.Add(1)
+	defer svc.running.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- svc.Shutdown(ctx) }()
+
+	select {
+	case err := <-shutdown:
+		if err == nil || !strings.Contains(err.Error(), "did not stop") || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected shutdown to give up on the stuck job, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected shutdown to return after the grace period")
+	}
+}
+
+func TestOpeningCSVService_PublishesProgress(t *testing.T) {
+	db := openTestDB(t)
+	producer := &feedbackProducerSpy{}
+	svc := NewOpeningCSVService(repository.New(db), producer, 1, WithBatchSize(1), WithProgress(2, 0))
+	svc.progressMinGap = 0
+
+	content := "role,company,location,remote,link,salary\n"
+	for i := range 5 {
+		content += fmt.Sprintf("Go Dev,Acme,BR,true,https://acme.com/progress/%d,1000\n", i)
+	}
+	svc.processJob(context.Background(), OpeningCSVJob{RequestID: "req-progress", Content: []byte(content)})
+
+	expected := []struct {
+		phase            string
+		total, processed int
+	}{
+		{PhaseParsing, 2, 1},
+		{PhaseInserting, 2, 2},
+		{PhaseParsing, 4, 3},
+		{PhaseInserting, 4, 4},
+	}
+	if len(producer.messages) != len(expected)+1 {
+		t.Fatalf("expected %d progress events and the result, got %+v", len(expected), producer.messages)
+	}
+	for i, want := range expected {
+		got := producer.messages[i]
+		if got.Status != "running" || got.Phase != want.phase || got.TotalRows != want.total || got.ProcessedRows != want.processed {
+			t.Fatalf("unexpected progress event %d: %+v", i, got)
+		}
+	}
+	if last := producer.messages[len(expected)]; last.Status != "success" || last.Phase != "" {
+		t.Fatalf("expected the final result last, got %+v", last)
+	}
+}
+
+func TestProgressTracker_AppliesToRunningJobs(t *testing.T) {
+	tracker := progressTracker{}
+	finish := time.Now().Add(time.Minute).UTC()
+	tracker.set("req-1", jobProgress{Phase: PhaseInserting, TotalRows: 10, ProcessedRows: 8, EstimatedFinishAt: finish})
+
+	running := schemas.ImportJob{RequestID: "req-1", Status: schemas.ImportJobRunning}
+	tracker.apply(&running)
+	if running.Phase != PhaseInserting || running.ProcessedRows != 8 || running.EstimatedFinishAt == nil || !running.EstimatedFinishAt.Equal(finish) {
+		t.Fatalf("expected live progress on the running job, got %+v", running)
+	}
+
+	finished := schemas.ImportJob{RequestID: "req-1", Status: schemas.ImportJobSucceeded, ProcessedRows: 10}
+	tracker.apply(&finished)
+	if finished.Phase != "" || finished.ProcessedRows != 10 {
+		t.Fatalf("expected the stored result of a finished job, got %+v", finished)
+	}
+
+	tracker.clear("req-1")
+	running = schemas.ImportJob{RequestID: "req-1", Status: schemas.ImportJobRunning}
+	tracker.apply(&running)
+	if running.Phase != "" {
+		t.Fatalf("expected no progress after clear, got %+v", running)
+	}
+}
+
+func TestOpeningCSVService_EnqueueOnce(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewOpeningCSVService(repository.New(db), &feedbackProducerSpy{}, 10,
+		WithImportJobs(repository.NewImportJobRepository(db)),
+		WithSpoolDir(t.TempDir()))
+
+	upload := func(requestID, key, hash string) (schemas.ImportJob, bool, error) {
+		return svc.EnqueueOnce(OpeningCSVJob{
+			RequestID:      requestID,
+			Owner:          "uploader@test.com",
+			IdempotencyKey: key,
+			ContentHash:    hash,
+			Content:        []byte("role,company,location,remote,link,salary\nGo Dev,Acme,BR,true,https://acme.com,0\n"),
+		})
+	}
+
+	if _, duplicate, err := upload("req-first", "key-1", "hash-1"); err != nil || duplicate {
+		t.Fatalf("expected the first upload to be queued, got %v, %v", duplicate, err)
+	}
+
+	original, duplicate, err := upload("req-retry", "key-1", "hash-1")
+	if err != nil || !duplicate || original.RequestID != "req-first" || original.Status != schemas.ImportJobQueued {
+		t.Fatalf("expected the retry to return req-first, got %+v, %v, %v", original, duplicate, err)
+	}
+
+	original, duplicate, err = upload("req-same-file", "", "hash-1")
+	if err != nil || !duplicate || original.RequestID != "req-first" {
+		t.Fatalf("expected the same content to return req-first, got %+v, %v, %v", original, duplicate, err)
+	}
+
+	if _, _, err := upload("req-reused-key", "key-1", "hash-2"); err != ErrIdempotencyKeyReused {
+		t.Fatalf("expected ErrIdempotencyKeyReused, got %v", err)
+	}
+
+	// A failed upload can be sent again.
+	svc.processJob(context.Background(), nextJob(t, svc))
+	if record, _ := svc.GetJob("req-first"); record.Status != schemas.ImportJobFailed {
+		t.Fatalf("expected req-first to fail on the invalid row, got %s", record.Status)
+	}
+	if _, duplicate, err := upload("req-fixed", "", "hash-1"); err != nil || duplicate {
+		t.Fatalf("expected the upload after a failure to be queued, got %v, %v", duplicate, err)
+	}
+
+	if _, err := svc.GetJob("req-retry"); err != repository.ErrImportJobNotFound {
+		t.Fatalf("expected no job for the retry, got %v", err)
+	}
+}
+
+func TestOpeningCSVService_Validate(t *testing.T) {
+	db := openTestDB(t)
+	repo := repository.New(db)
+	svc := NewOpeningCSVService(repo, &feedbackProducerSpy{}, 1)
+
+	existing := schemas.Openings{Role: "Go Dev", Company: "Acme", Location: "BR", Link: "https://acme.com/old", Salary: 1000, Owner: "uploader@test.com"}
+	if err := repo.Create(&existing); err != nil {
+		t.Fatalf("unexpected create error: %v", err)
+	}
+
+	content := "role,company,location,remote,link,salary\n" +
+		"Go Dev,Acme,BR,true,https://acme.com/old,2000\n" +
+		"Go Dev,Acme,BR,true,https://acme.com/new,2000\n" +
+		"Go Dev,Acme,BR,true,https://acme.com/new,3000\n" +
+		"Go Dev,Acme,BR,maybe,https://acme.com/bad,2000\n"
+
+	report, err := svc.Validate(strings.NewReader(content), ValidateOptions{Owner: "uploader@test.com", PreviewSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected validate error: %v", err)
+	}
+
+	if report.TotalRows != 4 || report.ValidRows != 3 || report.NewRows != 2 || report.ExistingRows != 1 {
+		t.Fatalf("unexpected counts: %+v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].LineNumber != 5 {
+		t.Fatalf("expected error on line 5, got %+v", report.Errors)
+	}
+	if len(report.Warnings) != 2 || report.Warnings[0].LineNumber != 2 || report.Warnings[1].Message != "duplicate link of line 3" {
+		t.Fatalf("unexpected warnings: %+v", report.Warnings)
+	}
+	if len(report.Preview) != 2 || report.Preview[1].Link != "https://acme.com/new" {
+		t.Fatalf("expected a preview of 2 rows, got %+v", report.Preview)
+	}
+
+	var count int64
+	db.Model(&schemas.Openings{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected validation to write nothing, got %d openings", count)
+	}
+
+	small := NewOpeningCSVService(repo, nil, 1, WithValidateMaxBytes(10))
+	if _, err := small.Validate(strings.NewReader(content), ValidateOptions{}); err != ErrCSVTooLarge {
+		t.Fatalf("expected ErrCSVTooLarge, got %v", err)
+	}
+
+	if _, err := svc.Validate(strings.NewReader("role,company\n"), ValidateOptions{}); !errors.Is(err, ErrInvalidCSV) {
+		t.Fatalf("expected ErrInvalidCSV, got %v", err)
+	}
+}
+
+func TestOpeningCSVService_ImportsNDJSON(t *testing.T) {
+	db := openTestDB(t)
+	producer := &feedbackProducerSpy{}
+	svc := NewOpeningCSVService(repository.New(db), producer, 1,
+		WithImportJobs(repository.NewImportJobRepository(db)),
+		WithSpoolDir(t.TempDir()))
+
+	job := OpeningCSVJob{
+		RequestID: "req-ndjson",
+		Owner:     "uploader@test.com",
+		Format:    "ndjson",
+		Mode:      ImportModePartial,
+		Content: []byte(`{"role":"Go Dev","company":"Acme","location":"BR","remote":true,"link":"https://acme.com/1","salary":{"amount":2000}}` + "\n" +
+			`{"role":"Go Dev","company":"Acme","location":"BR","remote":"yes","link":"https://acme.com/2","salary":2000}` + "\n"),
+	}
+	if err := svc.Enqueue(job); err != nil {
+		t.Fatalf("unexpected enqueue error: %v", err)
+	}
+	svc.processJob(context.Background(), nextJob(t, svc))
+
+	record, _ := svc.GetJob(job.RequestID)
+	if record.Format != "ndjson" || record.Status != schemas.ImportJobPartialSuccess || record.InsertedRows != 1 || record.SkippedRows != 1 {
+		t.Fatalf("unexpected job record: %+v", record)
+	}
+
+	rowErrors, _ := svc.RowErrors(job.RequestID)
+	if len(rowErrors) != 1 || rowErrors[0].LineNumber != 2 || rowErrors[0].Message != "remote must be a boolean" {
+		t.Fatalf("unexpected row errors: %+v", rowErrors)
+	}
+
+	if len(producer.messages) != 1 || producer.messages[0].Format != "ndjson" {
+		t.Fatalf("expected ndjson feedback, got %+v", producer.messages)
+	}
+
+	if _, err := svc.ErrorReport(job.RequestID); err != ErrErrorReportFormat {
+		t.Fatalf("expected ErrErrorReportFormat, got %v", err)
+	}
+}
+
+func TestOpeningCSVService_AppliesRulesToNDJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	rulesFile := "transforms:\n  - field: remote\n    map: { home office: \"true\" }\n" +
+		"validations:\n  - field: salary\n    min: 3000\n  - field: link\n    pattern: \"^https://\"\n"
+	if err := os.WriteFile(path, []byte(rulesFile), 0o644); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	store, err := rules.NewStore(path)
+	if err != nil {
+		t.Fatalf("load rules: %v", err)
+	}
+
+	db := openTestDB(t)
+	svc := NewOpeningCSVService(repository.New(db), &feedbackProducerSpy{}, 1,
+		WithImportJobs(repository.NewImportJobRepository(db)),
+		WithSpoolDir(t.TempDir()),
+		WithRules(store))
+
+	job := OpeningCSVJob{
+		RequestID: "req-ndjson-rules",
+		Owner:     "uploader@test.com",
+		Format:    "ndjson",
+		Mode:      ImportModePartial,
+		Content: []byte(`{"role":"Go Dev","company":"Acme","location":"BR","remote":"Home office","link":"https://acme.com/ndjson-rules-1","salary":5000}` + "\n" +
+			`{"role":"Go Dev","company":"Acme","location":"BR","remote":true,"link":"https://acme.com/ndjson-rules-2","salary":{"amount":2000}}` + "\n" +
+			`{"role":"Go Dev","company":"Acme","location":"BR","remote":false,"link":"http://acme.com/ndjson-rules-3","salary":5000}` + "\n"),
+	}
+	if err := svc.Enqueue(job); err != nil {
+		t.Fatalf("unexpected enqueue error: %v", err)
+	}
+	svc.processJob(context.Background(), nextJob(t, svc))
+
+	record, _ := svc.GetJob(job.RequestID)
+	if record.Status != schemas.ImportJobPartialSuccess || record.InsertedRows != 1 || record.SkippedRows != 2 {
+		t.Fatalf("unexpected job record: %+v", record)
+	}
+
+	rowErrors, _ := svc.RowErrors(job.RequestID)
+	if len(rowErrors) != 2 || rowErrors[0].Message != "salary must be at least 3000" || rowErrors[1].Message != "link must match ^https://" {
+		t.Fatalf("expected the rule errors, got %+v", rowErrors)
+	}
+
+	var opening schemas.Openings
+	if err := db.Where("link = ?", "https://acme.com/ndjson-rules-1").First(&opening).Error; err != nil || !opening.Remote {
+		t.Fatalf("expected the remote transform, got %+v, %v", opening, err)
+	}
+}
+
+// nextJob takes the next queued job without running the workers. The active
+// slot is released right away since tests process jobs synchronously.
+func nextJob(t *testing.T, svc *OpeningCSVService) OpeningCSVJob {
+	t.Helper()
+
+	job, ok := svc.queue.take()
+	if !ok {
+		t.Fatalf("expected a queued job")
+	}
+	svc.queue.done(job)
+
+	return job
+}
+
+func openTestDB(t testing.TB) *gorm.DB {
 	t.Helper()
 
 	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
@@ -162,7 +1372,7 @@ func openTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("failed opening test db: %v", err)
 	}
 
-	if err := db.AutoMigrate(&schemas.Openings{}); err != nil {
+	if err := db.AutoMigrate(&schemas.Openings{}, &schemas.TwoFactor{}, &schemas.TwoFactorPolicy{}, &schemas.ImportJob{}, &schemas.ImportRowError{}, &schemas.ImportJobFile{}, &schemas.ExportJob{}); err != nil {
 		t.Fatalf("failed migrating test db: %v", err)
 	}
 
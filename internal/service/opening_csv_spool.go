@@ -0,0 +1,65 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Spool writes an upload to the spool directory and returns its path, so the
+// queued job does not keep the file in memory. The file is removed once the
+// job finishes, unless it is needed for the error report.
+func (s *OpeningCSVService) Spool(r io.Reader) (string, error) {
+	if err := os.MkdirAll(s.spoolDir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create spool dir: %w", err)
+	}
+
+	file, err := os.CreateTemp(s.spoolDir, "upload-*.csv")
+	if err != nil {
+		return "", fmt.Errorf("failed to create spool file: %w", err)
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write spool file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write spool file: %w", err)
+	}
+
+	return file.Name(), nil
+}
+
+func (s *OpeningCSVService) removeSpool(path string) {
+	if path == "" {
+		return
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		s.logger.Error("failed to remove spool file",
+			slog.String("path", path),
+			slog.String("error", err.Error()))
+	}
+}
+
+func spoolExists(path string) bool {
+	if path == "" {
+		return false
+	}
+
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (j OpeningCSVJob) open() (io.ReadCloser, error) {
+	if j.Path == "" {
+		return io.NopCloser(bytes.NewReader(j.Content)), nil
+	}
+
+	return os.Open(j.Path)
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"opportunities/internal/archive"
+	csvutil "opportunities/internal/csv"
+	"opportunities/internal/schemas"
+)
+
+const (
+	defaultArchiveMaxEntries = 50
+	defaultArchiveMaxBytes   = 500 << 20
+	defaultUploadMaxBytes    = 500 << 20
+)
+
+// UploadError is a file of an upload that cannot be imported. File names the
+// file inside an archive when the upload is one.
+type UploadError struct {
+	File string
+	Err  error
+}
+
+func (e *UploadError) Error() string {
+	return fmt.Sprintf("%s: %s", e.File, e.Err)
+}
+
+func (e *UploadError) Unwrap() error {
+	return e.Err
+}
+
+// SpooledUpload is a file of an upload written to the spool directory and
+// checked by the parser of its format.
+type SpooledUpload struct {
+	Name        string
+	Format      string
+	Path        string
+	ContentHash string
+}
+
+// WithArchiveLimits bounds the files and the decompressed size of gzip and
+// zip uploads, counted over all the file parts of a request. Zero means no
+// limit.
+func WithArchiveLimits(maxEntries int, maxBytes int64) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		if maxEntries >= 0 {
+			s.archiveLimits.MaxEntries = maxEntries
+		}
+		if maxBytes >= 0 {
+			s.archiveLimits.MaxBytes = maxBytes
+		}
+	}
+}
+
+// WithUploadMaxBytes limits the size of the body of an upload request, all
+// its parts included.
+func WithUploadMaxBytes(limit int64) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		if limit > 0 {
+			s.uploadMaxBytes = limit
+		}
+	}
+}
+
+// UploadMaxBytes returns the largest upload request body accepted.
+func (s *OpeningCSVService) UploadMaxBytes() int64 {
+	return s.uploadMaxBytes
+}
+
+// UploadBudget returns the archive budget for the file parts of one request.
+func (s *OpeningCSVService) UploadBudget() *archive.Budget {
+	return archive.NewBudget(s.archiveLimits)
+}
+
+// SpoolUpload spools every file of an upload: the upload itself, the content
+// of a gzip file or each file of a zip archive. formatFor picks the format of
+// a file from its name. The files count on budget, which the other parts of
+// the request share. It reports whether the upload was compressed. When a
+// file is rejected, with an UploadError, nothing stays spooled.
+func (s *OpeningCSVService) SpoolUpload(name string, r io.ReaderAt, size int64, budget *archive.Budget, formatFor func(name string) (string, error), columns csvutil.ParseOptions) ([]SpooledUpload, bool, error) {
+	compressed := archive.Detect(r) != archive.KindPlain
+
+	var uploads []SpooledUpload
+	var entryErr error
+	err := budget.Walk(name, r, size, func(entry string, content io.Reader) error {
+		upload, err := s.spoolEntry(entry, content, formatFor, columns)
+		if err != nil {
+			entryErr = err
+			return err
+		}
+
+		uploads = append(uploads, upload)
+		return nil
+	})
+	if err != nil {
+		s.Discard(uploads)
+
+		if entryErr == nil {
+			// The archive itself is invalid or over the limits.
+			return nil, compressed, &UploadError{File: name, Err: err}
+		}
+		return nil, compressed, err
+	}
+
+	return uploads, compressed, nil
+}
+
+func (s *OpeningCSVService) spoolEntry(name string, content io.Reader, formatFor func(name string) (string, error), columns csvutil.ParseOptions) (SpooledUpload, error) {
+	format, err := formatFor(name)
+	if err != nil {
+		return SpooledUpload{}, &UploadError{File: name, Err: err}
+	}
+
+	parser, err := s.Parser(format)
+	if err != nil {
+		return SpooledUpload{}, &UploadError{File: name, Err: err}
+	}
+
+	// The file is hashed on the way to recognize repeated uploads.
+	hash := sha256.New()
+	path, err := s.Spool(io.TeeReader(content, hash))
+	if err != nil {
+		if errors.Is(err, archive.ErrTooLarge) {
+			return SpooledUpload{}, &UploadError{File: name, Err: archive.ErrTooLarge}
+		}
+		return SpooledUpload{}, err
+	}
+
+	// Opening the file checks what can be checked up front, such as the CSV
+	// header.
+	file, err := os.Open(path)
+	if err != nil {
+		s.removeSpool(path)
+		return SpooledUpload{}, fmt.Errorf("failed to read spool file: %w", err)
+	}
+	_, err = parser.Open(file, columns)
+	file.Close()
+	if err != nil {
+		s.removeSpool(path)
+		return SpooledUpload{}, &UploadError{File: name, Err: err}
+	}
+
+	return SpooledUpload{
+		Name:        name,
+		Format:      format,
+		Path:        path,
+		ContentHash: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// Discard removes spooled uploads that were not enqueued.
+func (s *OpeningCSVService) Discard(uploads []SpooledUpload) {
+	for _, upload := range uploads {
+		s.removeSpool(upload.Path)
+	}
+}
+
+// ListBatch returns the jobs of the files of one upload.
+func (s *OpeningCSVService) ListBatch(batchID string) ([]schemas.ImportJob, error) {
+	if s.jobRepo == nil {
+		return nil, ErrJobTrackingDisabled
+	}
+
+	jobs, err := s.jobRepo.ListByBatch(batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range jobs {
+		s.progress.apply(&jobs[i])
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	csvutil "opportunities/internal/csv"
+	"opportunities/internal/schemas"
+)
+
+const (
+	defaultValidateMaxBytes = 5 << 20
+	defaultPreviewSize      = 10
+	maxPreviewSize          = 100
+)
+
+var (
+	ErrCSVTooLarge = errors.New("csv file is too large to validate")
+	ErrInvalidCSV  = errors.New("invalid csv")
+)
+
+// ValidateOptions describes the upload being checked. They match the fields
+// of an import so the result predicts what the import would do.
+type ValidateOptions struct {
+	Owner       string
+	Upsert      bool
+	Columns     csvutil.ParseOptions
+	PreviewSize int
+}
+
+// ValidationIssue points at a CSV line.
+type ValidationIssue struct {
+	LineNumber int    `json:"line_number"`
+	Message    string `json:"message"`
+}
+
+// PreviewOpening is a parsed row as it would be imported.
+type PreviewOpening struct {
+	LineNumber int    `json:"line_number"`
+	Role       string `json:"role"`
+	Company    string `json:"company"`
+	Location   string `json:"location"`
+	Remote     bool   `json:"remote"`
+	Link       string `json:"link"`
+	Salary     int64  `json:"salary"`
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+// ValidationReport is the result of a dry run. Errors are the rows an import
+// would reject; warnings are duplicates that would still be imported.
+type ValidationReport struct {
+	Dialect      csvutil.Dialect   `json:"dialect"`
+	TotalRows    int               `json:"total_rows"`
+	ValidRows    int               `json:"valid_rows"`
+	NewRows      int               `json:"new_rows"`
+	ExistingRows int               `json:"existing_rows"`
+	Errors       []ValidationIssue `json:"errors"`
+	Warnings     []ValidationIssue `json:"warnings"`
+	Preview      []PreviewOpening  `json:"preview"`
+	// Normalizations are the values the locale rules would rewrite.
+	Normalizations []schemas.ImportNormalization `json:"normalizations"`
+}
+
+// WithValidateMaxBytes limits the size of the files accepted by Validate.
+func WithValidateMaxBytes(limit int64) OpeningCSVServiceOption {
+	return func(s *OpeningCSVService) {
+		if limit > 0 {
+			s.validateMaxBytes = limit
+		}
+	}
+}
+
+// ValidateMaxBytes returns the largest file Validate accepts.
+func (s *OpeningCSVService) ValidateMaxBytes() int64 {
+	return s.validateMaxBytes
+}
+
+// Validate parses the whole file and checks it against the owner's openings
+// without writing anything. A file that cannot be parsed returns
+// ErrInvalidCSV; row problems are part of the report.
+func (s *OpeningCSVService) Validate(r io.Reader, opts ValidateOptions) (ValidationReport, error) {
+	content, err := io.ReadAll(io.LimitReader(r, s.validateMaxBytes+1))
+	if err != nil {
+		return ValidationReport{}, fmt.Errorf("failed to read csv: %w", err)
+	}
+	if int64(len(content)) > s.validateMaxBytes {
+		return ValidationReport{}, ErrCSVTooLarge
+	}
+
+	opts.Columns.Rules = s.rowRules(opts.Owner)
+
+	dialect, err := csvutil.DetectDialect(bytes.NewReader(content), opts.Columns)
+	if err != nil {
+		return ValidationReport{}, fmt.Errorf("%w: %w", ErrInvalidCSV, err)
+	}
+
+	parsed, rowErrors, err := csvutil.ParseAndValidateWithOptions(content, opts.Columns)
+	if err != nil {
+		return ValidationReport{}, fmt.Errorf("%w: %w", ErrInvalidCSV, err)
+	}
+
+	previewSize := opts.PreviewSize
+	if previewSize <= 0 {
+		previewSize = defaultPreviewSize
+	}
+	previewSize = min(previewSize, maxPreviewSize)
+
+	report := ValidationReport{
+		Dialect:   dialect,
+		TotalRows: len(parsed) + len(rowErrors),
+		ValidRows: len(parsed),
+		Errors:    make([]ValidationIssue, 0, len(rowErrors)),
+		Warnings:  make([]ValidationIssue, 0),
+		Preview:   make([]PreviewOpening, 0, min(previewSize, len(parsed))),
+	}
+
+	normalized := newNormalizationReport()
+	normalized.add(parsed)
+	report.Normalizations = normalized.entries
+	if report.Normalizations == nil {
+		report.Normalizations = make([]schemas.ImportNormalization, 0)
+	}
+
+	for _, rowErr := range rowErrors {
+		report.Errors = append(report.Errors, ValidationIssue{LineNumber: rowErr.LineNumber, Message: rowErr.Message})
+	}
+
+	if len(parsed) == 0 {
+		return report, nil
+	}
+
+	// The transaction is only read from and always rolled back.
+	tx, err := s.repo.BeginTx()
+	if err != nil {
+		return ValidationReport{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	seenLinks := make(map[string]int)
+	seenExternalIDs := make(map[string]int)
+
+	for _, row := range parsed {
+		opening := row.Opening
+
+		if len(report.Preview) < previewSize {
+			report.Preview = append(report.Preview, PreviewOpening{
+				LineNumber: row.LineNumber,
+				Role:       opening.Role,
+				Company:    opening.Company,
+				Location:   opening.Location,
+				Remote:     opening.Remote,
+				Link:       opening.Link,
+				Salary:     opening.Salary,
+				ExternalID: opening.ExternalID,
+			})
+		}
+
+		if line, ok := seenExternalIDs[opening.ExternalID]; ok && opening.ExternalID != "" {
+			report.Warnings = append(report.Warnings, ValidationIssue{
+				LineNumber: row.LineNumber,
+				Message:    fmt.Sprintf("duplicate external_id of line %d", line),
+			})
+		} else if line, ok := seenLinks[opening.Link]; ok {
+			report.Warnings = append(report.Warnings, ValidationIssue{
+				LineNumber: row.LineNumber,
+				Message:    fmt.Sprintf("duplicate link of line %d", line),
+			})
+		}
+		if _, ok := seenExternalIDs[opening.ExternalID]; !ok && opening.ExternalID != "" {
+			seenExternalIDs[opening.ExternalID] = row.LineNumber
+		}
+		if _, ok := seenLinks[opening.Link]; !ok {
+			seenLinks[opening.Link] = row.LineNumber
+		}
+
+		existing, found, err := s.repo.FindImportMatchWithTx(tx, opts.Owner, opening.ExternalID, opening.Link)
+		if err != nil {
+			return ValidationReport{}, fmt.Errorf("failed to look up line %d: %w", row.LineNumber, err)
+		}
+		if !found {
+			report.NewRows++
+			continue
+		}
+
+		report.ExistingRows++
+		message := fmt.Sprintf("duplicates existing opening %d; use upsert to update it", existing.ID)
+		if opts.Upsert {
+			message = fmt.Sprintf("updates existing opening %d", existing.ID)
+		}
+		report.Warnings = append(report.Warnings, ValidationIssue{LineNumber: row.LineNumber, Message: message})
+	}
+
+	return report, nil
+}
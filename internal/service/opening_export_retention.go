@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// maxExportSweepInterval bounds how long an expired export file may outlive
+// its retention.
+const maxExportSweepInterval = time.Hour
+
+// WithExportFileTTL sets how long the file of a finished export is kept for
+// download. Zero keeps it forever.
+func WithExportFileTTL(ttl time.Duration) OpeningExportServiceOption {
+	return func(s *OpeningExportService) {
+		if ttl >= 0 {
+			s.fileTTL = ttl
+		}
+	}
+}
+
+// startFileSweep removes expired export files now and then periodically,
+// until ctx is done or Shutdown is called.
+func (s *OpeningExportService) startFileSweep(ctx context.Context) {
+	if s.fileTTL <= 0 {
+		return
+	}
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+
+		ticker := time.NewTicker(min(s.fileTTL, maxExportSweepInterval))
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.SweepFiles(time.Now()); err != nil {
+				s.logger.Error("failed to sweep export files", slog.String("error", err.Error()))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SweepFiles deletes the files of exports that finished more than the
+// retention before now. Their download then answers not found, while the
+// export itself stays listed. It returns how many files were removed.
+func (s *OpeningExportService) SweepFiles(now time.Time) (int, error) {
+	if s.fileTTL <= 0 {
+		return 0, nil
+	}
+
+	jobs, err := s.jobRepo.ListFilesFinishedBefore(now.Add(-s.fileTTL))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired export files: %w", err)
+	}
+
+	removed := 0
+	for _, job := range jobs {
+		path := job.Path
+
+		// The record goes first, so a download never points at a removed file.
+		job.Path = ""
+		if err := s.jobRepo.Update(&job); err != nil {
+			return removed, fmt.Errorf("failed to expire export %s: %w", job.ID, err)
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			s.logger.Error("failed to remove export file",
+				slog.String("export_id", job.ID),
+				slog.String("error", err.Error()))
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		s.logger.Info("expired export files removed", slog.Int("files", removed))
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"opportunities/internal/export"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+
+	"github.com/google/uuid"
+)
+
+const defaultExportBatchSize = 500
+
+var (
+	ErrExportQueueFull   = errors.New("export queue is full")
+	ErrExportNotReady    = errors.New("export is not ready")
+	ErrExportStopped     = errors.New("export service is shutting down")
+	ErrExportInterrupted = errors.New("export interrupted by a restart")
+	ErrExportExpired     = errors.New("export file expired")
+)
+
+// OpeningExportService writes openings in the export formats, either straight
+// to a response or, for large exports, to a file built in the background.
+type OpeningExportService struct {
+	logger    *slog.Logger
+	repo      repository.OpeningRepository
+	jobRepo   repository.ExportJobRepository
+	dir       string
+	batchSize int
+	jobs      chan schemas.ExportJob
+	// fileTTL is how long the file of a finished export is kept.
+	fileTTL time.Duration
+
+	// stop ends the worker started by Start; running waits for it.
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopping atomic.Bool
+	running  sync.WaitGroup
+}
+
+type OpeningExportServiceOption func(*OpeningExportService)
+
+func NewOpeningExportService(repo repository.OpeningRepository, jobRepo repository.ExportJobRepository, dir string, queueSize int, opts ...OpeningExportServiceOption) *OpeningExportService {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "opportunities-export")
+	}
+
+	s := &OpeningExportService{
+		logger:    slog.Default().With("group", "opening_export_service"),
+		repo:      repo,
+		jobRepo:   jobRepo,
+		dir:       dir,
+		batchSize: defaultExportBatchSize,
+		jobs:      make(chan schemas.ExportJob, queueSize),
+		stop:      make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Export streams the openings matching filter to w and returns how many were
+// written. The openings are read in batches, so memory stays constant.
+func (s *OpeningExportService) Export(w io.Writer, format string, filter repository.OpeningFilter) (int, error) {
+	writer, err := export.NewWriter(format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	err = s.repo.ListInBatches(filter, s.batchSize, func(openings []schemas.Openings) error {
+		for _, opening := range openings {
+			if err := writer.Write(opening); err != nil {
+				return err
+			}
+			rows++
+		}
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+
+	return rows, writer.Close()
+}
+
+func (s *OpeningExportService) Start(ctx context.Context) {
+	s.startFileSweep(ctx)
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("opening export service stopped")
+				return
+			case <-s.stop:
+				s.logger.Info("opening export service stopped")
+				return
+			case job := <-s.jobs:
+				s.run(job)
+			}
+		}
+	}()
+}
+
+// Enqueue records an export of the caller's openings (or of every opening
+// when mine is false) and builds it in the background.
+func (s *OpeningExportService) Enqueue(owner, format string, mine bool) (schemas.ExportJob, error) {
+	if s.stopping.Load() {
+		return schemas.ExportJob{}, ErrExportStopped
+	}
+
+	job := schemas.ExportJob{
+		ID:     uuid.NewString(),
+		Owner:  owner,
+		Format: format,
+		Mine:   mine,
+		Status: schemas.ImportJobQueued,
+	}
+
+	if err := s.jobRepo.Create(&job); err != nil {
+		return schemas.ExportJob{}, fmt.Errorf("failed to persist export job: %w", err)
+	}
+
+	select {
+	case s.jobs <- job:
+		return job, nil
+	default:
+		s.finish(&job, 0, ErrExportQueueFull)
+		return schemas.ExportJob{}, ErrExportQueueFull
+	}
+}
+
+// Restore fails the exports left queued or running by a previous process,
+// which stopped before finishing them, and removes their partial files.
+func (s *OpeningExportService) Restore() error {
+	jobs, err := s.jobRepo.ListUnfinished()
+	if err != nil {
+		return fmt.Errorf("failed to list unfinished exports: %w", err)
+	}
+
+	for _, job := range jobs {
+		s.logger.Warn("export interrupted", slog.String("export_id", job.ID))
+		os.Remove(s.filePath(job))
+		job.Path = ""
+		s.finish(&job, 0, ErrExportInterrupted)
+	}
+
+	return nil
+}
+
+// Shutdown stops taking exports and waits for the one being built, until ctx
+// is done. Exports still queued are failed, since they are not restored; one
+// still running is failed by Restore on the next start.
+func (s *OpeningExportService) Shutdown(ctx context.Context) error {
+	s.stopping.Store(true)
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	stopped := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return fmt.Errorf("export still running: %w", ctx.Err())
+	}
+
+	for {
+		select {
+		case job := <-s.jobs:
+			s.finish(&job, 0, ErrExportStopped)
+		default:
+			return nil
+		}
+	}
+}
+
+func (s *OpeningExportService) GetJob(id string) (schemas.ExportJob, error) {
+	return s.jobRepo.Get(id)
+}
+
+// Open returns the file of a finished export, or ErrExportExpired once the
+// retention removed it.
+func (s *OpeningExportService) Open(job schemas.ExportJob) (*os.File, error) {
+	if job.Status != schemas.ImportJobSucceeded {
+		return nil, ErrExportNotReady
+	}
+	if job.Path == "" {
+		return nil, ErrExportExpired
+	}
+
+	return os.Open(job.Path)
+}
+
+func (s *OpeningExportService) run(job schemas.ExportJob) {
+	logger := s.logger.With("export_id", job.ID)
+
+	started := time.Now().UTC()
+	job.Status = schemas.ImportJobRunning
+	job.StartedAt = &started
+	if err := s.jobRepo.Update(&job); err != nil {
+		logger.Error("failed to update export job", slog.String("error", err.Error()))
+	}
+
+	filter := repository.OpeningFilter{}
+	if job.Mine {
+		filter.Owner = job.Owner
+	}
+
+	rows, err := s.writeFile(&job, filter)
+	if err != nil {
+		logger.Error("export failed", slog.String("error", err.Error()))
+	} else {
+		logger.Info("export completed", slog.Int("rows", rows))
+	}
+
+	s.finish(&job, rows, err)
+}
+
+func (s *OpeningExportService) writeFile(job *schemas.ExportJob, filter repository.OpeningFilter) (int, error) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return 0, fmt.Errorf("failed to create export dir: %w", err)
+	}
+
+	job.Path = s.filePath(*job)
+	file, err := os.Create(job.Path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	rows, err := s.Export(file, job.Format, filter)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(job.Path)
+		job.Path = ""
+	}
+
+	return rows, err
+}
+
+func (s *OpeningExportService) filePath(job schemas.ExportJob) string {
+	return filepath.Join(s.dir, job.ID+"."+job.Format)
+}
+
+func (s *OpeningExportService) finish(job *schemas.ExportJob, rows int, err error) {
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	job.Rows = rows
+	job.Status = schemas.ImportJobSucceeded
+	if err != nil {
+		job.Status = schemas.ImportJobFailed
+		job.ErrorSummary = err.Error()
+	}
+
+	if err := s.jobRepo.Update(job); err != nil {
+		s.logger.Error("failed to update export job",
+			slog.String("export_id", job.ID),
+			slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+)
+
+func TestOpeningExportService_ExportsInBatches(t *testing.T) {
+	db := openTestDB(t)
+	repo := repository.New(db)
+	for i, owner := range []string{"a@test.com", "b@test.com", "a@test.com"} {
+		opening := schemas.Openings{Role: "Go Dev", Company: "Acme", Location: "BR", Link: "https://acme.com/" + string(rune('a'+i)), Salary: 1000, Owner: owner}
+		if err := repo.Create(&opening); err != nil {
+			t.Fatalf("unexpected create error: %v", err)
+		}
+	}
+
+	svc := NewOpeningExportService(repo, repository.NewExportJobRepository(db), t.TempDir(), 1)
+	svc.batchSize = 1
+
+	var buf bytes.Buffer
+	rows, err := svc.Export(&buf, "csv", repository.OpeningFilter{Owner: "a@test.com"})
+	if err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+	if rows != 2 {
+		t.Fatalf("expected 2 rows, got %d", rows)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != "role,company,location,remote,link,salary,external_id" {
+		t.Fatalf("unexpected export:\n%s", buf.String())
+	}
+}
+
+func TestOpeningExportService_RunsAsyncExport(t *testing.T) {
+	db := openTestDB(t)
+	repo := repository.New(db)
+	opening := schemas.Openings{Role: "Go Dev", Company: "Acme", Location: "BR", Link: "https://acme.com", Salary: 1000, Owner: "a@test.com"}
+	if err := repo.Create(&opening); err != nil {
+		t.Fatalf("unexpected create error: %v", err)
+	}
+
+	svc := NewOpeningExportService(repo, repository.NewExportJobRepository(db), t.TempDir(), 1)
+
+	job, err := svc.Enqueue("a@test.com", "ndjson", true)
+	if err != nil {
+		t.Fatalf("unexpected enqueue error: %v", err)
+	}
+	if _, err := svc.Open(job); err != ErrExportNotReady {
+		t.Fatalf("expected ErrExportNotReady, got %v", err)
+	}
+	if _, err := svc.Enqueue("a@test.com", "csv", true); err != ErrExportQueueFull {
+		t.Fatalf("expected ErrExportQueueFull, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc.Start(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for job.Status != schemas.ImportJobSucceeded && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if job, err = svc.GetJob(job.ID); err != nil {
+			t.Fatalf("unexpected get error: %v", err)
+		}
+	}
+	if job.Status != schemas.ImportJobSucceeded || job.Rows != 1 {
+		t.Fatalf("expected a succeeded export with 1 row, got %+v", job)
+	}
+
+	file, err := svc.Open(job)
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+	defer file.Close()
+
+	content, _ := os.ReadFile(file.Name())
+	if !strings.Contains(string(content), `"link":"https://acme.com"`) {
+		t.Fatalf("unexpected export content: %s", content)
+	}
+}
+
+func TestOpeningExportService_RestoreFailsInterruptedExports(t *testing.T) {
+	db := openTestDB(t)
+	jobRepo := repository.NewExportJobRepository(db)
+	dir := t.TempDir()
+	svc := NewOpeningExportService(repository.New(db), jobRepo, dir, 1)
+
+	running := schemas.ExportJob{ID: "export-running", Owner: "a@test.com", Format: "csv", Status: schemas.ImportJobRunning}
+	if err := jobRepo.Create(&running); err != nil {
+		t.Fatalf("unexpected create error: %v", err)
+	}
+	partial := filepath.Join(dir, "export-running.csv")
+	if err := os.WriteFile(partial, []byte("role,company\n"), 0o600); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+
+	if err := svc.Restore(); err != nil {
+		t.Fatalf("unexpected restore error: %v", err)
+	}
+
+	job, _ := svc.GetJob(running.ID)
+	if job.Status != schemas.ImportJobFailed || job.ErrorSummary != ErrExportInterrupted.Error() || job.FinishedAt == nil {
+		t.Fatalf("expected the interrupted export to fail, got %+v", job)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Fatalf("expected the partial file to be removed, got %v", err)
+	}
+}
+
+func TestOpeningExportService_SweepFiles(t *testing.T) {
+	db := openTestDB(t)
+	jobRepo := repository.NewExportJobRepository(db)
+	dir := t.TempDir()
+	svc := NewOpeningExportService(repository.New(db), jobRepo, dir, 1, WithExportFileTTL(time.Hour))
+
+	now := time.Now().UTC()
+	old, recent := now.Add(-2*time.Hour), now.Add(-time.Minute)
+	for _, job := range []schemas.ExportJob{
+		{ID: "export-old", Format: "csv", Status: schemas.ImportJobSucceeded, FinishedAt: &old},
+		{ID: "export-new", Format: "csv", Status: schemas.ImportJobSucceeded, FinishedAt: &recent},
+	} {
+		job.Path = filepath.Join(dir, job.ID+".csv")
+		if err := os.WriteFile(job.Path, []byte("role\n"), 0o600); err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+		if err := jobRepo.Create(&job); err != nil {
+			t.Fatalf("unexpected create error: %v", err)
+		}
+	}
+
+	removed, err := svc.SweepFiles(now)
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 expired file, got %d (%v)", removed, err)
+	}
+
+	expired, _ := svc.GetJob("export-old")
+	if _, err := svc.Open(expired); err != ErrExportExpired {
+		t.Fatalf("expected ErrExportExpired, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "export-old.csv")); !os.IsNotExist(err) {
+		t.Fatalf("expected the expired file to be removed, got %v", err)
+	}
+
+	kept, _ := svc.GetJob("export-new")
+	file, err := svc.Open(kept)
+	if err != nil {
+		t.Fatalf("expected the recent export to be kept, got %v", err)
+	}
+	file.Close()
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"opportunities/internal/auth"
+	"opportunities/internal/repository"
+	"opportunities/internal/schemas"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor enrollment was not started")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrTwoFactorRequired       = errors.New("two-factor authentication is required for your role")
+)
+
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorService struct {
+	logger *slog.Logger
+	repo   repository.TwoFactorRepository
+	issuer string
+	now    func() time.Time
+	// accounts serializes the changes of one account, so a code cannot be
+	// replayed concurrently, without making other accounts wait.
+	accounts accountLocks
+}
+
+func NewTwoFactorService(repo repository.TwoFactorRepository, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		logger: slog.Default().With("group", "two_factor_service"),
+		repo:   repo,
+		issuer: issuer,
+		now:    time.Now,
+	}
+}
+
+func (s *TwoFactorService) Enabled(email string) (bool, error) {
+	twoFactor, err := s.repo.Get(email)
+	if errors.Is(err, repository.ErrTwoFactorNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return twoFactor.Enabled, nil
+}
+
+// Required reports whether any of the roles has the "2FA required" policy.
+func (s *TwoFactorService) Required(roles []string) (bool, error) {
+	policies, err := s.repo.ListPolicies()
+	if err != nil {
+		return false, err
+	}
+
+	for _, policy := range policies {
+		if !policy.Required {
+			continue
+		}
+
+		for _, role := range roles {
+			if role == policy.Role {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// Enroll generates a new secret for the account. It is only activated after
+// Confirm receives a valid code.
+func (s *TwoFactorService) Enroll(email string) (TwoFactorEnrollment, error) {
+	defer s.accounts.lock(email)()
+
+	enabled, err := s.Enabled(email)
+	if err != nil {
+		return TwoFactorEnrollment{}, err
+	}
+	if enabled {
+		return TwoFactorEnrollment{}, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return TwoFactorEnrollment{}, err
+	}
+
+	if err := s.repo.Save(&schemas.TwoFactor{Email: email, Secret: secret}); err != nil {
+		return TwoFactorEnrollment{}, err
+	}
+
+	return TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(secret, s.issuer, email),
+	}, nil
+}
+
+// Confirm enables 2FA after the first valid code and returns the recovery
+// codes, which are shown to the user only once.
+func (s *TwoFactorService) Confirm(email, code string) ([]string, error) {
+	defer s.accounts.lock(email)()
+
+	twoFactor, err := s.repo.Get(email)
+	if errors.Is(err, repository.ErrTwoFactorNotFound) {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if twoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := auth.ValidateTOTP(twoFactor.Secret, code, s.now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	twoFactor.Enabled = true
+	twoFactor.LastUsedStep = step
+	twoFactor.RecoveryCodes = strings.Join(hashes, "\n")
+	twoFactor.ConfirmedAt = &now
+
+	if err := s.repo.Save(&twoFactor); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("two-factor enabled", slog.String("email", email))
+	return codes, nil
+}
+
+// Verify accepts a current TOTP code or an unused recovery code.
+func (s *TwoFactorService) Verify(email, code string) error {
+	defer s.accounts.lock(email)()
+
+	twoFactor, err := s.repo.Get(email)
+	if errors.Is(err, repository.ErrTwoFactorNotFound) {
+		return ErrTwoFactorNotEnrolled
+	}
+	if err != nil {
+		return err
+	}
+
+	if !twoFactor.Enabled {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	if step, ok := auth.ValidateTOTP(twoFactor.Secret, code, s.now()); ok {
+		if step <= twoFactor.LastUsedStep {
+			return ErrInvalidTwoFactorCode
+		}
+
+		twoFactor.LastUsedStep = step
+		return s.repo.Save(&twoFactor)
+	}
+
+	// Only input shaped like a recovery code is checked against the bcrypt
+	// hashes; a wrong TOTP code fails here.
+	if !auth.IsRecoveryCode(code) {
+		return ErrInvalidTwoFactorCode
+	}
+
+	hashes := recoveryHashes(twoFactor)
+	index := auth.MatchRecoveryCode(code, hashes)
+	if index < 0 {
+		return ErrInvalidTwoFactorCode
+	}
+
+	hashes = append(hashes[:index], hashes[index+1:]...)
+	twoFactor.RecoveryCodes = strings.Join(hashes, "\n")
+
+	s.logger.Info("two-factor recovery code used",
+		slog.String("email", email),
+		slog.Int("remaining_codes", len(hashes)))
+
+	return s.repo.Save(&twoFactor)
+}
+
+// Disable removes the enrollment after checking a valid code. Accounts whose
+// role requires 2FA cannot disable it.
+func (s *TwoFactorService) Disable(email, code string, roles []string) error {
+	required, err := s.Required(roles)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+
+	if err := s.Verify(email, code); err != nil {
+		return err
+	}
+
+	s.logger.Info("two-factor disabled", slog.String("email", email))
+	return s.repo.Delete(email)
+}
+
+func (s *TwoFactorService) ListPolicies() ([]schemas.TwoFactorPolicy, error) {
+	return s.repo.ListPolicies()
+}
+
+func (s *TwoFactorService) SetPolicy(role string, required bool, updatedBy string) (schemas.TwoFactorPolicy, error) {
+	policy := schemas.TwoFactorPolicy{
+		Role:      role,
+		Required:  required,
+		UpdatedBy: updatedBy,
+		UpdatedAt: s.now(),
+	}
+
+	if err := s.repo.SavePolicy(&policy); err != nil {
+		return schemas.TwoFactorPolicy{}, err
+	}
+
+	s.logger.Info("two-factor policy updated",
+		slog.String("role", role),
+		slog.Bool("required", required),
+		slog.String("updated_by", updatedBy))
+
+	return policy, nil
+}
+
+func recoveryHashes(twoFactor schemas.TwoFactor) []string {
+	if twoFactor.RecoveryCodes == "" {
+		return nil
+	}
+
+	return strings.Split(twoFactor.RecoveryCodes, "\n")
+}
+
+// accountLocks hands out one mutex per account, dropped once nobody holds
+// or waits for it.
+type accountLocks struct {
+	mu    sync.Mutex
+	locks map[string]*accountLock
+}
+
+type accountLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks the account and returns the function that unlocks it.
+func (l *accountLocks) lock(key string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*accountLock)
+	}
+	entry, ok := l.locks[key]
+	if !ok {
+		entry = &accountLock{}
+		l.locks[key] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	entry.Lock()
+
+	return func() {
+		entry.Unlock()
+
+		l.mu.Lock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"opportunities/internal/auth"
+	"opportunities/internal/repository"
+)
+
+func TestTwoFactorService_EnrollVerifyAndRecover(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewTwoFactorService(repository.NewTwoFactorRepository(db), "Opportunities API")
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	enrollment, err := svc.Enroll("admin@admin.com")
+	if err != nil {
+		t.Fatalf("unexpected enroll error: %v", err)
+	}
+	if enrollment.ProvisioningURI == "" {
+		t.Fatalf("expected provisioning uri")
+	}
+
+	if enabled, _ := svc.Enabled("admin@admin.com"); enabled {
+		t.Fatalf("expected 2fa to stay disabled until confirmed")
+	}
+
+	code, _ := auth.TOTPCode(enrollment.Secret, now)
+	recoveryCodes, err := svc.Confirm("admin@admin.com", code)
+	if err != nil {
+		t.Fatalf("unexpected confirm error: %v", err)
+	}
+	if len(recoveryCodes) == 0 {
+		t.Fatalf("expected recovery codes")
+	}
+
+	if err := svc.Verify("admin@admin.com", code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("expected replayed code to be rejected, got %v", err)
+	}
+
+	now = now.Add(30 * time.Second)
+	next, _ := auth.TOTPCode(enrollment.Secret, now)
+	if err := svc.Verify("admin@admin.com", next); err != nil {
+		t.Fatalf("expected next code to be accepted, got %v", err)
+	}
+
+	if err := svc.Verify("admin@admin.com", recoveryCodes[0]); err != nil {
+		t.Fatalf("expected recovery code to be accepted, got %v", err)
+	}
+	if err := svc.Verify("admin@admin.com", recoveryCodes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("expected recovery code to be single use, got %v", err)
+	}
+}
+
+func TestTwoFactorService_VerifyConcurrently(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewTwoFactorService(repository.NewTwoFactorRepository(db), "Opportunities API")
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	enrollment, err := svc.Enroll("admin@admin.com")
+	if err != nil {
+		t.Fatalf("unexpected enroll error: %v", err)
+	}
+	code, _ := auth.TOTPCode(enrollment.Secret, now)
+	if _, err := svc.Confirm("admin@admin.com", code); err != nil {
+		t.Fatalf("unexpected confirm error: %v", err)
+	}
+
+	// A wrong code shaped like a TOTP code never reaches bcrypt.
+	start := time.Now()
+	for range 20 {
+		if err := svc.Verify("admin@admin.com", "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("expected the wrong code to be rejected, got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected wrong TOTP codes to fail fast, took %s", elapsed)
+	}
+
+	now = now.Add(30 * time.Second)
+	next, _ := auth.TOTPCode(enrollment.Secret, now)
+
+	var wg sync.WaitGroup
+	var accepted atomic.Int32
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if svc.Verify("admin@admin.com", next) == nil {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted.Load() != 1 {
+		t.Fatalf("expected the code to be accepted once, got %d", accepted.Load())
+	}
+	if len(svc.accounts.locks) != 0 {
+		t.Fatalf("expected the account locks to be released, got %d", len(svc.accounts.locks))
+	}
+}
+
+func TestTwoFactorService_RolePolicy(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewTwoFactorService(repository.NewTwoFactorRepository(db), "Opportunities API")
+
+	if _, err := svc.SetPolicy(auth.RoleAdmin, true, "root@admin.com"); err != nil {
+		t.Fatalf("unexpected policy error: %v", err)
+	}
+
+	required, err := svc.Required([]string{auth.RoleRecruiter, auth.RoleAdmin})
+	if err != nil || !required {
+		t.Fatalf("expected 2fa required for admins, got %v (%v)", required, err)
+	}
+
+	required, _ = svc.Required([]string{auth.RoleRecruiter})
+	if required {
+		t.Fatalf("expected 2fa optional for recruiters")
+	}
+
+	if err := svc.Disable("admin@admin.com", "000000", []string{auth.RoleAdmin}); !errors.Is(err, ErrTwoFactorRequired) {
+		t.Fatalf("expected disable to be refused, got %v", err)
+	}
+
+	if _, err := svc.SetPolicy(auth.RoleAdmin, false, "root@admin.com"); err != nil {
+		t.Fatalf("unexpected policy error: %v", err)
+	}
+
+	required, _ = svc.Required([]string{auth.RoleAdmin})
+	if required {
+		t.Fatalf("expected policy update to be persisted")
+	}
+}